@@ -12,6 +12,7 @@ const (
 // AccountDetailsResource access to account details
 type AccountDetailsResource struct {
 	nordigenResource[AccountDetailsResponse]
+	accountID uuid.UUID
 }
 
 // AccountDetailsResponse API response structure
@@ -67,12 +68,18 @@ func (r *AccountResource) Details(accountID uuid.UUID) *AccountDetailsResource {
 				Client: r.nordigen.restClient,
 			},
 		},
+		accountID,
 	}
 }
 
 // Get details for the underlying account resource
 // In case API HTTP error response rest.ApiError will be returned,
+// ScopeError is returned without calling the API if the client's ScopeGuard rejects the request
 func (d *AccountDetailsResource) Get() (*AccountDetailsResponse, error) {
+	if err := d.nordigen.checkScope(d.accountID, AccessScopeDetails); err != nil {
+		return nil, err
+	}
+
 	return d.wrap(
 		func() (*AccountDetailsResponse, error) {
 			return d.generic.Get("", nil)
@@ -0,0 +1,181 @@
+package nordigen
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultSnapshotConcurrency = 4
+)
+
+const (
+	snapshotPartAccount      = "account"
+	snapshotPartDetails      = AccessScopeDetails
+	snapshotPartBalances     = AccessScopeBalances
+	snapshotPartTransactions = AccessScopeTransactions
+)
+
+// ErrScopeNotGranted reported for the parts of an account which are not covered by the agreement's access scopes
+var ErrScopeNotGranted = errors.New("access scope not granted by the end user agreement")
+
+// AccountSnapshotOptions options for fetching an account snapshot
+type AccountSnapshotOptions struct {
+	// Agreement the account has been linked with. Details, balances and transactions are fetched
+	// only if they are in the agreement's access scopes. All the parts are fetched if nil
+	Agreement *EndUserAgreementResponse
+	// Concurrency the maximum number of parallel requests. 4 by default
+	Concurrency int
+	// Limiter throttles the requests. Requests are not throttled if nil
+	Limiter RateLimiter
+	// DateFrom of the transactions to fetch
+	DateFrom *time.Time
+	// DateTo of the transactions to fetch
+	DateTo *time.Time
+}
+
+// AccountSnapshot all the data of an account fetched at once.
+// The data of a part is nil if the corresponding error is not nil.
+// The error of a part is ErrScopeNotGranted if the part has been skipped because of the agreement's access scopes
+type AccountSnapshot struct {
+	AccountID    uuid.UUID
+	Account      *AccountResponse
+	Details      *AccountDetailsResponse
+	Balances     *BalanceCollectionResponse
+	Transactions *TransactionCollectionResponse
+	FetchedAt    time.Time
+
+	AccountErr      error
+	DetailsErr      error
+	BalancesErr     error
+	TransactionsErr error
+}
+
+// AccountSnapshotError returned when some parts of the account snapshot could not be fetched
+type AccountSnapshotError struct {
+	AccountID uuid.UUID
+	// Parts maps the name of a failed part (account, details, balances, transactions) to its error
+	Parts map[string]error
+}
+
+// Error returns string representation of the error
+func (e *AccountSnapshotError) Error() string {
+	msg := make([]string, 0, len(e.Parts))
+	for _, part := range []string{snapshotPartAccount, snapshotPartDetails, snapshotPartBalances, snapshotPartTransactions} {
+		if err, ok := e.Parts[part]; ok {
+			msg = append(msg, part+": "+err.Error())
+		}
+	}
+
+	return "error fetching account " + e.AccountID.String() + " snapshot: " + strings.Join(msg, "; ")
+}
+
+// Err returns AccountSnapshotError if any part of the snapshot failed.
+// Parts skipped because of the agreement's access scopes are not treated as failures
+func (s *AccountSnapshot) Err() error {
+	parts := map[string]error{}
+	for part, err := range map[string]error{
+		snapshotPartAccount:      s.AccountErr,
+		snapshotPartDetails:      s.DetailsErr,
+		snapshotPartBalances:     s.BalancesErr,
+		snapshotPartTransactions: s.TransactionsErr,
+	} {
+		if err != nil && !errors.Is(err, ErrScopeNotGranted) {
+			parts[part] = err
+		}
+	}
+
+	if len(parts) == 0 {
+		return nil
+	}
+
+	return &AccountSnapshotError{AccountID: s.AccountID, Parts: parts}
+}
+
+// Snapshot fetches the account metadata, details, balances and transactions concurrently.
+// The snapshot is always returned, along with AccountSnapshotError if some of its parts failed
+func (r *AccountResource) Snapshot(ctx context.Context, ID uuid.UUID, opts *AccountSnapshotOptions) (*AccountSnapshot, error) {
+	if opts == nil {
+		opts = &AccountSnapshotOptions{}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultSnapshotConcurrency
+	}
+
+	snapshot := r.snapshot(ctx, ID, opts, make(chan struct{}, concurrency))
+
+	return snapshot, snapshot.Err()
+}
+
+// snapshot fetches the parts of the account running at most cap(sem) requests in parallel.
+// The semaphore can be shared between several snapshots
+func (r *AccountResource) snapshot(
+	ctx context.Context,
+	ID uuid.UUID,
+	opts *AccountSnapshotOptions,
+	sem chan struct{},
+) *AccountSnapshot {
+	s := &AccountSnapshot{AccountID: ID}
+
+	wg := &sync.WaitGroup{}
+	run := func(scope string, call func() error, errTarget *error) {
+		if scope != "" && !agreementGrantsScope(opts.Agreement, scope) {
+			*errTarget = ErrScopeNotGranted
+			return
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			*errTarget = execLimited(ctx, sem, opts.Limiter, call)
+		}()
+	}
+
+	run("", func() (err error) {
+		s.Account, err = r.Get(ID)
+		return
+	}, &s.AccountErr)
+
+	run(AccessScopeDetails, func() (err error) {
+		s.Details, err = r.Details(ID).Get()
+		return
+	}, &s.DetailsErr)
+
+	run(AccessScopeBalances, func() (err error) {
+		s.Balances, err = r.Balance(ID).Get()
+		return
+	}, &s.BalancesErr)
+
+	run(AccessScopeTransactions, func() (err error) {
+		s.Transactions, err = r.Transaction(ID).Get(opts.DateFrom, opts.DateTo)
+		return
+	}, &s.TransactionsErr)
+
+	wg.Wait()
+	s.FetchedAt = time.Now()
+
+	return s
+}
+
+// agreementGrantsScope reports whether the agreement allows access to the given scope.
+// The API grants all the scopes if the agreement doesn't specify any
+func agreementGrantsScope(agreement *EndUserAgreementResponse, scope string) bool {
+	if agreement == nil || len(agreement.AccessScopes) == 0 {
+		return true
+	}
+
+	for _, s := range agreement.AccessScopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
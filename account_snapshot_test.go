@@ -0,0 +1,120 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen/rest"
+)
+
+func TestAccountResource_Snapshot(t *testing.T) {
+	t.Parallel()
+	t.Run("getting snapshot", testAccountSnapshotOk)
+	t.Run("getting snapshot partial failure", testAccountSnapshotPartialFailure)
+}
+
+func startAccountServer(failingPart string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		atomic.AddInt32(requests, 1)
+
+		if failingPart != "" && strings.HasSuffix(r.URL.Path, "/"+failingPart) {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"summary": "Rate limit exceeded"}`))
+			return
+		}
+
+		var payload string
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/details"):
+			payload = `{"account": {"iban": "DE75512108001245126199", "currency": "EUR"}}`
+		case strings.HasSuffix(r.URL.Path, "/balances"):
+			payload = `{"balances": [{"balanceAmount": {"amount": "657.49", "currency": "EUR"}, "balanceType": "expected"}]}`
+		case strings.HasSuffix(r.URL.Path, "/transactions"):
+			payload = `{"transactions": {"booked": [{"transactionAmount": {"amount": "-3.9", "currency": "EUR"}}]}}`
+		default:
+			payload = `{"id": "3fa85f64-5717-4562-b3fc-2c963f66afa6", "iban": "DE75512108001245126199", "status": "READY"}`
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(payload))
+	}))
+}
+
+func testAccountSnapshotOk(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startAccountServer("", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Account()
+
+	opts := &AccountSnapshotOptions{
+		Agreement: &EndUserAgreementResponse{AccessScopes: []string{"balances", "transactions"}},
+	}
+
+	// When/Act
+	snapshot, err := underTest.Snapshot(context.Background(), uuid.New(), opts)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if requests != 3 {
+		t.Fatalf("3 requests expected as details are not in the scope, %d sent", requests)
+	}
+
+	if snapshot.Account == nil || snapshot.Balances == nil || snapshot.Transactions == nil {
+		t.Fatal("account, balances and transactions expected to be fetched")
+	}
+
+	if snapshot.Details != nil || !errors.Is(snapshot.DetailsErr, ErrScopeNotGranted) {
+		t.Fatal("details expected to be skipped")
+	}
+}
+
+func testAccountSnapshotPartialFailure(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startAccountServer("transactions", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Account()
+
+	// When/Act
+	snapshot, err := underTest.Snapshot(context.Background(), uuid.New(), &AccountSnapshotOptions{Concurrency: 1})
+
+	// Then/Assert
+	var snapshotErr *AccountSnapshotError
+	if !errors.As(err, &snapshotErr) {
+		t.Fatalf("AccountSnapshotError expected, %v returned", err)
+	}
+
+	if _, ok := snapshotErr.Parts["transactions"]; !ok || len(snapshotErr.Parts) != 1 {
+		t.Fatalf("only transactions expected to fail, %v reported", snapshotErr.Parts)
+	}
+
+	var apiErr *rest.ApiError
+	if !errors.As(snapshot.TransactionsErr, &apiErr) || apiErr.StatusCode() != http.StatusTooManyRequests {
+		t.Fatalf("429 API error expected for transactions, %v returned", snapshot.TransactionsErr)
+	}
+
+	if snapshot.Account == nil || snapshot.Details == nil || snapshot.Balances == nil {
+		t.Fatal("account, details and balances expected to be fetched despite the failure")
+	}
+}
@@ -12,6 +12,27 @@ const (
 	endUserAgreementResourceID = "/agreements/enduser"
 )
 
+// Access scopes of the account data granted by an end user agreement
+const (
+	AccessScopeBalances     = "balances"
+	AccessScopeDetails      = "details"
+	AccessScopeTransactions = "transactions"
+)
+
+// AllAccessScopes the list of all the access scopes supported by the API
+var AllAccessScopes = []string{AccessScopeBalances, AccessScopeDetails, AccessScopeTransactions}
+
+// IsValidAccessScope reports whether the scope is supported by the API
+func IsValidAccessScope(scope string) bool {
+	for _, s := range AllAccessScopes {
+		if scope == s {
+			return true
+		}
+	}
+
+	return false
+}
+
 type EndUserAgreementResource struct {
 	nordigenResource[EndUserAgreementResponse]
 }
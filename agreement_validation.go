@@ -0,0 +1,197 @@
+package nordigen
+
+import (
+	"strconv"
+	"strings"
+)
+
+const (
+	// defaultMaxHistoricalDays applied by the API when MaxHistoricalDays is not set
+	defaultMaxHistoricalDays = 90
+	// defaultAccessValidForDays applied by the API when AccessValidForDays is not set
+	defaultAccessValidForDays = 90
+	// maxAccessValidForDays the maximum AccessValidForDays accepted by the API
+	maxAccessValidForDays = 180
+)
+
+// AgreementAdjustment a change made to CreateAgreementRequest by Clamp
+type AgreementAdjustment struct {
+	// Field JSON name of the changed field
+	Field  string
+	Old    string
+	New    string
+	Reason string
+}
+
+// Validate checks the request against the institution's limits without sending it to the API.
+// The institution is optional, only the limits of the API are checked if nil.
+// ValidationError is returned if the request would be rejected
+func (r *CreateAgreementRequest) Validate(institution *InstitutionResponse) error {
+	verr := &ValidationError{}
+
+	if r.InstitutionID == "" {
+		verr.add("institution_id", "must not be empty")
+	} else if institution != nil && institution.ID != r.InstitutionID {
+		verr.add("institution_id", "doesn't match the institution "+institution.ID)
+	}
+
+	if r.MaxHistoricalDays < 0 {
+		verr.add("max_historical_days", "must not be negative")
+	} else if limit := historicalDaysLimit(institution); limit > 0 && r.effectiveMaxHistoricalDays() > limit {
+		verr.add(
+			"max_historical_days",
+			strconv.Itoa(r.effectiveMaxHistoricalDays())+" exceeds the institution's limit of "+strconv.Itoa(limit),
+		)
+	}
+
+	if r.AccessValidForDays < 0 {
+		verr.add("access_valid_for_days", "must not be negative")
+	} else if limit := accessValidForDaysLimit(institution); r.effectiveAccessValidForDays() > limit {
+		verr.add(
+			"access_valid_for_days",
+			strconv.Itoa(r.effectiveAccessValidForDays())+" exceeds the maximum of "+strconv.Itoa(limit),
+		)
+	}
+
+	seen := make(map[string]bool, len(r.AccessScope))
+	for _, scope := range r.AccessScope {
+		if !IsValidAccessScope(scope) {
+			verr.add("access_scope", `invalid scope "`+scope+`"`)
+		} else if seen[scope] {
+			verr.add("access_scope", `duplicate scope "`+scope+`"`)
+		}
+		seen[scope] = true
+	}
+
+	return verr.errorOrNil()
+}
+
+// Clamp lowers MaxHistoricalDays and AccessValidForDays to the institution's maximums, replaces negative
+// values with the API defaults and removes invalid and duplicate scopes. The changes are returned,
+// along with ValidationError if the request is still invalid, e.g. because of a mismatching institution
+func (r *CreateAgreementRequest) Clamp(institution *InstitutionResponse) ([]AgreementAdjustment, error) {
+	adjustments := make([]AgreementAdjustment, 0)
+
+	if r.MaxHistoricalDays < 0 {
+		adjustments = append(adjustments, r.setMaxHistoricalDays(0, "negative value replaced with the API default"))
+	}
+
+	if limit := historicalDaysLimit(institution); limit > 0 && r.effectiveMaxHistoricalDays() > limit {
+		adjustments = append(adjustments, r.setMaxHistoricalDays(limit, "lowered to the institution's limit"))
+	}
+
+	if r.AccessValidForDays < 0 {
+		adjustments = append(adjustments, r.setAccessValidForDays(0, "negative value replaced with the API default"))
+	}
+
+	if limit := accessValidForDaysLimit(institution); r.effectiveAccessValidForDays() > limit {
+		adjustments = append(adjustments, r.setAccessValidForDays(limit, "lowered to the institution's maximum"))
+	}
+
+	if len(r.AccessScope) > 0 {
+		scopes := make([]string, 0, len(r.AccessScope))
+		seen := make(map[string]bool, len(r.AccessScope))
+		for _, scope := range r.AccessScope {
+			if IsValidAccessScope(scope) && !seen[scope] {
+				scopes = append(scopes, scope)
+			}
+			seen[scope] = true
+		}
+
+		if len(scopes) != len(r.AccessScope) {
+			adjustments = append(adjustments, AgreementAdjustment{
+				Field:  "access_scope",
+				Old:    strings.Join(r.AccessScope, ","),
+				New:    strings.Join(scopes, ","),
+				Reason: "invalid and duplicate scopes removed",
+			})
+			r.AccessScope = scopes
+		}
+	}
+
+	return adjustments, r.Validate(institution)
+}
+
+func (r *CreateAgreementRequest) effectiveMaxHistoricalDays() int {
+	if r.MaxHistoricalDays == 0 {
+		return defaultMaxHistoricalDays
+	}
+
+	return r.MaxHistoricalDays
+}
+
+func (r *CreateAgreementRequest) effectiveAccessValidForDays() int {
+	if r.AccessValidForDays == 0 {
+		return defaultAccessValidForDays
+	}
+
+	return r.AccessValidForDays
+}
+
+func (r *CreateAgreementRequest) setMaxHistoricalDays(value int, reason string) AgreementAdjustment {
+	adjustment := AgreementAdjustment{
+		Field:  "max_historical_days",
+		Old:    strconv.Itoa(r.MaxHistoricalDays),
+		New:    strconv.Itoa(value),
+		Reason: reason,
+	}
+	r.MaxHistoricalDays = value
+
+	return adjustment
+}
+
+func (r *CreateAgreementRequest) setAccessValidForDays(value int, reason string) AgreementAdjustment {
+	adjustment := AgreementAdjustment{
+		Field:  "access_valid_for_days",
+		Old:    strconv.Itoa(r.AccessValidForDays),
+		New:    strconv.Itoa(value),
+		Reason: reason,
+	}
+	r.AccessValidForDays = value
+
+	return adjustment
+}
+
+// historicalDaysLimit returns the institution's TransactionTotalDays or 0 if unknown
+func historicalDaysLimit(institution *InstitutionResponse) int {
+	if institution == nil {
+		return 0
+	}
+
+	return institution.TransactionTotalDays
+}
+
+// accessValidForDaysLimit returns the institution's MaxAccessValidForDays or the API maximum if unknown
+func accessValidForDaysLimit(institution *InstitutionResponse) int {
+	if institution == nil || institution.MaxAccessValidForDays <= 0 {
+		return maxAccessValidForDays
+	}
+
+	return institution.MaxAccessValidForDays
+}
+
+// CreateValidated validates the request against the institution before creating the agreement.
+// If clamp is true the request is adjusted to the institution's limits first and the changes are returned.
+// ValidationError is returned without calling the API if the request is invalid
+func (r *EndUserAgreementResource) CreateValidated(
+	payload *CreateAgreementRequest,
+	institution *InstitutionResponse,
+	clamp bool,
+) (*EndUserAgreementResponse, []AgreementAdjustment, error) {
+	var adjustments []AgreementAdjustment
+	var err error
+
+	if clamp {
+		adjustments, err = payload.Clamp(institution)
+	} else {
+		err = payload.Validate(institution)
+	}
+
+	if err != nil {
+		return nil, adjustments, err
+	}
+
+	agreement, err := r.Create(payload)
+
+	return agreement, adjustments, err
+}
@@ -0,0 +1,166 @@
+package nordigen
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var testValidationInstitution = &InstitutionResponse{
+	ID:                   "N26_NTSBDEB1",
+	TransactionTotalDays: 60,
+}
+
+type agreementValidationTestCase struct {
+	name          string
+	request       CreateAgreementRequest
+	invalidFields []string
+}
+
+var agreementValidationTestCases = []*agreementValidationTestCase{
+	{
+		"valid request",
+		CreateAgreementRequest{"N26_NTSBDEB1", 60, 30, []string{AccessScopeBalances}},
+		nil,
+	},
+	{
+		"default historical days exceeding the institution's limit",
+		CreateAgreementRequest{"N26_NTSBDEB1", 0, 30, nil},
+		[]string{"max_historical_days"},
+	},
+	{
+		"access valid for days over the maximum",
+		CreateAgreementRequest{"N26_NTSBDEB1", 30, 365, nil},
+		[]string{"access_valid_for_days"},
+	},
+	{
+		"invalid and duplicate scopes",
+		CreateAgreementRequest{"N26_NTSBDEB1", 30, 30, []string{"payments", AccessScopeDetails, AccessScopeDetails}},
+		[]string{"access_scope"},
+	},
+	{
+		"mismatching institution",
+		CreateAgreementRequest{"REVOLUT_REVOGB21", 30, 30, nil},
+		[]string{"institution_id"},
+	},
+}
+
+func TestCreateAgreementRequest_Validate(t *testing.T) {
+	t.Parallel()
+	for _, tc := range agreementValidationTestCases {
+		t.Run(tc.name, testCreateAgreementRequestValidate(tc))
+	}
+}
+
+func testCreateAgreementRequestValidate(tc *agreementValidationTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// What/Arrange
+		underTest := tc.request
+
+		// When/Act
+		err := underTest.Validate(testValidationInstitution)
+
+		// Then/Assert
+		if len(tc.invalidFields) == 0 {
+			if err != nil {
+				t.Fatalf("unexpected error occurred: %s", err)
+			}
+			return
+		}
+
+		var verr *ValidationError
+		if !errors.As(err, &verr) {
+			t.Fatalf("ValidationError expected, %v returned", err)
+		}
+
+		for _, field := range tc.invalidFields {
+			if !verr.Has(field) {
+				t.Fatalf(`error expected for the field "%s", %s returned`, field, verr)
+			}
+		}
+	}
+}
+
+func TestCreateAgreementRequest_Clamp(t *testing.T) {
+	// What/Arrange
+	underTest := &CreateAgreementRequest{
+		InstitutionID:      "N26_NTSBDEB1",
+		MaxHistoricalDays:  730,
+		AccessValidForDays: 365,
+		AccessScope:        []string{AccessScopeBalances, "payments"},
+	}
+
+	// When/Act
+	adjustments, err := underTest.Clamp(testValidationInstitution)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(adjustments) != 3 {
+		t.Fatalf("3 adjustments expected, %v reported", adjustments)
+	}
+
+	if underTest.MaxHistoricalDays != 60 || underTest.AccessValidForDays != maxAccessValidForDays {
+		t.Fatalf("values expected to be lowered to the limits, %d and %d set", underTest.MaxHistoricalDays, underTest.AccessValidForDays)
+	}
+
+	if len(underTest.AccessScope) != 1 || underTest.AccessScope[0] != AccessScopeBalances {
+		t.Fatalf("invalid scope expected to be removed, %v left", underTest.AccessScope)
+	}
+}
+
+func TestCreateAgreementRequest_Validate_institutionMaximum(t *testing.T) {
+	// What/Arrange
+	institution := &InstitutionResponse{ID: "N26_NTSBDEB1", TransactionTotalDays: 730, MaxAccessValidForDays: 90}
+	underTest := &CreateAgreementRequest{InstitutionID: "N26_NTSBDEB1", AccessValidForDays: 120}
+
+	// When/Act
+	err := underTest.Validate(institution)
+	adjustments, clampErr := underTest.Clamp(institution)
+
+	// Then/Assert
+	var verr *ValidationError
+	if !errors.As(err, &verr) || !verr.Has("access_valid_for_days") {
+		t.Fatalf("ValidationError expected over the institution's maximum, %v returned", err)
+	}
+
+	if clampErr != nil || len(adjustments) != 1 || underTest.AccessValidForDays != 90 {
+		t.Fatalf("access_valid_for_days expected to be lowered to 90, %d set", underTest.AccessValidForDays)
+	}
+}
+
+func TestEndUserAgreementResource_CreateValidated(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.EndUserAgreement()
+
+	// When/Act
+	_, _, invalidErr := underTest.CreateValidated(&CreateAgreementRequest{InstitutionID: "N26_NTSBDEB1"}, testValidationInstitution, false)
+	agreement, adjustments, err := underTest.CreateValidated(&CreateAgreementRequest{InstitutionID: "N26_NTSBDEB1"}, testValidationInstitution, true)
+
+	// Then/Assert
+	var verr *ValidationError
+	if !errors.As(invalidErr, &verr) {
+		t.Fatalf("ValidationError expected without clamping, %v returned", invalidErr)
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if agreement.ID == uuid.Nil || agreement.MaxHistoricalDays != 60 || len(adjustments) != 1 {
+		t.Fatalf("agreement expected to be created with clamped values, %v created", agreement)
+	}
+
+	if len(api.agreements) != 1 {
+		t.Fatalf("only the valid agreement expected to be sent, %d created", len(api.agreements))
+	}
+}
@@ -26,6 +26,15 @@ type tokensResponse struct {
 	RefreshExpires int    `json:"refresh_expires"`
 }
 
+// Tokens the access and refresh tokens the client is authenticated with.
+// The expiration times are shifted by TokenExpirationBuffer
+type Tokens struct {
+	Access         string    `json:"access"`
+	AccessExpires  time.Time `json:"access_expires"`
+	Refresh        string    `json:"refresh"`
+	RefreshExpires time.Time `json:"refresh_expires"`
+}
+
 type refreshRequest struct {
 	Refresh string `json:"refresh"`
 }
@@ -38,7 +47,7 @@ type refreshResponse struct {
 // authenticate requests an access and refresh token and configures the client.
 // In case of API error ApiServerErrorResponse returned
 func (n *Nordigen) authenticate() error {
-	n.unauthenticate()
+	n.clearAuthentication()
 
 	body := bytes.NewBuffer([]byte{})
 	if err := json.NewEncoder(body).Encode(tokenRequest{SecretId: n.SecretID, SecretKey: n.SecretKey}); err != nil {
@@ -50,7 +59,8 @@ func (n *Nordigen) authenticate() error {
 		return errors.Wrap(err, "error executing authentication request")
 	}
 
-	n.restClient.Header.Set("Authorization", "Bearer "+tokens.Access)
+	n.restClient.SetHeader("Authorization", "Bearer "+tokens.Access)
+	n.accessToken = tokens.Access
 	n.accessTokenExpiration = time.Now().Add(time.Duration(tokens.AccessExpires) * time.Second).
 		Add(n.TokenExpirationBuffer)
 	n.RefreshToken = tokens.Refresh
@@ -73,7 +83,12 @@ func (n *Nordigen) refresh() error {
 		return ErrRefreshTokeExpired
 	}
 
-	n.unauthenticate()
+	return n.requestAccessToken()
+}
+
+// requestAccessToken requests a new access token with the refresh token without checking its expiration
+func (n *Nordigen) requestAccessToken() error {
+	n.clearAuthentication()
 
 	body := bytes.NewBuffer([]byte{})
 	if err := json.NewEncoder(body).Encode(refreshRequest{Refresh: n.RefreshToken}); err != nil {
@@ -85,7 +100,8 @@ func (n *Nordigen) refresh() error {
 		return errors.Wrap(err, "error executing token refresh request")
 	}
 
-	n.restClient.Header.Set("Authorization", "Bearer "+token.Access)
+	n.restClient.SetHeader("Authorization", "Bearer "+token.Access)
+	n.accessToken = token.Access
 	n.accessTokenExpiration = time.Now().Add(time.Duration(token.AccessExpires) * time.Second).
 		Add(n.TokenExpirationBuffer)
 
@@ -93,12 +109,24 @@ func (n *Nordigen) refresh() error {
 }
 
 func (n *Nordigen) unauthenticate() {
-	n.restClient.Header.Del("Authorization")
+	n.authMu.Lock()
+	defer n.authMu.Unlock()
+
+	n.clearAuthentication()
+}
+
+func (n *Nordigen) clearAuthentication() {
+	n.restClient.DelHeader("Authorization")
+	n.accessToken = ""
 	n.accessTokenExpiration = time.Unix(0, 0)
 }
 
+// ensureAuthenticated authenticates the client if needed. Safe for concurrent use
 func (n *Nordigen) ensureAuthenticated() error {
-	if n.restClient.Header.Get("Authorization") != "" && n.accessTokenExpiration.Unix() > time.Now().Unix() {
+	n.authMu.Lock()
+	defer n.authMu.Unlock()
+
+	if n.restClient.GetHeader("Authorization") != "" && n.accessTokenExpiration.Unix() > time.Now().Unix() {
 		return nil
 	}
 
@@ -108,3 +136,59 @@ func (n *Nordigen) ensureAuthenticated() error {
 
 	return n.authenticate()
 }
+
+// NewToken requests new access and refresh tokens and authenticates the client with them.
+// In case of API error rest.ApiError returned
+func (n *Nordigen) NewToken() (*Tokens, error) {
+	n.authMu.Lock()
+	defer n.authMu.Unlock()
+
+	if err := n.authenticate(); err != nil {
+		return nil, err
+	}
+
+	return n.tokens(), nil
+}
+
+// RefreshAccessToken requests a new access token with the client's refresh token.
+// ErrNoRefreshToken or ErrRefreshTokeExpired returned if the refresh token is missing or expired
+func (n *Nordigen) RefreshAccessToken() (*Tokens, error) {
+	n.authMu.Lock()
+	defer n.authMu.Unlock()
+
+	if err := n.refresh(); err != nil {
+		return nil, err
+	}
+
+	return n.tokens(), nil
+}
+
+// RefreshAccessTokenWith requests a new access token with the given refresh token, e.g. one stored
+// outside the client. The expiration of the token isn't known, so it's checked by the API only and
+// the RefreshExpires of the returned tokens is zero. In case of API error rest.ApiError returned
+func (n *Nordigen) RefreshAccessTokenWith(refreshToken string) (*Tokens, error) {
+	if refreshToken == "" {
+		return nil, ErrNoRefreshToken
+	}
+
+	n.authMu.Lock()
+	defer n.authMu.Unlock()
+
+	n.RefreshToken = refreshToken
+	n.RefreshTokenExpiration = time.Time{}
+
+	if err := n.requestAccessToken(); err != nil {
+		return nil, err
+	}
+
+	return n.tokens(), nil
+}
+
+func (n *Nordigen) tokens() *Tokens {
+	return &Tokens{
+		Access:         n.accessToken,
+		AccessExpires:  n.accessTokenExpiration,
+		Refresh:        n.RefreshToken,
+		RefreshExpires: n.RefreshTokenExpiration,
+	}
+}
@@ -35,6 +35,82 @@ func TestClient_refresh(t *testing.T) {
 	t.Run("refresh with an expired refresh token", testRefreshWithExpiredRefreshToken)
 }
 
+func TestClient_tokens(t *testing.T) {
+	t.Parallel()
+	t.Run("new token", testNewToken)
+	t.Run("refresh access token without a refresh token", testRefreshAccessTokenWithoutRefreshToken)
+	t.Run("refresh access token with a given refresh token", testRefreshAccessTokenWith)
+}
+
+func testRefreshAccessTokenWith(t *testing.T) {
+	// What/Arrange
+	responsePayload := fmt.Sprintf(`{"access":"%s","access_expires":%d}`, testAccessToken, testAccessTokenExpires)
+	srv := startServer(responsePayload, http.StatusOK)
+	defer srv.Close()
+
+	underTest := createTestNordigen(srv)
+
+	// When/Act
+	tokens, err := underTest.RefreshAccessTokenWith(testRefreshToken)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("refresh failed: %s", err)
+	}
+
+	if tokens.Access != testAccessToken || tokens.Refresh != testRefreshToken {
+		t.Fatalf("access token expected to be refreshed with the given refresh token, %+v returned", tokens)
+	}
+
+	if !tokens.RefreshExpires.IsZero() {
+		t.Fatalf("unknown refresh token expiration expected, %s returned", tokens.RefreshExpires)
+	}
+}
+
+func testNewToken(t *testing.T) {
+	// What/Arrange
+	responsePayload := fmt.Sprintf(
+		`{"access":"%s","access_expires":%d,"refresh":"%s","refresh_expires":%d}`,
+		testAccessToken,
+		testAccessTokenExpires,
+		testRefreshToken,
+		testRefreshTokenExpires)
+
+	srv := startServer(responsePayload, http.StatusOK)
+	defer srv.Close()
+
+	underTest := createTestNordigen(srv)
+
+	// When/Act
+	tokens, err := underTest.NewToken()
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if tokens.Access != testAccessToken || tokens.Refresh != testRefreshToken {
+		t.Errorf("issued tokens expected, got %+v", tokens)
+	}
+
+	if !tokens.AccessExpires.After(time.Now()) || !tokens.RefreshExpires.After(tokens.AccessExpires) {
+		t.Errorf("token expiration times expected, got %+v", tokens)
+	}
+}
+
+func testRefreshAccessTokenWithoutRefreshToken(t *testing.T) {
+	// What/Arrange
+	underTest := createTestNordigen(nil)
+
+	// When/Act
+	_, err := underTest.RefreshAccessToken()
+
+	// Then/Assert
+	if err != ErrNoRefreshToken {
+		t.Fatalf("ErrNoRefreshToken expected, got %v", err)
+	}
+}
+
 func testAuthenticationOk(t *testing.T) {
 	// What/Arrange
 	responsePayload := fmt.Sprintf(
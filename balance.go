@@ -14,6 +14,7 @@ const (
 // BalanceResource access to account's balances
 type BalanceResource struct {
 	nordigenResource[BalanceCollectionResponse]
+	accountID uuid.UUID
 }
 
 // BalanceCollectionResponse API response structure
@@ -49,12 +50,18 @@ func (r *AccountResource) Balance(accountID uuid.UUID) *BalanceResource {
 				Client: r.nordigen.restClient,
 			},
 		},
+		accountID,
 	}
 }
 
 // Get balances for the underlying account resource
 // In case API HTTP error response rest.ApiError will be returned,
+// ScopeError is returned without calling the API if the client's ScopeGuard rejects the request
 func (b *BalanceResource) Get() (*BalanceCollectionResponse, error) {
+	if err := b.nordigen.checkScope(b.accountID, AccessScopeBalances); err != nil {
+		return nil, err
+	}
+
 	return b.wrap(
 		func() (*BalanceCollectionResponse, error) {
 			return b.generic.Get("", nil)
@@ -0,0 +1,404 @@
+package nordigen
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultBankPickerLimit = 50
+
+	bankPickerCSRFCookie = "nordigen_csrf"
+	bankPickerCSRFParam  = "csrf_token"
+	bankPickerCSRFSize   = 32
+)
+
+var (
+	// ErrCountryNotAllowed returned by BankPicker for a country outside BankPicker.Countries
+	ErrCountryNotAllowed = errors.New("country not allowed")
+	// ErrAlreadyLinked returned by BankPicker when the flow with the same ID is already linked
+	ErrAlreadyLinked = errors.New("bank account already linked")
+)
+
+// DefaultBankPickerTemplate the default "picker" template of BankPicker, rendered with BankPickerPage
+const DefaultBankPickerTemplate = `{{define "picker"}}<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Choose your bank</title>
+</head>
+<body>
+<h1>Choose your bank</h1>
+<form method="get" action="">
+{{if .Countries}}<select name="country">
+{{range .Countries}}<option value="{{.}}"{{if eq . $.Country}} selected{{end}}>{{.}}</option>
+{{end}}</select>{{end}}
+<input type="search" name="q" value="{{.Query}}" placeholder="Search" autofocus>
+<button type="submit">Search</button>
+</form>
+{{if .Error}}<p role="alert">{{.Error}}</p>{{end}}
+<ul>
+{{range .Institutions}}<li>
+<form method="post" action="{{$.ChooseUrl}}">
+<input type="hidden" name="institution_id" value="{{.ID}}">
+<input type="hidden" name="country" value="{{$.Country}}">
+<input type="hidden" name="csrf_token" value="{{$.CSRFToken}}">
+<button type="submit">{{if .LogoUrl}}<img src="{{.LogoUrl}}" alt="" width="32" height="32"> {{end}}{{.Name}}</button>
+</form>
+</li>
+{{else}}<li>No banks found</li>
+{{end}}</ul>
+</body>
+</html>
+{{end}}`
+
+// defaultBankPickerTemplate rendered if BankPicker.Template is nil
+var defaultBankPickerTemplate = template.Must(template.New("picker").Parse(DefaultBankPickerTemplate))
+
+// BankPickerInstitution an institution shown by BankPicker
+type BankPickerInstitution struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	BIC  string `json:"bic"`
+	// LogoUrl relative URL of the cached logo, empty if BankPicker.Logos is nil
+	LogoUrl              string `json:"logo,omitempty"`
+	TransactionTotalDays int    `json:"transaction_total_days"`
+}
+
+// BankPickerPage the data the BankPicker template is rendered with
+type BankPickerPage struct {
+	Country      string                  `json:"country"`
+	Countries    []string                `json:"countries,omitempty"`
+	Query        string                  `json:"query"`
+	Institutions []BankPickerInstitution `json:"institutions"`
+	// ChooseUrl relative URL the chosen institution is posted to
+	ChooseUrl string `json:"choose_url"`
+	// CSRFToken must be posted as "csrf_token" with the chosen institution
+	CSRFToken string `json:"csrf_token"`
+	Error     string `json:"error,omitempty"`
+}
+
+// BankPicker serves the "choose your bank" page, as HTML or as JSON if requested with
+// "Accept: application/json" or "?format=json". Choosing a bank starts a LinkFlow and redirects the browser
+// to the requisition link. Must be mounted with a trailing slash and http.StripPrefix, e.g.
+// http.Handle("/banks/", http.StripPrefix("/banks", picker)), as the page uses relative URLs:
+//
+//	GET  /             the page, "country" and "q" query parameters
+//	POST /choose       "institution_id", "country" and "csrf_token" form values
+//	GET  /logos/<ID>   the cached logos if Logos is set
+//
+// The choice is protected against CSRF with a token in a cookie set by the page, which must be posted back
+// as "csrf_token". An unfinished flow with the same ID is abandoned before a new one starts,
+// a linked one is not replaced
+type BankPicker struct {
+	Client  *Nordigen
+	Catalog *InstitutionCatalog
+	Flows   LinkFlowStore
+	// Logos serves the cached logos, no logos are shown if nil
+	Logos *LogoCache
+	// Countries the end user can choose from, any country if empty
+	Countries []string
+	// DefaultCountry shown if no country is chosen. The first of Countries if empty, or all countries if both are empty
+	DefaultCountry string
+	// Redirect URL to your application after end-user authorization with ASPSP
+	Redirect string
+	// Params returns the parameters of the flow started for the chosen institution, e.g. with the session ID
+	// as the flow ID. Only the institution ID and Redirect are set if nil. InstitutionID is always overridden
+	Params func(r *http.Request, institution *InstitutionResponse) (*LinkFlowParams, error)
+	// RedirectHandler handles the end user returning from the institution, the picker stores its state
+	// before redirecting to the requisition link. The default state cookie is stored if nil
+	RedirectHandler *RedirectHandler
+	// InsecureCookie allows sending the CSRF cookie over plain HTTP, e.g. for local development
+	InsecureCookie bool
+	// OnError renders failures of choosing a bank, 502 Bad Gateway with the page by default
+	OnError func(w http.ResponseWriter, r *http.Request, err error)
+	// Template executed as "picker" with BankPickerPage. Overridable, defaults to DefaultBankPickerTemplate
+	Template *template.Template
+	// Limit of the institutions shown, 50 by default
+	Limit int
+}
+
+// NewBankPicker creates a picker with the default template
+func NewBankPicker(n *Nordigen, catalog *InstitutionCatalog, flows LinkFlowStore, redirect string) *BankPicker {
+	return &BankPicker{
+		Client:   n,
+		Catalog:  catalog,
+		Flows:    flows,
+		Redirect: redirect,
+		Template: template.Must(template.New("picker").Parse(DefaultBankPickerTemplate)),
+	}
+}
+
+// ServeHTTP routes the request to the page, the choice or the logos
+func (p *BankPicker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	route := strings.Trim(r.URL.Path, "/")
+	switch {
+	case route == "":
+		if !allowMethods(w, r, http.MethodGet, http.MethodHead) {
+			return
+		}
+		p.servePage(w, r)
+	case route == "choose":
+		if !allowMethods(w, r, http.MethodPost) {
+			return
+		}
+		p.serveChoice(w, r)
+	case strings.HasPrefix(route, "logos/") && p.Logos != nil:
+		p.Logos.ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (p *BankPicker) servePage(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, status := p.page(r, query.Get("country"), query.Get("q"))
+	page.CSRFToken = p.csrfToken(w, r)
+	p.render(w, r, status, page)
+}
+
+func (p *BankPicker) serveChoice(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, http.StatusText(http.StatusBadRequest), http.StatusBadRequest)
+		return
+	}
+
+	if !p.verifyCSRF(r) {
+		http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+		return
+	}
+
+	country, err := p.country(r.PostForm.Get("country"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	institution, err := p.Catalog.ByID(r.Context(), country, r.PostForm.Get("institution_id"))
+	if errors.Is(err, ErrInstitutionNotFound) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		p.fail(w, r, err)
+		return
+	}
+
+	params := &LinkFlowParams{Redirect: p.Redirect}
+	if p.Params != nil {
+		if params, err = p.Params(r, institution); err != nil {
+			p.fail(w, r, err)
+			return
+		}
+	}
+	params.InstitutionID = institution.ID
+
+	if err := p.replaceFlow(r, params.ID); err != nil {
+		if errors.Is(err, ErrAlreadyLinked) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		p.fail(w, r, err)
+		return
+	}
+
+	flow, err := p.Client.NewLinkFlow(r.Context(), p.Flows, params)
+	if err != nil {
+		p.fail(w, r, err)
+		return
+	}
+
+	link, err := flow.Start(r.Context())
+	if err != nil {
+		p.fail(w, r, err)
+		return
+	}
+
+	p.redirectHandler().SetState(w, flow.Record().Reference)
+	http.Redirect(w, r, link, http.StatusSeeOther)
+}
+
+// replaceFlow abandons the unfinished flow with the ID, so its agreement and requisition are not orphaned
+// by the new flow saved under the same ID. ErrAlreadyLinked is returned if the flow is linked
+func (p *BankPicker) replaceFlow(r *http.Request, ID string) error {
+	if ID == "" {
+		return nil
+	}
+
+	flow, err := p.Client.ResumeLinkFlow(r.Context(), p.Flows, ID)
+	if errors.Is(err, ErrLinkFlowNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if flow.State() == LinkStateLinked {
+		return ErrAlreadyLinked
+	}
+
+	return errors.Wrap(flow.Abandon(r.Context()), "error abandoning the previous link flow")
+}
+
+func (p *BankPicker) redirectHandler() *RedirectHandler {
+	if p.RedirectHandler == nil {
+		return &RedirectHandler{InsecureCookie: p.InsecureCookie}
+	}
+
+	return p.RedirectHandler
+}
+
+// csrfToken returns the token of the end user's browser, setting a new one if there is none
+func (p *BankPicker) csrfToken(w http.ResponseWriter, r *http.Request) string {
+	cookie, err := r.Cookie(bankPickerCSRFCookie)
+	if err == nil && len(cookie.Value) == referenceEncoding.EncodedLen(bankPickerCSRFSize) {
+		return cookie.Value
+	}
+
+	token := make([]byte, bankPickerCSRFSize)
+	if _, err := rand.Read(token); err != nil {
+		return ""
+	}
+
+	value := referenceEncoding.EncodeToString(token)
+	http.SetCookie(w, &http.Cookie{
+		Name:     bankPickerCSRFCookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !p.InsecureCookie,
+		SameSite: http.SameSiteStrictMode,
+	})
+
+	return value
+}
+
+// verifyCSRF reports whether the posted token matches the token of the end user's browser
+func (p *BankPicker) verifyCSRF(r *http.Request) bool {
+	cookie, err := r.Cookie(bankPickerCSRFCookie)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(r.PostForm.Get(bankPickerCSRFParam))) == 1
+}
+
+func (p *BankPicker) fail(w http.ResponseWriter, r *http.Request, err error) {
+	if p.OnError != nil {
+		p.OnError(w, r, err)
+		return
+	}
+
+	page, _ := p.page(r, r.PostForm.Get("country"), "")
+	page.CSRFToken = p.csrfToken(w, r)
+	page.Error = "The bank can't be connected right now, please try again later"
+	p.render(w, r, http.StatusBadGateway, page)
+}
+
+// page lists the institutions of the country matching the search, with the status to render it with
+func (p *BankPicker) page(r *http.Request, country, search string) (*BankPickerPage, int) {
+	page := &BankPickerPage{
+		Countries:    p.Countries,
+		Query:        search,
+		Institutions: []BankPickerInstitution{},
+		ChooseUrl:    "choose",
+	}
+
+	country, err := p.country(country)
+	if err != nil {
+		page.Error = err.Error()
+		return page, http.StatusBadRequest
+	}
+	page.Country = country
+
+	institutions, err := p.Catalog.Search(r.Context(), InstitutionQuery{Country: country, Name: search, Limit: p.limit()})
+	if err != nil {
+		page.Error = "The banks can't be listed right now, please try again later"
+		return page, http.StatusBadGateway
+	}
+
+	for _, institution := range institutions {
+		item := BankPickerInstitution{
+			ID:                   institution.ID,
+			Name:                 institution.Name,
+			BIC:                  institution.BIC,
+			TransactionTotalDays: institution.TransactionTotalDays,
+		}
+		if p.Logos != nil && institution.LogoUrl != "" {
+			item.LogoUrl = "logos/" + institution.ID
+		}
+		page.Institutions = append(page.Institutions, item)
+	}
+
+	return page, http.StatusOK
+}
+
+func (p *BankPicker) render(w http.ResponseWriter, r *http.Request, status int, page *BankPickerPage) {
+	if r.URL.Query().Get("format") == "json" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(page)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	tmpl := p.Template
+	if tmpl == nil {
+		tmpl = defaultBankPickerTemplate
+	}
+	_ = tmpl.ExecuteTemplate(w, "picker", page)
+}
+
+// country normalizes the chosen country and checks it's allowed
+func (p *BankPicker) country(country string) (string, error) {
+	country = strings.ToUpper(strings.TrimSpace(country))
+	if country == "" {
+		country = strings.ToUpper(p.DefaultCountry)
+	}
+
+	if country == "" && len(p.Countries) > 0 {
+		country = strings.ToUpper(p.Countries[0])
+	}
+
+	if len(p.Countries) == 0 {
+		return country, nil
+	}
+
+	for _, allowed := range p.Countries {
+		if strings.EqualFold(allowed, country) {
+			return country, nil
+		}
+	}
+
+	return "", ErrCountryNotAllowed
+}
+
+func (p *BankPicker) limit() int {
+	if p.Limit <= 0 {
+		return defaultBankPickerLimit
+	}
+
+	return p.Limit
+}
+
+// allowMethods responds with 405 Method Not Allowed if the request method isn't one of the methods
+func allowMethods(w http.ResponseWriter, r *http.Request, methods ...string) bool {
+	for _, method := range methods {
+		if r.Method == method {
+			return true
+		}
+	}
+
+	w.Header().Set("Allow", strings.Join(methods, ", "))
+	w.WriteHeader(http.StatusMethodNotAllowed)
+
+	return false
+}
@@ -0,0 +1,257 @@
+package nordigen
+
+import (
+	"context"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestBankPicker(t *testing.T) {
+	t.Parallel()
+	t.Run("bank picker page", testBankPickerPage)
+	t.Run("bank picker choice", testBankPickerChoice)
+	t.Run("bank picker keeps linked flow", testBankPickerLinkedFlow)
+	t.Run("bank picker template override", testBankPickerTemplate)
+	t.Run("bank picker default template", testBankPickerDefaultTemplate)
+}
+
+func startTestBankPicker(t *testing.T) (*BankPicker, *MemoryLinkFlowStore, func()) {
+	picker, flows, _, stop := startTestBankPickerApi(t)
+	return picker, flows, stop
+}
+
+func startTestBankPickerApi(t *testing.T) (*BankPicker, *MemoryLinkFlowStore, *fakeApi, func()) {
+	api := startCatalogApi()
+	api.institutions["N26_NTSBDEB1"].LogoUrl = "https://cdn.nordigen.com/ais/N26_NTSBDEB1.png"
+	srv := api.start()
+
+	n := createTestNordigen(srv)
+	flows := NewMemoryLinkFlowStore()
+	catalog := NewInstitutionCatalog(n)
+	picker := NewBankPicker(n, catalog, flows, "https://example.com/bank/callback")
+	picker.Logos = NewLogoCache(catalog, NewMemoryLogoStore())
+	picker.Countries = []string{"DE", "GB"}
+
+	if _, err := picker.Logos.Seed(context.Background(), "N26_NTSBDEB1", testPNG()); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	return picker, flows, api, srv.Close
+}
+
+func testBankPickerPage(t *testing.T) {
+	// What/Arrange
+	picker, _, stop := startTestBankPicker(t)
+	defer stop()
+
+	handler := http.StripPrefix("/banks", picker)
+
+	// When/Act
+	html := httptest.NewRecorder()
+	handler.ServeHTTP(html, httptest.NewRequest(http.MethodGet, "/banks/?q=koln", nil))
+
+	jsonResp := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/banks/?country=gb", nil)
+	req.Header.Set("Accept", "application/json")
+	handler.ServeHTTP(jsonResp, req)
+
+	logo := httptest.NewRecorder()
+	handler.ServeHTTP(logo, httptest.NewRequest(http.MethodGet, "/banks/logos/N26_NTSBDEB1", nil))
+
+	notAllowed := httptest.NewRecorder()
+	handler.ServeHTTP(notAllowed, httptest.NewRequest(http.MethodGet, "/banks/?country=FR", nil))
+
+	// Then/Assert
+	if html.Code != http.StatusOK || !strings.Contains(html.Body.String(), "Sparkasse KölnBonn") ||
+		strings.Contains(html.Body.String(), "Deutsche Bank") {
+		t.Errorf("search results of the default country expected, got %d %s", html.Code, html.Body.String())
+	}
+
+	page := &BankPickerPage{}
+	if err := json.NewDecoder(jsonResp.Body).Decode(page); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if page.Country != "GB" || len(page.Institutions) != 1 || page.Institutions[0].ID != "REVOLUT_REVOGB21" {
+		t.Errorf("institutions of the chosen country expected as JSON, got %+v", page)
+	}
+
+	if logo.Code != http.StatusOK || logo.Header().Get("Content-Type") != "image/png" {
+		t.Errorf("cached logo expected to be served, got %d", logo.Code)
+	}
+
+	if notAllowed.Code != http.StatusBadRequest {
+		t.Errorf("400 expected for a country not allowed, got %d", notAllowed.Code)
+	}
+}
+
+func testBankPickerChoice(t *testing.T) {
+	// What/Arrange
+	picker, flows, api, stop := startTestBankPickerApi(t)
+	defer stop()
+
+	picker.Params = func(r *http.Request, institution *InstitutionResponse) (*LinkFlowParams, error) {
+		return &LinkFlowParams{ID: "session-1", Redirect: picker.Redirect, UserLanguage: "DE"}, nil
+	}
+	handler := http.StripPrefix("/banks", picker)
+
+	pageResp := httptest.NewRecorder()
+	handler.ServeHTTP(pageResp, httptest.NewRequest(http.MethodGet, "/banks/?format=json", nil))
+	page := &BankPickerPage{}
+	if err := json.NewDecoder(pageResp.Body).Decode(page); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	csrf := findCookie(pageResp, bankPickerCSRFCookie)
+
+	choose := func(institutionID, token string) *httptest.ResponseRecorder {
+		form := url.Values{"institution_id": {institutionID}, "country": {"DE"}, "csrf_token": {token}}
+		req := httptest.NewRequest(http.MethodPost, "/banks/choose", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if csrf != nil {
+			req.AddCookie(csrf)
+		}
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		return resp
+	}
+
+	// When/Act
+	forged := choose("N26_NTSBDEB1", "forged")
+	first := choose("REVOLUT_REVOGB21", page.CSRFToken)
+	firstRecord, _ := flows.LoadLinkFlow(context.Background(), "session-1")
+	chosen := choose("N26_NTSBDEB1", page.CSRFToken)
+	unknown := choose("UNKNOWN", page.CSRFToken)
+
+	get := httptest.NewRecorder()
+	handler.ServeHTTP(get, httptest.NewRequest(http.MethodGet, "/banks/choose", nil))
+
+	// Then/Assert
+	if csrf == nil || page.CSRFToken != csrf.Value {
+		t.Fatalf("CSRF token expected in the page and the cookie, got %q and %v", page.CSRFToken, csrf)
+	}
+
+	if forged.Code != http.StatusForbidden {
+		t.Errorf("403 expected for a forged CSRF token, got %d", forged.Code)
+	}
+
+	if first.Code != http.StatusSeeOther || firstRecord == nil {
+		t.Fatalf("first flow expected to be started, got %d", first.Code)
+	}
+
+	if chosen.Code != http.StatusSeeOther || !strings.Contains(chosen.Header().Get("Location"), "/N26_NTSBDEB1") {
+		t.Fatalf("redirect to the requisition link expected, got %d %s", chosen.Code, chosen.Header().Get("Location"))
+	}
+
+	record, err := flows.LoadLinkFlow(context.Background(), "session-1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if record.State != LinkStateAwaitingUser || record.InstitutionID != "N26_NTSBDEB1" || record.Link != chosen.Header().Get("Location") {
+		t.Errorf("link flow expected to be started, got %+v", record)
+	}
+
+	if state := findCookie(chosen, defaultRedirectStateCookie); state == nil || state.Value != record.Reference {
+		t.Errorf("redirect state expected to be set to the reference %s, got %v", record.Reference, state)
+	}
+
+	if _, ok := api.requisitions[firstRecord.RequisitionID]; ok {
+		t.Error("requisition of the replaced flow expected to be deleted")
+	}
+
+	if _, ok := api.agreements[firstRecord.AgreementID]; ok {
+		t.Error("agreement of the replaced flow expected to be deleted")
+	}
+
+	if unknown.Code != http.StatusBadRequest {
+		t.Errorf("400 expected for an unknown institution, got %d", unknown.Code)
+	}
+
+	if get.Code != http.StatusMethodNotAllowed {
+		t.Errorf("405 expected for GET of the choice, got %d", get.Code)
+	}
+}
+
+func testBankPickerLinkedFlow(t *testing.T) {
+	// What/Arrange
+	picker, flows, stop := startTestBankPicker(t)
+	defer stop()
+
+	picker.Params = func(r *http.Request, institution *InstitutionResponse) (*LinkFlowParams, error) {
+		return &LinkFlowParams{ID: "session-1", Redirect: picker.Redirect}, nil
+	}
+	linked := &LinkFlowRecord{LinkFlowParams: LinkFlowParams{ID: "session-1", InstitutionID: "REVOLUT_REVOGB21"}, State: LinkStateLinked}
+	if err := flows.SaveLinkFlow(context.Background(), linked); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	form := url.Values{"institution_id": {"N26_NTSBDEB1"}, "country": {"DE"}, "csrf_token": {"token"}}
+	req := httptest.NewRequest(http.MethodPost, "/choose", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(&http.Cookie{Name: bankPickerCSRFCookie, Value: "token"})
+
+	// When/Act
+	resp := httptest.NewRecorder()
+	picker.ServeHTTP(resp, req)
+
+	// Then/Assert
+	if resp.Code != http.StatusConflict {
+		t.Errorf("409 expected for a linked flow, got %d", resp.Code)
+	}
+
+	record, _ := flows.LoadLinkFlow(context.Background(), "session-1")
+	if record == nil || record.State != LinkStateLinked {
+		t.Errorf("linked flow expected to be kept, got %+v", record)
+	}
+}
+
+func findCookie(resp *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, cookie := range resp.Result().Cookies() {
+		if cookie.Name == name {
+			return cookie
+		}
+	}
+
+	return nil
+}
+
+func testBankPickerDefaultTemplate(t *testing.T) {
+	// What/Arrange
+	picker, _, stop := startTestBankPicker(t)
+	defer stop()
+
+	literal := &BankPicker{Client: picker.Client, Catalog: picker.Catalog, Flows: picker.Flows, Redirect: picker.Redirect}
+
+	// When/Act
+	resp := httptest.NewRecorder()
+	literal.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?q=n26", nil))
+
+	// Then/Assert
+	if body := resp.Body.String(); resp.Code != http.StatusOK || !strings.Contains(body, "N26 Bank") {
+		t.Fatalf("default template expected to be rendered, got %d %s", resp.Code, body)
+	}
+}
+
+func testBankPickerTemplate(t *testing.T) {
+	// What/Arrange
+	picker, _, stop := startTestBankPicker(t)
+	defer stop()
+
+	picker.Template = template.Must(template.New("custom").Parse(
+		`{{define "picker"}}{{range .Institutions}}<a data-logo="{{.LogoUrl}}">{{.Name}}</a>{{end}}{{end}}`,
+	))
+
+	// When/Act
+	resp := httptest.NewRecorder()
+	picker.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/?q=n26", nil))
+
+	// Then/Assert
+	if body := resp.Body.String(); body != `<a data-logo="logos/N26_NTSBDEB1">N26 Bank</a>` {
+		t.Fatalf("custom template expected to be rendered, got %s", body)
+	}
+}
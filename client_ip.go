@@ -0,0 +1,118 @@
+package nordigen
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const headerForwardedFor = "X-Forwarded-For"
+
+// TrustedProxies the networks of the reverse proxies whose X-Forwarded-For header is trusted
+type TrustedProxies []*net.IPNet
+
+// ParseTrustedProxies parses CIDR networks or single IP addresses, e.g. "10.0.0.0/8" or "192.168.1.10"
+func ParseTrustedProxies(networks ...string) (TrustedProxies, error) {
+	proxies := make(TrustedProxies, 0, len(networks))
+	for _, network := range networks {
+		network = strings.TrimSpace(network)
+		if !strings.Contains(network, "/") {
+			ip := net.ParseIP(network)
+			if ip == nil {
+				return nil, errors.Errorf("invalid trusted proxy %q", network)
+			}
+
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			proxies = append(proxies, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid trusted proxy %q", network)
+		}
+		proxies = append(proxies, ipNet)
+	}
+
+	return proxies, nil
+}
+
+// Contains reports whether the address belongs to a trusted proxy
+func (p TrustedProxies) Contains(ip net.IP) bool {
+	for _, network := range p {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns the address of the end user. X-Forwarded-For is read right to left only while
+// the addresses belong to trusted proxies, so the entries added by the end user are never trusted.
+// Returns nil if the remote address of the request is invalid
+func (p TrustedProxies) ClientIP(r *http.Request) net.IP {
+	ip := parseRemoteAddr(r.RemoteAddr)
+	if ip == nil || !p.Contains(ip) {
+		return ip
+	}
+
+	hops := make([]string, 0)
+	for _, header := range r.Header.Values(headerForwardedFor) {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := net.ParseIP(strings.TrimSpace(hops[i]))
+		if hop == nil {
+			// the malformed entry has not been added by a trusted proxy, the last trusted hop is the client
+			return ip
+		}
+
+		ip = hop
+		if !p.Contains(ip) {
+			return ip
+		}
+	}
+
+	return ip
+}
+
+func parseRemoteAddr(remoteAddr string) net.IP {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+
+	return net.ParseIP(host)
+}
+
+// NewAcceptRequest builds the request for accepting an end user agreement from the end user's HTTP request.
+// The address is resolved with the trusted proxies, none are trusted if nil.
+// ValidationError is returned if the user agent or the address is missing
+func NewAcceptRequest(r *http.Request, trusted TrustedProxies) (*AcceptEndUserAgreementRequest, error) {
+	request := &AcceptEndUserAgreementRequest{
+		UserAgent: r.UserAgent(),
+		IPAddress: trusted.ClientIP(r),
+	}
+
+	verr := &ValidationError{}
+	if request.UserAgent == "" {
+		verr.add("user_agent", "must not be empty")
+	}
+
+	if request.IPAddress == nil {
+		verr.add("ip_address", "could not be determined from the request")
+	}
+
+	if err := verr.errorOrNil(); err != nil {
+		return nil, err
+	}
+
+	return request, nil
+}
@@ -0,0 +1,95 @@
+package nordigen
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+type clientIPTestCase struct {
+	name       string
+	remoteAddr string
+	forwarded  []string
+	expected   string
+}
+
+var clientIPTestCases = []*clientIPTestCase{
+	{"direct request", "203.0.113.7:5123", nil, "203.0.113.7"},
+	{"untrusted remote with spoofed header", "203.0.113.7:5123", []string{"198.51.100.1"}, "203.0.113.7"},
+	{"trusted proxy", "10.0.0.2:5123", []string{"198.51.100.1"}, "198.51.100.1"},
+	{"chain of trusted proxies", "10.0.0.2:5123", []string{"198.51.100.1, 10.0.0.5", "10.0.0.3"}, "198.51.100.1"},
+	{"spoofed entry before the client", "10.0.0.2:5123", []string{"192.0.2.66, 198.51.100.1"}, "198.51.100.1"},
+	{"malformed entry", "10.0.0.2:5123", []string{"unknown, 10.0.0.5"}, "10.0.0.5"},
+	{"only trusted hops", "10.0.0.2:5123", []string{"10.0.0.9"}, "10.0.0.9"},
+	{"single trusted address", "192.168.1.10:5123", []string{"2001:db8::1"}, "2001:db8::1"},
+}
+
+func TestTrustedProxies_ClientIP(t *testing.T) {
+	t.Parallel()
+	for _, tc := range clientIPTestCases {
+		t.Run(tc.name, testTrustedProxiesClientIP(tc))
+	}
+}
+
+func testTrustedProxiesClientIP(tc *clientIPTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// What/Arrange
+		underTest, err := ParseTrustedProxies("10.0.0.0/8", "192.168.1.10")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.RemoteAddr = tc.remoteAddr
+		for _, header := range tc.forwarded {
+			r.Header.Add("X-Forwarded-For", header)
+		}
+
+		// When/Act
+		ip := underTest.ClientIP(r)
+
+		// Then/Assert
+		if ip.String() != tc.expected {
+			t.Fatalf("%s expected, %s returned", tc.expected, ip)
+		}
+	}
+}
+
+func TestParseTrustedProxies_invalid(t *testing.T) {
+	// When/Act
+	_, err := ParseTrustedProxies("10.0.0.0/8", "proxy.local")
+
+	// Then/Assert
+	if err == nil {
+		t.Fatal("error expected for the invalid proxy address")
+	}
+}
+
+func TestNewAcceptRequest(t *testing.T) {
+	// What/Arrange
+	withAgent := httptest.NewRequest("GET", "/", nil)
+	withAgent.RemoteAddr = "203.0.113.7:5123"
+	withAgent.Header.Set("User-Agent", "Mozilla/5.0")
+
+	withoutAgent := httptest.NewRequest("GET", "/", nil)
+	withoutAgent.RemoteAddr = "invalid"
+
+	// When/Act
+	request, err := NewAcceptRequest(withAgent, nil)
+	_, invalidErr := NewAcceptRequest(withoutAgent, nil)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if request.UserAgent != "Mozilla/5.0" || request.IPAddress.String() != "203.0.113.7" {
+		t.Fatalf("user agent and address of the request expected, %v returned", request)
+	}
+
+	var verr *ValidationError
+	if !errors.As(invalidErr, &verr) || !verr.Has("user_agent") || !verr.Has("ip_address") {
+		t.Fatalf("ValidationError expected for the missing user agent and address, %v returned", invalidErr)
+	}
+}
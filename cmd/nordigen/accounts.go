@@ -0,0 +1,173 @@
+package main
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+func init() {
+	register(&command{
+		name:        "accounts",
+		description: "get the accounts, their details, balances and transactions",
+		subcommands: []subcommand{
+			{name: "get", usage: "<account ID>", run: accountsGet},
+			{name: "details", usage: "<account ID>", run: accountsDetails},
+			{name: "balances", usage: "<account ID>", run: accountsBalances},
+			{name: "transactions", usage: "<account ID> [-from 2006-01-02] [-to 2006-01-02]", run: accountsTransactions},
+		},
+	})
+}
+
+func accountsGet(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("accounts get", "<account ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	account, err := n.Account().Get(ID)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"ID", "INSTITUTION", "IBAN", "STATUS", "CREATED", "LAST_ACCESSED"}}
+	t.add(
+		account.ID.String(),
+		account.InstitutionID,
+		account.Iban,
+		account.Status,
+		formatTime(account.Created),
+		formatTime(account.LastAccessed),
+	)
+
+	return a.out.print(account, t)
+}
+
+func accountsDetails(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("accounts details", "<account ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	details, err := n.Account().Details(ID).Get()
+	if err != nil {
+		return err
+	}
+
+	info := details.Account
+	t := &table{header: []string{"IBAN", "CURRENCY", "OWNER", "NAME", "PRODUCT", "TYPE", "USAGE", "STATUS"}}
+	t.add(info.Iban, info.Currency, info.OwnerName, info.Name, info.Product, info.CashAccountType, info.Usage, info.Status)
+
+	return a.out.print(details, t)
+}
+
+func accountsBalances(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("accounts balances", "<account ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	balances, err := n.Account().Balance(ID).Get()
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"TYPE", "AMOUNT", "CURRENCY", "REFERENCE_DATE", "LAST_CHANGE"}}
+	for _, balance := range balances.Balances {
+		t.add(
+			balance.BalanceType,
+			balance.BalanceAmount.Amount,
+			balance.BalanceAmount.Currency,
+			balance.ReferenceDate,
+			formatTime(balance.LastChangeDateTime),
+		)
+	}
+
+	return a.out.print(balances, t)
+}
+
+func accountsTransactions(a *app, args []string) error {
+	fs := a.newFlagSet("accounts transactions", "<account ID> [-from 2006-01-02] [-to 2006-01-02]")
+	from := fs.String("from", "", "first booking date, the whole available history if empty")
+	to := fs.String("to", "", "last booking date, today if empty")
+	ID, err := parseIDArg(fs, args)
+	if err != nil {
+		return err
+	}
+
+	dateFrom, err := parseDateFlag("from", *from)
+	if err != nil {
+		return err
+	}
+
+	dateTo, err := parseDateFlag("to", *to)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	transactions, err := n.Account().Transaction(ID).Get(dateFrom, dateTo)
+	if err != nil {
+		return err
+	}
+
+	t := &table{header: []string{"STATUS", "ID", "BOOKING_DATE", "VALUE_DATE", "AMOUNT", "CURRENCY", "COUNTERPARTY", "DESCRIPTION"}}
+	addTransactions(t, "booked", transactions.Transactions.Booked)
+	addTransactions(t, "pending", transactions.Transactions.Pending)
+
+	return a.out.print(transactions, t)
+}
+
+func addTransactions(t *table, status string, transactions []nordigen.TransactionResponse) {
+	for _, transaction := range transactions {
+		counterparty := transaction.CreditorName
+		if counterparty == "" {
+			counterparty = transaction.DebtorName
+		}
+
+		t.add(
+			status,
+			transaction.ID.String(),
+			transaction.BookingDate,
+			transaction.ValueDate,
+			transaction.Amount.Amount,
+			transaction.Amount.Currency,
+			counterparty,
+			transaction.RemittanceInformationUnstructured,
+		)
+	}
+}
+
+// parseDateFlag parses an optional YYYY-MM-DD flag value
+func parseDateFlag(name, value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid -%s date", name)
+	}
+
+	return &date, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+func addTestAccount(api *fakeApi) uuid.UUID {
+	ID := uuid.New()
+	account := &fakeAccount{
+		account: nordigen.AccountResponse{ID: ID, Iban: "DE89370400440532013000", InstitutionID: "N26_NTSBDEB1", Status: "READY"},
+	}
+	account.details.Account = nordigen.AccountDetailsInfoResponse{Iban: "DE89370400440532013000", Currency: "EUR", OwnerName: "Jane Doe"}
+	account.balances.Balances = []nordigen.BalanceResponse{
+		{BalanceAmount: nordigen.Amount{Amount: "1234.56", Currency: "EUR"}, BalanceType: "closingBooked", ReferenceDate: "2022-03-31"},
+		{BalanceAmount: nordigen.Amount{Amount: "1200.00", Currency: "EUR"}, BalanceType: "interimAvailable", ReferenceDate: "2022-04-01"},
+	}
+	account.transactions.Transactions.Booked = []nordigen.TransactionResponse{
+		{
+			ID:                                uuid.New(),
+			Amount:                            nordigen.Amount{Amount: "-45.10", Currency: "EUR"},
+			BookingDate:                       "2022-03-01",
+			ValueDate:                         "2022-03-01",
+			CreditorName:                      "Grocery Store",
+			RemittanceInformationUnstructured: "Groceries",
+		},
+		{
+			ID:                                uuid.New(),
+			Amount:                            nordigen.Amount{Amount: "2500.00", Currency: "EUR"},
+			BookingDate:                       "2022-03-25",
+			ValueDate:                         "2022-03-25",
+			DebtorName:                        "Employer GmbH",
+			RemittanceInformationUnstructured: "Salary March",
+		},
+	}
+	account.transactions.Transactions.Pending = []nordigen.TransactionResponse{
+		{
+			Amount:                            nordigen.Amount{Amount: "-9.99", Currency: "USD"},
+			ValueDate:                         "2022-03-30",
+			CreditorName:                      "Streaming Service",
+			RemittanceInformationUnstructured: "Subscription",
+		},
+	}
+	api.accounts[ID] = account
+
+	return ID
+}
+
+func TestAccountsCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api)
+	srv := api.start(t)
+
+	// When/Act
+	account, _, accountCode := runTest(t, srv, nil, "accounts", "get", ID.String())
+	details, _, _ := runTest(t, srv, nil, "accounts", "details", ID.String())
+	balances, _, _ := runTest(t, srv, nil, "accounts", "balances", ID.String())
+	transactions, _, transactionsCode := runTest(t, srv, nil, "accounts", "transactions", ID.String(), "-from", "2022-03-10")
+	_, _, invalidID := runTest(t, srv, nil, "accounts", "get", "not-an-uuid")
+
+	// Then/Assert
+	if accountCode != 0 || !strings.Contains(account, "DE89370400440532013000") || !strings.Contains(details, "Jane Doe") {
+		t.Errorf("account and details expected, got %s %s", account, details)
+	}
+
+	if !strings.Contains(balances, "closingBooked") || !strings.Contains(balances, "1200.00") {
+		t.Errorf("balances expected, got %s", balances)
+	}
+
+	if transactionsCode != 0 || strings.Contains(transactions, "Groceries") || !strings.Contains(transactions, "Salary March") ||
+		!strings.Contains(transactions, "pending") {
+		t.Errorf("transactions since the date expected, got %d %s", transactionsCode, transactions)
+	}
+
+	if invalidID != 1 {
+		t.Errorf("error expected for an invalid ID, got %d", invalidID)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"gromson/nordigen"
+)
+
+func init() {
+	register(&command{
+		name:        "agreements",
+		description: "list, get, create and delete the end user agreements",
+		subcommands: []subcommand{
+			{name: "list", usage: "", run: agreementsList},
+			{name: "get", usage: "<agreement ID>", run: agreementsGet},
+			{
+				name:  "create",
+				usage: "-institution ID [-max-historical-days 90] [-access-valid-for-days 90] [-scope balances,details,transactions]",
+				run:   agreementsCreate,
+			},
+			{name: "delete", usage: "<agreement ID>", run: agreementsDelete},
+		},
+	})
+}
+
+func agreementsList(a *app, args []string) error {
+	if _, err := parseArgs(a.newFlagSet("agreements list", ""), args, 0); err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	list, err := n.EndUserAgreement().List()
+	if err != nil {
+		return err
+	}
+
+	agreements, err := list.All(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(agreements, agreementsTable(agreements...))
+}
+
+func agreementsGet(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("agreements get", "<agreement ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	agreement, err := n.EndUserAgreement().Get(ID)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(agreement, agreementsTable(*agreement))
+}
+
+func agreementsCreate(a *app, args []string) error {
+	fs := a.newFlagSet("agreements create", "-institution ID [flags]")
+	institution := fs.String("institution", "", "institution ID, required")
+	maxHistoricalDays := fs.Int("max-historical-days", 0, "length of the transaction history in days, the API default if zero")
+	accessValidForDays := fs.Int("access-valid-for-days", 0, "length of the access in days, the API default if zero")
+	scope := fs.String("scope", "", "comma-separated access scopes, the API default if empty")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *institution == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	request := &nordigen.CreateAgreementRequest{
+		InstitutionID:      *institution,
+		MaxHistoricalDays:  *maxHistoricalDays,
+		AccessValidForDays: *accessValidForDays,
+		AccessScope:        splitList(*scope),
+	}
+
+	if err := request.Validate(nil); err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	agreement, err := n.EndUserAgreement().Create(request)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(agreement, agreementsTable(*agreement))
+}
+
+func agreementsDelete(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("agreements delete", "<agreement ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	return n.EndUserAgreement().Delete(ID)
+}
+
+func agreementsTable(agreements ...nordigen.EndUserAgreementResponse) *table {
+	t := &table{header: []string{"ID", "INSTITUTION", "CREATED", "ACCEPTED", "HISTORY_DAYS", "ACCESS_DAYS", "SCOPE"}}
+	for _, agreement := range agreements {
+		t.add(
+			agreement.ID.String(),
+			agreement.InstitutionID,
+			formatTime(agreement.Created),
+			formatTimePtr(agreement.Accepted),
+			strconv.Itoa(agreement.MaxHistoricalDays),
+			strconv.Itoa(agreement.AccessValidForDays),
+			strings.Join(agreement.AccessScopes, ","),
+		)
+	}
+
+	return t
+}
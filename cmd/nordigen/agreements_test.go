@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gromson/nordigen"
+)
+
+func TestAgreementsCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start(t)
+
+	// When/Act
+	created, _, createCode := runTest(t, srv, nil, "-format", "json", "agreements", "create",
+		"-institution", "N26_NTSBDEB1", "-max-historical-days", "90", "-scope", "balances,transactions")
+	agreement := &nordigen.EndUserAgreementResponse{}
+	if err := json.Unmarshal([]byte(created), agreement); err != nil {
+		t.Fatalf("unexpected error occurred: %s %s", err, created)
+	}
+
+	_, _, _ = runTest(t, srv, nil, "agreements", "create", "-institution", "REVOLUT_REVOGB21")
+	list, _, listCode := runTest(t, srv, nil, "-format", "csv", "agreements", "list")
+	_, _, deleteCode := runTest(t, srv, nil, "agreements", "delete", agreement.ID.String())
+	_, invalidErr, invalidCode := runTest(t, srv, nil, "agreements", "create", "-institution", "N26_NTSBDEB1", "-scope", "everything")
+
+	// Then/Assert
+	if createCode != 0 || agreement.MaxHistoricalDays != 90 || len(agreement.AccessScopes) != 2 {
+		t.Errorf("agreement expected to be created, got %d %s", createCode, created)
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(list)).ReadAll()
+	if err != nil || listCode != 0 || len(rows) != 3 || rows[1][1] != "N26_NTSBDEB1" || rows[2][1] != "REVOLUT_REVOGB21" {
+		t.Errorf("all agreements expected as CSV, got %d %s", listCode, list)
+	}
+
+	if deleteCode != 0 || len(api.agreements) != 1 {
+		t.Errorf("agreement expected to be deleted, got %d", deleteCode)
+	}
+
+	if invalidCode != 1 || !strings.Contains(invalidErr, "access_scope") {
+		t.Errorf("invalid scope expected to be rejected before calling the API, got %d %s", invalidCode, invalidErr)
+	}
+}
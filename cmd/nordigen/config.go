@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+const (
+	envSecretID   = "NORDIGEN_SECRET_ID"
+	envSecretKey  = "NORDIGEN_SECRET_KEY"
+	envBaseUrl    = "NORDIGEN_BASE_URL"
+	envConfigPath = "NORDIGEN_CONFIG"
+)
+
+// config the credentials of the API. Read from the config file, e.g.
+//
+//	{"secret_id": "c2256760-abc0-49a2-968d-b4cb4cf715d0", "secret_key": "88812918b15...93a59239bb7"}
+//
+// The environment variables override the file
+type config struct {
+	SecretID  string `json:"secret_id"`
+	SecretKey string `json:"secret_key"`
+	// BaseUrl of the API including the version, "https://ob.nordigen.com/api/v2" if empty
+	BaseUrl string `json:"base_url,omitempty"`
+	// Sync the accounts refreshed by the serve and sync commands
+	Sync syncConfig `json:"sync"`
+}
+
+// syncConfig the accounts refreshed by the serve and sync commands, e.g.
+//
+//	"sync": {"references": ["customer-42"], "store": "/var/lib/nordigen", "listen": ":8080"}
+type syncConfig struct {
+	// Requisitions the IDs of the requisitions whose accounts are refreshed
+	Requisitions []uuid.UUID `json:"requisitions"`
+	// References of the requisitions whose accounts are refreshed
+	References []string `json:"references"`
+	// Store the directory of the refreshed data
+	Store string `json:"store"`
+	// DailyLimit the number of refreshes of an account per day, 4 by default as allowed by the API
+	DailyLimit int `json:"daily_limit"`
+	// HistoryDays the number of days of transactions fetched on the first refresh, 90 by default
+	HistoryDays int `json:"history_days"`
+	// Listen the address of the health and status endpoints of the serve command, 127.0.0.1:8080 by default
+	Listen string `json:"listen"`
+}
+
+// loadConfig reads the config file and applies the environment variables. The file is the given path,
+// NORDIGEN_CONFIG or nordigen/config.json in the user config directory. Only a missing default file is ignored
+func loadConfig(path string, getenv func(string) string) (*config, error) {
+	cfg := &config{}
+
+	explicit := path != ""
+	if !explicit {
+		path = getenv(envConfigPath)
+		explicit = path != ""
+	}
+	if !explicit {
+		if dir, err := os.UserConfigDir(); err == nil {
+			path = filepath.Join(dir, "nordigen", "config.json")
+		}
+	}
+
+	if path != "" {
+		payload, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist) && !explicit:
+		case err != nil:
+			return nil, errors.Wrap(err, "error reading config")
+		default:
+			if err := json.Unmarshal(payload, cfg); err != nil {
+				return nil, errors.Wrapf(err, "error parsing config %s", path)
+			}
+		}
+	}
+
+	if v := getenv(envSecretID); v != "" {
+		cfg.SecretID = v
+	}
+	if v := getenv(envSecretKey); v != "" {
+		cfg.SecretKey = v
+	}
+	if v := getenv(envBaseUrl); v != "" {
+		cfg.BaseUrl = v
+	}
+
+	if cfg.SecretID == "" || cfg.SecretKey == "" {
+		return nil, errors.New("credentials not configured, set " + envSecretID + " and " + envSecretKey + " or use a config file")
+	}
+
+	return cfg, nil
+}
+
+// client creates the API client with the credentials
+func (c *config) client() (*nordigen.Nordigen, error) {
+	client, err := nordigen.New(c.SecretID, c.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.BaseUrl != "" {
+		if err := client.SetBaseUrl(c.BaseUrl); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+	t.Run("config file with env override", testLoadConfigFile)
+	t.Run("missing credentials", testLoadConfigMissing)
+	t.Run("missing explicit config file", testLoadConfigMissingFile)
+}
+
+func testLoadConfigFile(t *testing.T) {
+	// What/Arrange
+	path := filepath.Join(t.TempDir(), "config.json")
+	payload := `{"secret_id":"c2256760-abc0-49a2-968d-b4cb4cf715d0","secret_key":"88812918b15a","base_url":"https://file.example.com/v2"}`
+	if err := os.WriteFile(path, []byte(payload), 0o600); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	env := map[string]string{envBaseUrl: "https://env.example.com/v2"}
+
+	// When/Act
+	cfg, err := loadConfig(path, func(key string) string { return env[key] })
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if cfg.SecretID != "c2256760-abc0-49a2-968d-b4cb4cf715d0" || cfg.SecretKey != "88812918b15a" || cfg.BaseUrl != "https://env.example.com/v2" {
+		t.Errorf("file config with the env override expected, got %+v", cfg)
+	}
+
+	if _, err := cfg.client(); err != nil {
+		t.Errorf("unexpected error occurred: %s", err)
+	}
+}
+
+func testLoadConfigMissing(t *testing.T) {
+	// What/Arrange
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(`{"secret_id":"c2256760-abc0-49a2-968d-b4cb4cf715d0"}`), 0o600); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	// When/Act
+	_, err := loadConfig(path, func(string) string { return "" })
+
+	// Then/Assert
+	if err == nil {
+		t.Fatal("error expected if the secret key is missing")
+	}
+}
+
+func testLoadConfigMissingFile(t *testing.T) {
+	// What/Arrange
+	env := map[string]string{
+		envConfigPath: filepath.Join(t.TempDir(), "missing.json"),
+		envSecretID:   "c2256760-abc0-49a2-968d-b4cb4cf715d0",
+		envSecretKey:  "88812918b15a",
+	}
+
+	// When/Act
+	_, err := loadConfig("", func(key string) string { return env[key] })
+
+	// Then/Assert
+	if err == nil {
+		t.Fatal("error expected if the explicitly configured file is missing")
+	}
+}
@@ -0,0 +1,446 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+	exportcsv "gromson/nordigen/export/csv"
+)
+
+// defaultExportDays the period exported by default if the institution's history length isn't known
+const defaultExportDays = 90
+
+func init() {
+	register(&command{
+		name:        "export",
+		description: "export the transactions of an account as a statement file",
+		run:         export,
+	})
+}
+
+// exportState the state of an incremental export, stored next to the output file
+type exportState struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Format    string    `json:"format"`
+	// LastDate the booking date of the most recent exported transaction
+	LastDate string `json:"last_date"`
+	// LastKeys the keys of the exported transactions booked on the LastDate, a key repeated
+	// for every identical transaction
+	LastKeys []string `json:"last_keys"`
+	// Sequence the number of the last exported statement
+	Sequence int `json:"sequence"`
+}
+
+func export(a *app, args []string) error {
+	fs := a.newFlagSet("export", "-account ID [-from 2006-01-02] [-to 2006-01-02] [-format csv] [-output FILE] [flags]")
+	account := fs.String("account", "", "account ID, required")
+	from := fs.String("from", "", "first booking date, the institution's whole history if empty")
+	to := fs.String("to", "", "last booking date, today if empty")
+	format := fs.String("format", "csv", "statement format: "+strings.Join(exportFormats(), ", "))
+	output := fs.String("output", "", "output file, the standard output if empty or -")
+	status := fs.String("status", statusBooked, "exported transactions: booked, pending or all")
+	currency := fs.String("currency", "", "export only the transactions in the currency")
+	appendExport := fs.Bool("append", false, "append the transactions booked since the previous export to the output file")
+	chunkDays := fs.Int("chunk-days", 0, "maximum number of days fetched by a single request, 90 if zero")
+	csvColumns := fs.String("csv-columns", "", "comma-separated Field[:Header] columns of the csv format, e.g. BookingDate:Date,Amount,CreditorAccount.Iban")
+	csvLocale := fs.String("csv-locale", "", "number and date format of the csv format: de or fr, ISO dates and a decimal point if empty")
+	csvDebitCredit := fs.Bool("csv-debit-credit", false, "separate debit and credit amount columns in the csv format")
+	csvBOM := fs.Bool("csv-bom", false, "start the csv format with the UTF-8 byte order mark")
+	ledgerAccount := fs.String("beancount-account", "", "beancount account of the bank account, derived from the institution if empty")
+	intuBID := fs.String("qfx-bid", "", "Intuit bank ID of the qfx format, required by Quicken and QuickBooks")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	accountID, err := uuid.Parse(*account)
+	if err != nil {
+		fs.Usage()
+		return errUsage
+	}
+
+	writer, ok := statementWriters[*format]
+	if !ok {
+		return errors.Errorf("unknown format %q, expected one of %s", *format, strings.Join(exportFormats(), ", "))
+	}
+
+	if *status != statusBooked && *status != statusPending && *status != "all" {
+		return errors.Errorf("unknown status %q, expected booked, pending or all", *status)
+	}
+
+	if bookedOnlyFormats[*format] && *status != statusBooked {
+		return errors.Errorf("the %s format contains only booked transactions", *format)
+	}
+
+	if *format == "qfx" && *intuBID == "" {
+		return errors.New("the qfx format requires -qfx-bid")
+	}
+
+	csvOpts, err := parseCSVOptions(*csvColumns, *csvLocale, *csvDebitCredit, *csvBOM)
+	if err != nil {
+		return err
+	}
+
+	toFile := *output != "" && *output != "-"
+	if *appendExport {
+		if !toFile {
+			return errors.New("-append requires an -output file")
+		}
+		if !appendableFormats[*format] {
+			return errors.Errorf("the %s format can't be appended to", *format)
+		}
+		if *status != statusBooked {
+			return errors.New("-append exports only booked transactions")
+		}
+	}
+
+	dateTo := truncateDate(time.Now())
+	if *to != "" {
+		parsed, err := parseDateFlag("to", *to)
+		if err != nil {
+			return err
+		}
+		dateTo = *parsed
+	}
+
+	var dateFrom *time.Time
+	if *from != "" {
+		if dateFrom, err = parseDateFlag("from", *from); err != nil {
+			return err
+		}
+	}
+
+	var state *exportState
+	if *appendExport {
+		if state, err = loadExportState(*output, accountID, *format); err != nil {
+			return err
+		}
+		// a previous export without booked transactions has no last date, the period isn't narrowed then
+		if state != nil && state.LastDate != "" {
+			last, err := time.Parse("2006-01-02", state.LastDate)
+			if err != nil {
+				return errors.Wrap(err, "invalid export state")
+			}
+			// the transactions of the last date are fetched again, the already exported ones are skipped
+			dateFrom = &last
+		}
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	filter := &exportFilter{status: *status, currency: *currency, state: state}
+	s, err := fetchStatement(a.ctx, n, accountID, dateFrom, dateTo, *chunkDays, filter)
+	if err != nil {
+		return err
+	}
+
+	opts := &exportOptions{appending: state != nil, sequence: 1, ledgerAccount: *ledgerAccount, csv: *csvOpts, intuBID: *intuBID}
+	if opts.ledgerAccount == "" {
+		opts.ledgerAccount = beancountAccount(s.InstitutionID)
+	}
+	if state != nil {
+		opts.sequence = state.Sequence + 1
+	}
+
+	if !toFile {
+		return writer(a.stdout, s, opts)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := writer(buf, s, opts); err != nil {
+		return err
+	}
+
+	if err := writeExport(*output, buf.Bytes(), opts.appending); err != nil {
+		return err
+	}
+
+	if *appendExport {
+		if err := saveExportState(*output, nextExportState(state, accountID, *format, s.Booked)); err != nil {
+			return err
+		}
+	}
+
+	_, _ = fmt.Fprintf(a.stderr, "Exported %d booked and %d pending transactions to %s\n", len(s.Booked), len(s.Pending), *output)
+
+	return nil
+}
+
+// parseCSVOptions parses the flags of the csv format
+func parseCSVOptions(columns, locale string, debitCredit, bom bool) (*exportcsv.Options, error) {
+	opts := &exportcsv.Options{BOM: bom}
+	if debitCredit {
+		opts.Amount = exportcsv.DebitCredit
+	}
+
+	switch strings.ToLower(locale) {
+	case "":
+	case "de":
+		opts.Locale = exportcsv.LocaleDE
+	case "fr":
+		opts.Locale = exportcsv.LocaleFR
+	default:
+		return nil, errors.Errorf("unknown csv locale %q, expected de or fr", locale)
+	}
+
+	if columns != "" {
+		parsed, err := exportcsv.ParseColumns(columns)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid -csv-columns")
+		}
+		opts.Columns = parsed
+	}
+
+	return opts, nil
+}
+
+// fetchStatement fetches the transactions of the period along with the account's details and balances.
+// The details and balances are optional, the statement is built without them if they aren't available
+func fetchStatement(
+	ctx context.Context,
+	n *nordigen.Nordigen,
+	accountID uuid.UUID,
+	from *time.Time,
+	to time.Time,
+	chunkDays int,
+	filter *exportFilter,
+) (*statement, error) {
+	account, err := n.Account().Get(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &nordigen.GetRangeOptions{ChunkDays: chunkDays, StopOnError: true}
+	if institution, err := n.Institution().Get(account.InstitutionID); err == nil {
+		opts.Institution = institution
+	}
+
+	dateFrom := to.AddDate(0, 0, -defaultExportDays+1)
+	if from != nil {
+		dateFrom = *from
+	} else if opts.Institution != nil && opts.Institution.TransactionTotalDays > 0 {
+		// clamped to the available history by GetRange
+		dateFrom = to.AddDate(0, 0, -opts.Institution.TransactionTotalDays+1)
+	}
+
+	if dateFrom.After(to) {
+		return nil, errors.New("-from is after -to")
+	}
+
+	transactions, err := n.Account().Transaction(accountID).GetRange(ctx, dateFrom, to, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var info *nordigen.AccountDetailsInfoResponse
+	if details, err := n.Account().Details(accountID).Get(); err == nil {
+		info = &details.Account
+	}
+
+	var balances []nordigen.BalanceResponse
+	// the current balances are the closing balance only if the statement ends today
+	if !to.Before(truncateDate(time.Now())) {
+		if res, err := n.Account().Balance(accountID).Get(); err == nil {
+			balances = res.Balances
+		}
+	}
+
+	booked, pending := filter.apply(transactions.Transactions.Booked, transactions.Transactions.Pending)
+	if filter.currency != "" {
+		if info == nil {
+			info = &nordigen.AccountDetailsInfoResponse{}
+		}
+		withCurrency := *info
+		withCurrency.Currency = strings.ToUpper(filter.currency)
+		info = &withCurrency
+	}
+
+	s, err := newStatement(accountID, info, balances, dateFrom, to, booked, pending)
+	if err != nil {
+		return nil, err
+	}
+	s.InstitutionID = account.InstitutionID
+	if s.Iban == "" {
+		s.Iban = account.Iban
+	}
+
+	return s, nil
+}
+
+// exportFilter selects the exported transactions
+type exportFilter struct {
+	// status booked, pending or all
+	status   string
+	currency string
+	// state of the incremental export, the previously exported transactions are skipped
+	state *exportState
+}
+
+// apply returns the exported booked and pending transactions
+func (f *exportFilter) apply(booked, pending []nordigen.TransactionResponse) ([]nordigen.TransactionResponse, []nordigen.TransactionResponse) {
+	booked = filterCurrency(booked, f.currency)
+	pending = filterCurrency(pending, f.currency)
+
+	switch f.status {
+	case statusBooked:
+		pending = nil
+	case statusPending:
+		booked = nil
+	}
+
+	if f.state != nil {
+		booked = skipExported(booked, f.state)
+	}
+
+	return booked, pending
+}
+
+// filterCurrency keeps the transactions in the currency, all of them if the currency is empty
+func filterCurrency(transactions []nordigen.TransactionResponse, currency string) []nordigen.TransactionResponse {
+	if currency == "" {
+		return transactions
+	}
+
+	kept := make([]nordigen.TransactionResponse, 0, len(transactions))
+	for _, t := range transactions {
+		if strings.EqualFold(t.Amount.Currency, currency) {
+			kept = append(kept, t)
+		}
+	}
+
+	return kept
+}
+
+// skipExported removes the transactions exported previously. The keys of identical transactions without an ID
+// are equal, so as many transactions of a key are skipped as have been exported
+func skipExported(transactions []nordigen.TransactionResponse, state *exportState) []nordigen.TransactionResponse {
+	exported := make(map[string]int, len(state.LastKeys))
+	for _, key := range state.LastKeys {
+		exported[key]++
+	}
+
+	kept := make([]nordigen.TransactionResponse, 0, len(transactions))
+	for i := range transactions {
+		t := &transactions[i]
+		date := transactionDate(t)
+		if date < state.LastDate {
+			continue
+		}
+		if key := t.Key(); date == state.LastDate && exported[key] > 0 {
+			exported[key]--
+			continue
+		}
+		kept = append(kept, *t)
+	}
+
+	return kept
+}
+
+// nextExportState returns the state after exporting the booked transactions
+func nextExportState(previous *exportState, accountID uuid.UUID, format string, booked []nordigen.TransactionResponse) *exportState {
+	state := &exportState{AccountID: accountID, Format: format, LastKeys: []string{}, Sequence: 1}
+	if previous != nil {
+		state.LastDate = previous.LastDate
+		state.LastKeys = append(state.LastKeys, previous.LastKeys...)
+		state.Sequence = previous.Sequence + 1
+	}
+
+	for i := range booked {
+		date := transactionDate(&booked[i])
+		if date > state.LastDate {
+			state.LastDate = date
+			state.LastKeys = state.LastKeys[:0]
+		}
+		if date == state.LastDate {
+			state.LastKeys = append(state.LastKeys, booked[i].Key())
+		}
+	}
+
+	return state
+}
+
+func exportStatePath(output string) string {
+	return output + ".state"
+}
+
+// loadExportState loads the state of the export to the output file, nil if the file doesn't exist yet
+func loadExportState(output string, accountID uuid.UUID, format string) (*exportState, error) {
+	data, err := os.ReadFile(exportStatePath(output))
+	if errors.Is(err, os.ErrNotExist) {
+		if _, err := os.Stat(output); err == nil {
+			return nil, errors.Errorf("%s exists but wasn't exported with -append, the state %s is missing", output, exportStatePath(output))
+		}
+
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading export state")
+	}
+
+	state := &exportState{}
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, errors.Wrap(err, "error reading export state")
+	}
+
+	if state.AccountID != accountID || state.Format != format {
+		return nil, errors.Errorf("%s is the %s export of the account %s", output, state.Format, state.AccountID)
+	}
+
+	return state, nil
+}
+
+func saveExportState(output string, state *exportState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrap(os.WriteFile(exportStatePath(output), data, 0o600), "error writing export state")
+}
+
+// writeExport writes the exported data to the file, appending to it if required
+func writeExport(path string, data []byte, appending bool) error {
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appending {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	f, err := os.OpenFile(path, flags, 0o600)
+	if err != nil {
+		return errors.Wrap(err, "error opening output file")
+	}
+
+	if _, err := io.Copy(f, bytes.NewReader(data)); err != nil {
+		_ = f.Close()
+		return errors.Wrap(err, "error writing output file")
+	}
+
+	return errors.Wrap(f.Close(), "error writing output file")
+}
+
+func exportFormats() []string {
+	formats := make([]string, 0, len(statementWriters))
+	for format := range statementWriters {
+		formats = append(formats, format)
+	}
+	sort.Strings(formats)
+
+	return formats
+}
+
+// truncateDate returns the UTC midnight of the date
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+	exportcsv "gromson/nordigen/export/csv"
+)
+
+const (
+	statusBooked  = "booked"
+	statusPending = "pending"
+)
+
+// exportOptions the options of the statement writers
+type exportOptions struct {
+	// appending to an existing export, the headers are not written again
+	appending bool
+	// sequence number of the statement, starting from 1
+	sequence int
+	// ledgerAccount the beancount account of the bank account
+	ledgerAccount string
+	// csv the columns and the locale of the csv format
+	csv exportcsv.Options
+	// intuBID the Intuit bank ID of the qfx format
+	intuBID string
+}
+
+// statementWriter writes the statement in a format
+type statementWriter func(w io.Writer, s *statement, opts *exportOptions) error
+
+var statementWriters = map[string]statementWriter{
+	"csv":       writeCSV,
+	"jsonl":     writeJSONL,
+	"qif":       writeQIF,
+	"beancount": writeBeancount,
+	"mt940":     writeMT940,
+	"camt053":   writeCamt053,
+	"ofx":       writeOFX,
+	"qfx":       writeQFX,
+}
+
+// appendableFormats the formats an export can be appended to, the others are single documents
+var appendableFormats = map[string]bool{"csv": true, "jsonl": true, "qif": true, "beancount": true, "mt940": true}
+
+// bookedOnlyFormats the formats which can't contain pending transactions
+var bookedOnlyFormats = map[string]bool{"mt940": true, "ofx": true, "qfx": true}
+
+// errBalanceUnknown the statement formats with balances can't be written without the booked balance
+var errBalanceUnknown = errors.New("the booked balance isn't known, export up to today with the balances scope")
+
+// statementEntry a transaction of the statement with its parsed amount
+type statementEntry struct {
+	status      string
+	transaction *nordigen.TransactionResponse
+	amount      *big.Rat
+}
+
+// entries returns the booked transactions ordered by the booking date followed by the pending ones
+func (s *statement) entries() ([]statementEntry, error) {
+	entries := make([]statementEntry, 0, len(s.Booked)+len(s.Pending))
+	add := func(status string, transactions []nordigen.TransactionResponse) error {
+		start := len(entries)
+		for i := range transactions {
+			amount, err := parseAmount(transactions[i].Amount.Amount)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, statementEntry{status, &transactions[i], amount})
+		}
+
+		added := entries[start:]
+		sort.SliceStable(added, func(i, j int) bool {
+			return transactionDate(added[i].transaction) < transactionDate(added[j].transaction)
+		})
+
+		return nil
+	}
+
+	if err := add(statusBooked, s.Booked); err != nil {
+		return nil, err
+	}
+	if err := add(statusPending, s.Pending); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// csvColumns the default columns of the csv format
+var csvColumns = []exportcsv.Column{
+	{Header: "status", Field: exportcsv.FieldStatus},
+	{Header: "booking_date", Field: "BookingDate"},
+	{Header: "value_date", Field: "ValueDate"},
+	{Header: "amount", Field: "Amount"},
+	{Header: "currency", Field: "Amount.Currency"},
+	{Header: "counterparty", Value: func(t *nordigen.TransactionResponse, _ exportcsv.Status) string {
+		name, _ := csvCounterparty(t)
+		return name
+	}},
+	{Header: "counterparty_account", Value: func(t *nordigen.TransactionResponse, _ exportcsv.Status) string {
+		_, account := csvCounterparty(t)
+		return accountIdentifier(account)
+	}},
+	{Header: "description", Value: func(t *nordigen.TransactionResponse, _ exportcsv.Status) string {
+		return description(t)
+	}},
+	{Header: "transaction_id", Field: "ID"},
+	{Header: "bank_transaction_code", Field: "BankTransactionCode"},
+}
+
+func csvCounterparty(t *nordigen.TransactionResponse) (string, *nordigen.AccountReference) {
+	amount, err := parseAmount(t.Amount.Amount)
+	if err != nil {
+		return "", nil
+	}
+
+	return counterparty(t, amount)
+}
+
+func writeCSV(w io.Writer, s *statement, opts *exportOptions) error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	csvOpts := opts.csv
+	if len(csvOpts.Columns) == 0 {
+		csvOpts.Columns = csvColumns
+	}
+	csvOpts.NoHeader = opts.appending
+	csvOpts.BOM = csvOpts.BOM && !opts.appending
+
+	cw, err := exportcsv.NewWriter(w, &csvOpts)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range entries {
+		if err := cw.Write(exportcsv.Status(e.status), e.transaction); err != nil {
+			return err
+		}
+	}
+
+	return cw.Flush()
+}
+
+// jsonlTransaction a line of the JSON Lines export
+type jsonlTransaction struct {
+	AccountID uuid.UUID `json:"account_id"`
+	Status    string    `json:"status"`
+	nordigen.TransactionResponse
+}
+
+func writeJSONL(w io.Writer, s *statement, _ *exportOptions) error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(jsonlTransaction{AccountID: s.AccountID, Status: e.status, TransactionResponse: *e.transaction}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeQIF(w io.Writer, s *statement, opts *exportOptions) error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	if !opts.appending {
+		_, _ = bw.WriteString("!Type:Bank\n")
+	}
+
+	for _, e := range entries {
+		date, err := time.Parse("2006-01-02", transactionDate(e.transaction))
+		if err != nil {
+			return err
+		}
+
+		name, _ := counterparty(e.transaction, e.amount)
+		_, _ = fmt.Fprintf(bw, "D%s\n", date.Format("01/02/2006"))
+		_, _ = fmt.Fprintf(bw, "T%s\n", formatAmount(e.amount, e.transaction.Amount.Amount, "."))
+		if e.status == statusBooked {
+			_, _ = bw.WriteString("C*\n")
+		}
+		if ID := transactionID(e.transaction); ID != "" {
+			_, _ = fmt.Fprintf(bw, "N%s\n", singleLine(ID))
+		}
+		if name != "" {
+			_, _ = fmt.Fprintf(bw, "P%s\n", singleLine(name))
+		}
+		if memo := description(e.transaction); memo != "" {
+			_, _ = fmt.Fprintf(bw, "M%s\n", singleLine(memo))
+		}
+		_, _ = bw.WriteString("^\n")
+	}
+
+	return bw.Flush()
+}
+
+func writeBeancount(w io.Writer, s *statement, opts *exportOptions) error {
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, e := range entries {
+		flag := "*"
+		if e.status == statusPending {
+			flag = "!"
+		}
+
+		counterAccount := "Expenses:Uncategorized"
+		if e.amount.Sign() > 0 {
+			counterAccount = "Income:Uncategorized"
+		}
+
+		name, _ := counterparty(e.transaction, e.amount)
+		_, _ = fmt.Fprintf(bw, "%s %s %s %s\n", transactionDate(e.transaction), flag,
+			beancountString(name), beancountString(description(e.transaction)))
+		if ID := transactionID(e.transaction); ID != "" {
+			_, _ = fmt.Fprintf(bw, "  transaction_id: %s\n", beancountString(ID))
+		}
+		_, _ = fmt.Fprintf(bw, "  %s  %s %s\n", opts.ledgerAccount,
+			formatAmount(e.amount, e.transaction.Amount.Amount, "."), e.transaction.Amount.Currency)
+		_, _ = fmt.Fprintf(bw, "  %s\n\n", counterAccount)
+	}
+
+	return bw.Flush()
+}
+
+// beancountAccount derives a valid beancount account name from the institution ID
+func beancountAccount(institutionID string) string {
+	name := regexp.MustCompile(`[^A-Za-z0-9-]+`).ReplaceAllString(institutionID, "-")
+	name = strings.Trim(name, "-")
+	if name == "" {
+		return "Assets:Bank"
+	}
+
+	return "Assets:Bank:" + strings.ToUpper(name[:1]) + name[1:]
+}
+
+func beancountString(value string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", " ", "\r", " ").Replace(value) + `"`
+}
+
+func writeMT940(w io.Writer, s *statement, opts *exportOptions) error {
+	if !s.BalanceKnown {
+		return errors.Wrap(errBalanceUnknown, "mt940")
+	}
+
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintf(bw, ":20:%s\n", swiftText(strings.ReplaceAll(s.Created.Format("20060102150405"), " ", ""), 16))
+	_, _ = fmt.Fprintf(bw, ":25:%s\n", swiftText(firstNonEmpty(s.Iban, s.AccountID.String()), 35))
+	_, _ = fmt.Fprintf(bw, ":28C:%05d/001\n", opts.sequence)
+	_, _ = fmt.Fprintf(bw, ":60F:%s\n", mt940Balance(s.Opening, s.Currency))
+
+	for _, e := range entries {
+		if e.status != statusBooked {
+			continue
+		}
+
+		booking, err := time.Parse("2006-01-02", transactionDate(e.transaction))
+		if err != nil {
+			return err
+		}
+
+		value := booking
+		if e.transaction.ValueDate != "" {
+			if value, err = time.Parse("2006-01-02", e.transaction.ValueDate); err != nil {
+				return err
+			}
+		}
+
+		mark := "C"
+		if e.amount.Sign() < 0 {
+			mark = "D"
+		}
+
+		bankRef := strings.ReplaceAll(transactionID(e.transaction), "-", "")
+		if len(bankRef) > 16 {
+			bankRef = bankRef[:16]
+		}
+		if bankRef != "" {
+			bankRef = "//" + swiftText(bankRef, 16)
+		}
+
+		_, _ = fmt.Fprintf(bw, ":61:%s%s%s%sNTRFNONREF%s\n",
+			value.Format("060102"),
+			booking.Format("0102"),
+			mark,
+			mt940Amount(new(big.Rat).Abs(e.amount)),
+			bankRef,
+		)
+
+		name, _ := counterparty(e.transaction, e.amount)
+		if info := strings.TrimSpace(strings.Join([]string{name, description(e.transaction)}, " ")); info != "" {
+			_, _ = fmt.Fprintf(bw, ":86:%s\n", wrapSwiftText(info, 65, 6))
+		}
+	}
+
+	_, _ = fmt.Fprintf(bw, ":62F:%s\n-\n", mt940Balance(s.Closing, s.Currency))
+
+	return bw.Flush()
+}
+
+func mt940Balance(b statementBalance, currency string) string {
+	mark := "C"
+	if b.Amount.Sign() < 0 {
+		mark = "D"
+	}
+
+	return mark + b.Date.Format("060102") + currency + mt940Amount(new(big.Rat).Abs(b.Amount))
+}
+
+// mt940Amount formats the amount with a decimal comma, e.g. "45,1"
+func mt940Amount(amount *big.Rat) string {
+	formatted := strings.TrimRight(amount.FloatString(2), "0")
+	return strings.Replace(formatted, ".", ",", 1)
+}
+
+// swiftText replaces the characters outside the SWIFT X character set and truncates to the length
+func swiftText(value string, length int) string {
+	b := strings.Builder{}
+	for _, r := range value {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-?:().,'+ ", r):
+			b.WriteRune(r)
+		default:
+			if folded, ok := swiftFolding[r]; ok {
+				b.WriteString(folded)
+			} else {
+				b.WriteRune('.')
+			}
+		}
+	}
+
+	text := b.String()
+	if len(text) > length {
+		text = text[:length]
+	}
+
+	return text
+}
+
+var swiftFolding = map[rune]string{
+	'ä': "ae", 'ö': "oe", 'ü': "ue", 'Ä': "Ae", 'Ö': "Oe", 'Ü': "Ue", 'ß': "ss",
+	'é': "e", 'è': "e", 'ê': "e", 'á': "a", 'à': "a", 'å': "a", 'ø': "o", 'ñ': "n", 'ç': "c",
+	'\n': " ", '\t': " ", '&': "+", '_': "-",
+}
+
+// wrapSwiftText splits the SWIFT text into lines of the width, at most the given number of lines
+func wrapSwiftText(value string, width, lines int) string {
+	text := swiftText(value, width*lines)
+	parts := make([]string, 0, lines)
+	for len(text) > width {
+		parts = append(parts, text[:width])
+		text = text[width:]
+	}
+	parts = append(parts, text)
+
+	// a line starting with ":" or "-" would be read as a new field or the end of the statement
+	for i := 1; i < len(parts); i++ {
+		if strings.HasPrefix(parts[i], ":") || strings.HasPrefix(parts[i], "-") {
+			parts[i] = "." + parts[i][1:]
+		}
+	}
+
+	return strings.Join(parts, "\n")
+}
+
+// transactionID returns the transaction ID or an empty string if the ASPSP doesn't provide it
+func transactionID(t *nordigen.TransactionResponse) string {
+	if t.ID == uuid.Nil {
+		return ""
+	}
+
+	return t.ID.String()
+}
+
+func singleLine(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func writeTestStatement(t *testing.T, format string, s *statement, opts *exportOptions) string {
+	if opts == nil {
+		opts = &exportOptions{sequence: 1, ledgerAccount: beancountAccount(s.InstitutionID)}
+	}
+
+	buf := &bytes.Buffer{}
+	if err := statementWriters[format](buf, s, opts); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	return buf.String()
+}
+
+func TestStatementWriters(t *testing.T) {
+	t.Run("csv", testWriteCSV)
+	t.Run("jsonl", testWriteJSONL)
+	t.Run("qif", testWriteQIF)
+	t.Run("beancount", testWriteBeancount)
+	t.Run("mt940", testWriteMT940)
+	t.Run("unknown balance", testWriteUnknownBalance)
+}
+
+func testWriteCSV(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+
+	// When/Act
+	records, err := csv.NewReader(strings.NewReader(writeTestStatement(t, "csv", s, nil))).ReadAll()
+	appended := writeTestStatement(t, "csv", s, &exportOptions{appending: true})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(records) != 4 || records[0][0] != "status" {
+		t.Fatalf("header and 3 transactions expected, got %v", records)
+	}
+
+	if strings.Join(records[1], ",") != "booked,2022-03-01,2022-03-01,-45.10,EUR,Grocery Store,,Groceries,"+s.Booked[0].ID.String()+"," {
+		t.Errorf("unexpected booked transaction %v", records[1])
+	}
+
+	if records[3][0] != "pending" || records[3][3] != "-9.99" || records[3][8] != "" {
+		t.Errorf("unexpected pending transaction %v", records[3])
+	}
+
+	if strings.HasPrefix(appended, "status") {
+		t.Error("header not expected when appending")
+	}
+}
+
+func testWriteJSONL(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+
+	// When/Act
+	lines := strings.Split(strings.TrimSpace(writeTestStatement(t, "jsonl", s, nil)), "\n")
+
+	// Then/Assert
+	if len(lines) != 3 {
+		t.Fatalf("a line per transaction expected, got %d", len(lines))
+	}
+
+	line := map[string]interface{}{}
+	if err := json.Unmarshal([]byte(lines[1]), &line); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if line["status"] != "booked" || line["account_id"] != s.AccountID.String() || line["remittanceInformationUnstructured"] != "Salary March" {
+		t.Errorf("transaction with the status and the account expected, got %v", line)
+	}
+}
+
+func testWriteQIF(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+
+	// When/Act
+	qif := writeTestStatement(t, "qif", s, nil)
+
+	// Then/Assert
+	if !strings.HasPrefix(qif, "!Type:Bank\nD03/01/2022\nT-45.10\nC*\n") {
+		t.Errorf("unexpected QIF\n%s", qif)
+	}
+
+	if strings.Count(qif, "^\n") != 3 || !strings.Contains(qif, "PEmployer GmbH\nMSalary March\n^") {
+		t.Errorf("3 records with payees expected\n%s", qif)
+	}
+}
+
+func testWriteBeancount(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.Booked[0].RemittanceInformationUnstructured = `Groceries "weekly"`
+
+	// When/Act
+	ledger := writeTestStatement(t, "beancount", s, nil)
+
+	// Then/Assert
+	expected := `2022-03-01 * "Grocery Store" "Groceries \"weekly\""` + "\n" +
+		`  transaction_id: "` + s.Booked[0].ID.String() + `"` + "\n" +
+		"  Assets:Bank:N26-NTSBDEB1  -45.10 EUR\n" +
+		"  Expenses:Uncategorized\n"
+	if !strings.HasPrefix(ledger, expected) {
+		t.Errorf("unexpected beancount transaction\n%s", ledger)
+	}
+
+	if !strings.Contains(ledger, "2022-03-30 ! \"Streaming Service\"") || !strings.Contains(ledger, "Income:Uncategorized") {
+		t.Errorf("pending transaction and income expected\n%s", ledger)
+	}
+}
+
+func testWriteMT940(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.Booked[1].RemittanceInformationUnstructured = "Salär März " + strings.Repeat("x", 80)
+
+	// When/Act
+	mt940 := writeTestStatement(t, "mt940", s, &exportOptions{sequence: 7})
+
+	// Then/Assert
+	lines := strings.Split(mt940, "\n")
+	expected := []string{
+		":20:20220401083000",
+		":25:DE89370400440532013000",
+		":28C:00007/001",
+		":60F:D220301EUR1220,34",
+		":61:2203010301D45,1NTRFNONREF//" + strings.ReplaceAll(s.Booked[0].ID.String(), "-", "")[:16],
+		":86:Grocery Store Groceries",
+	}
+	for i, line := range expected {
+		if lines[i] != line {
+			t.Errorf("line %d expected to be %s, got %s", i, line, lines[i])
+		}
+	}
+
+	if lines[7] != ":86:Employer GmbH Salaer Maerz "+strings.Repeat("x", 38) || lines[8] != strings.Repeat("x", 42) {
+		t.Errorf("transliterated and wrapped information expected, got\n%s\n%s", lines[7], lines[8])
+	}
+
+	if !strings.HasSuffix(mt940, ":62F:C220331EUR1234,56\n-\n") || strings.Contains(mt940, "Streaming") {
+		t.Errorf("closing balance and no pending transactions expected\n%s", mt940)
+	}
+}
+
+func testWriteUnknownBalance(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.BalanceKnown = false
+	opts := &exportOptions{sequence: 1, intuBID: "12345"}
+
+	for _, format := range []string{"mt940", "camt053", "ofx", "qfx"} {
+		buf := &bytes.Buffer{}
+
+		// When/Act
+		err := statementWriters[format](buf, s, opts)
+
+		// Then/Assert
+		if !errors.Is(err, errBalanceUnknown) {
+			t.Errorf("%s: errBalanceUnknown expected, got %v", format, err)
+		}
+
+		if buf.Len() != 0 {
+			t.Errorf("%s: nothing expected to be written, got\n%s", format, buf)
+		}
+	}
+
+	if out := writeTestStatement(t, "csv", s, nil); out == "" {
+		t.Error("the formats without balances expected to be written")
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+func TestExportCommand(t *testing.T) {
+	t.Run("stdout", testExportStdout)
+	t.Run("append", testExportAppend)
+	t.Run("append without booked", testExportAppendWithoutBooked)
+	t.Run("invalid", testExportInvalid)
+}
+
+func testExportStdout(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api).String()
+	srv := api.start(t)
+
+	// When/Act
+	all, _, allCode := runTest(t, srv, nil, "export", "-account", ID, "-from", "2022-03-01", "-to", "2022-03-31", "-status", "all")
+	usd, _, _ := runTest(t, srv, nil, "export", "-account", ID, "-from", "2022-03-01", "-to", "2022-03-31", "-status", "all", "-currency", "usd")
+	qif, _, _ := runTest(t, srv, nil, "export", "-account", ID, "-from", "2022-03-02", "-to", "2022-03-31", "-format", "qif")
+	german, _, _ := runTest(t, srv, nil, "export", "-account", ID, "-from", "2022-03-01", "-to", "2022-03-31",
+		"-csv-locale", "de", "-csv-debit-credit", "-csv-columns", "BookingDate:Buchungstag,Amount:Betrag,CreditorName:Empfänger")
+
+	// Then/Assert
+	if allCode != 0 || strings.Count(all, "\n") != 4 || !strings.Contains(all, "Groceries") || !strings.Contains(all, "pending") {
+		t.Errorf("csv with booked and pending transactions expected, got %d\n%s", allCode, all)
+	}
+
+	if strings.Count(usd, "\n") != 2 || !strings.Contains(usd, "Subscription") {
+		t.Errorf("only the transactions in the currency expected\n%s", usd)
+	}
+
+	if strings.Contains(qif, "Groceries") || !strings.Contains(qif, "Salary March") || strings.Contains(qif, "Subscription") {
+		t.Errorf("booked transactions of the period expected\n%s", qif)
+	}
+
+	expected := "Buchungstag;Betrag debit;Betrag credit;Empfänger\n01.03.2022;45,10;;Grocery Store\n25.03.2022;;2500,00;\n"
+	if german != expected {
+		t.Errorf("csv with the columns and the locale expected\n%s", german)
+	}
+}
+
+func testExportAppend(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api).String()
+	srv := api.start(t)
+	output := filepath.Join(t.TempDir(), "statement.csv")
+	args := []string{"export", "-account", ID, "-from", "2022-03-01", "-output", output, "-append"}
+
+	// When/Act
+	_, firstErr, firstCode := runTest(t, srv, nil, append(args, "-to", "2022-03-10")...)
+	_, secondErr, secondCode := runTest(t, srv, nil, append(args, "-to", "2022-03-31")...)
+	_, _, thirdCode := runTest(t, srv, nil, append(args, "-to", "2022-03-31")...)
+	_, _, otherFormat := runTest(t, srv, nil, append(args, "-format", "qif")...)
+
+	// Then/Assert
+	if firstCode != 0 || secondCode != 0 || thirdCode != 0 {
+		t.Fatalf("successful exports expected, got %d %d %d: %s %s", firstCode, secondCode, thirdCode, firstErr, secondErr)
+	}
+
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "status,") || !strings.Contains(lines[1], "Groceries") ||
+		!strings.Contains(lines[2], "Salary March") {
+		t.Errorf("a single header and each transaction once expected\n%s", data)
+	}
+
+	if !strings.Contains(secondErr, "Exported 1 booked") {
+		t.Errorf("only the new transaction expected to be exported, got %s", secondErr)
+	}
+
+	state := &exportState{}
+	stateData, _ := os.ReadFile(exportStatePath(output))
+	if err := json.Unmarshal(stateData, state); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if state.LastDate != "2022-03-25" || len(state.LastKeys) != 1 || state.Sequence != 3 {
+		t.Errorf("state after the last export expected, got %+v", state)
+	}
+
+	if otherFormat != 1 {
+		t.Errorf("error expected for appending in another format, got %d", otherFormat)
+	}
+}
+
+func testExportAppendWithoutBooked(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api).String()
+	srv := api.start(t)
+	output := filepath.Join(t.TempDir(), "statement.csv")
+	args := []string{"export", "-account", ID, "-output", output, "-append"}
+
+	// When/Act
+	_, firstErr, firstCode := runTest(t, srv, nil, append(args, "-from", "2022-02-01", "-to", "2022-02-28")...)
+	_, secondErr, secondCode := runTest(t, srv, nil, append(args, "-from", "2022-03-01", "-to", "2022-03-31")...)
+
+	// Then/Assert
+	if firstCode != 0 || secondCode != 0 {
+		t.Fatalf("successful exports expected, got %d %d: %s %s", firstCode, secondCode, firstErr, secondErr)
+	}
+
+	if !strings.Contains(firstErr, "Exported 0 booked") || !strings.Contains(secondErr, "Exported 2 booked") {
+		t.Errorf("the booked transactions of the second period expected, got %s %s", firstErr, secondErr)
+	}
+
+	state := &exportState{}
+	stateData, _ := os.ReadFile(exportStatePath(output))
+	if err := json.Unmarshal(stateData, state); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if state.LastDate != "2022-03-25" || state.Sequence != 2 {
+		t.Errorf("state after the second export expected, got %+v", state)
+	}
+}
+
+func testExportInvalid(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api).String()
+	srv := api.start(t)
+	existing := filepath.Join(t.TempDir(), "existing.csv")
+	if err := os.WriteFile(existing, []byte("data\n"), 0o600); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	cases := map[string][]string{
+		"missing account":     {"export"},
+		"unknown format":      {"export", "-account", ID, "-format", "xls"},
+		"pending in mt940":    {"export", "-account", ID, "-format", "mt940", "-status", "all"},
+		"appending to stdout": {"export", "-account", ID, "-append"},
+		"unknown csv locale":  {"export", "-account", ID, "-csv-locale", "xx"},
+		"unknown csv column":  {"export", "-account", ID, "-csv-columns", "Unknown"},
+		"appending to ofx":    {"export", "-account", ID, "-format", "ofx", "-append", "-output", existing},
+		"qfx without bid":     {"export", "-account", ID, "-format", "qfx"},
+		"mt940 of the past":   {"export", "-account", ID, "-format", "mt940", "-to", "2022-03-31"},
+		"file without state":  {"export", "-account", ID, "-append", "-output", existing},
+	}
+
+	for name, args := range cases {
+		// When/Act
+		_, _, code := runTest(t, srv, nil, args...)
+
+		// Then/Assert
+		if code == 0 {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+
+	if data, _ := os.ReadFile(existing); string(data) != "data\n" {
+		t.Errorf("existing file expected to be untouched, got %s", data)
+	}
+}
+
+func Test_skipExported(t *testing.T) {
+	// What/Arrange
+	transfer := nordigen.TransactionResponse{
+		Amount:                            nordigen.Amount{Amount: "-5.00", Currency: "EUR"},
+		BookingDate:                       "2022-03-10",
+		RemittanceInformationUnstructured: "Coffee",
+	}
+	earlier := transfer
+	earlier.BookingDate = "2022-03-09"
+	state := &exportState{LastDate: "2022-03-10", LastKeys: []string{transfer.Key()}}
+
+	// When/Act
+	kept := skipExported([]nordigen.TransactionResponse{earlier, transfer, transfer}, state)
+
+	// Then/Assert
+	if len(kept) != 1 || kept[0].BookingDate != "2022-03-10" {
+		t.Fatalf("only the identical transaction not exported yet expected to be kept, got %+v", kept)
+	}
+
+	next := nextExportState(state, uuid.New(), "csv", kept)
+	if len(next.LastKeys) != 2 {
+		t.Errorf("a key per exported transaction expected, got %v", next.LastKeys)
+	}
+}
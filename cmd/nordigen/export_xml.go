@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/xml"
+	"io"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"gromson/nordigen"
+	exportofx "gromson/nordigen/export/ofx"
+)
+
+const camt053Namespace = "urn:iso:std:iso:20022:tech:xsd:camt.053.001.02"
+
+// camt053Document the ISO 20022 bank to customer statement, the elements in the schema's order
+type camt053Document struct {
+	XMLName   xml.Name `xml:"Document"`
+	Namespace string   `xml:"xmlns,attr"`
+	Statement struct {
+		GroupHeader struct {
+			MessageID string `xml:"MsgId"`
+			Created   string `xml:"CreDtTm"`
+		} `xml:"GrpHdr"`
+		Stmt camt053Statement `xml:"Stmt"`
+	} `xml:"BkToCstmrStmt"`
+}
+
+type camt053Statement struct {
+	ID             string `xml:"Id"`
+	SequenceNumber int    `xml:"ElctrncSeqNb,omitempty"`
+	Created        string `xml:"CreDtTm"`
+	Period         struct {
+		From string `xml:"FrDtTm"`
+		To   string `xml:"ToDtTm"`
+	} `xml:"FrToDt"`
+	Account  camt053Account   `xml:"Acct"`
+	Balances []camt053Balance `xml:"Bal"`
+	Entries  []camt053Entry   `xml:"Ntry"`
+}
+
+type camt053Account struct {
+	ID       camt053AccountID `xml:"Id"`
+	Currency string           `xml:"Ccy,omitempty"`
+	Owner    *camt053Party    `xml:"Ownr,omitempty"`
+}
+
+type camt053AccountID struct {
+	Iban  string `xml:"IBAN,omitempty"`
+	Other *struct {
+		ID string `xml:"Id"`
+	} `xml:"Othr,omitempty"`
+}
+
+type camt053Party struct {
+	Name string `xml:"Nm"`
+}
+
+type camt053Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type camt053Date struct {
+	Date string `xml:"Dt"`
+}
+
+type camt053Balance struct {
+	Code              string        `xml:"Tp>CdOrPrtry>Cd"`
+	Amount            camt053Amount `xml:"Amt"`
+	CreditDebitMarker string        `xml:"CdtDbtInd"`
+	Date              camt053Date   `xml:"Dt"`
+}
+
+type camt053Entry struct {
+	Amount              camt053Amount `xml:"Amt"`
+	CreditDebitMarker   string        `xml:"CdtDbtInd"`
+	Status              string        `xml:"Sts"`
+	BookingDate         *camt053Date  `xml:"BookgDt,omitempty"`
+	ValueDate           *camt053Date  `xml:"ValDt,omitempty"`
+	ServicerReference   string        `xml:"AcctSvcrRef,omitempty"`
+	BankTransactionCode struct {
+		Code string `xml:"Prtry>Cd"`
+	} `xml:"BkTxCd"`
+	Details camt053TransactionDetails `xml:"NtryDtls>TxDtls"`
+}
+
+type camt053TransactionDetails struct {
+	RelatedParties *camt053RelatedParties `xml:"RltdPties,omitempty"`
+	Remittance     *struct {
+		Unstructured []string `xml:"Ustrd"`
+	} `xml:"RmtInf,omitempty"`
+}
+
+type camt053RelatedParties struct {
+	Debtor          *camt053Party     `xml:"Dbtr,omitempty"`
+	DebtorAccount   *camt053AccountID `xml:"DbtrAcct>Id,omitempty"`
+	Creditor        *camt053Party     `xml:"Cdtr,omitempty"`
+	CreditorAccount *camt053AccountID `xml:"CdtrAcct>Id,omitempty"`
+}
+
+func writeCamt053(w io.Writer, s *statement, opts *exportOptions) error {
+	if !s.BalanceKnown {
+		return errors.Wrap(errBalanceUnknown, "camt053")
+	}
+
+	entries, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	doc := &camt053Document{Namespace: camt053Namespace}
+	created := s.Created.Format(time.RFC3339)
+	doc.Statement.GroupHeader.MessageID = "NORDIGEN-" + s.Created.Format("20060102150405")
+	doc.Statement.GroupHeader.Created = created
+
+	stmt := &doc.Statement.Stmt
+	stmt.ID = s.AccountID.String()[:8] + "-" + s.Created.Format("20060102150405")
+	stmt.SequenceNumber = opts.sequence
+	stmt.Created = created
+	stmt.Period.From = s.From.Format("2006-01-02") + "T00:00:00"
+	stmt.Period.To = s.To.Format("2006-01-02") + "T23:59:59"
+	stmt.Account = camt053Account{ID: *camt053Identifier(s.Iban, s.AccountID.String()), Currency: s.Currency}
+	if s.OwnerName != "" {
+		stmt.Account.Owner = &camt053Party{Name: s.OwnerName}
+	}
+	stmt.Balances = []camt053Balance{
+		newCamt053Balance("OPBD", s.Opening, s.Currency, s.Decimals),
+		newCamt053Balance("CLBD", s.Closing, s.Currency, s.Decimals),
+	}
+
+	for _, e := range entries {
+		stmt.Entries = append(stmt.Entries, newCamt053Entry(e))
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
+
+func newCamt053Balance(code string, b statementBalance, currency string, decimals int) camt053Balance {
+	return camt053Balance{
+		Code:              code,
+		Amount:            camt053Amount{Currency: currency, Value: new(big.Rat).Abs(b.Amount).FloatString(decimals)},
+		CreditDebitMarker: creditDebitMarker(b.Amount),
+		Date:              camt053Date{Date: b.Date.Format("2006-01-02")},
+	}
+}
+
+func newCamt053Entry(e statementEntry) camt053Entry {
+	t := e.transaction
+	entry := camt053Entry{
+		Amount: camt053Amount{
+			Currency: t.Amount.Currency,
+			Value:    formatAmount(new(big.Rat).Abs(e.amount), t.Amount.Amount, "."),
+		},
+		CreditDebitMarker: creditDebitMarker(e.amount),
+		Status:            "BOOK",
+		ServicerReference: transactionID(t),
+	}
+	if e.status == statusPending {
+		entry.Status = "PDNG"
+	}
+	if t.BookingDate != "" {
+		entry.BookingDate = &camt053Date{Date: t.BookingDate}
+	}
+	if t.ValueDate != "" {
+		entry.ValueDate = &camt053Date{Date: t.ValueDate}
+	}
+
+	// the bank transaction code is mandatory, NOTPROVIDED if the ASPSP doesn't report it
+	entry.BankTransactionCode.Code = firstNonEmpty(t.BankTransactionCode, "NOTPROVIDED")
+
+	parties := &camt053RelatedParties{}
+	if t.DebtorName != "" {
+		parties.Debtor = &camt053Party{Name: t.DebtorName}
+	}
+	if ID := accountIdentifier(t.DebtorAccount); ID != "" {
+		parties.DebtorAccount = camt053Identifier(t.DebtorAccount.Iban, ID)
+	}
+	if t.CreditorName != "" {
+		parties.Creditor = &camt053Party{Name: t.CreditorName}
+	}
+	if ID := accountIdentifier(t.CreditorAccount); ID != "" {
+		parties.CreditorAccount = camt053Identifier(t.CreditorAccount.Iban, ID)
+	}
+	if *parties != (camt053RelatedParties{}) {
+		entry.Details.RelatedParties = parties
+	}
+
+	if info := description(t); info != "" {
+		entry.Details.Remittance = &struct {
+			Unstructured []string `xml:"Ustrd"`
+		}{Unstructured: splitText(info, 140)}
+	}
+
+	return entry
+}
+
+// camt053Identifier identifies the account by the IBAN or the other identifier
+func camt053Identifier(iban, other string) *camt053AccountID {
+	if iban != "" {
+		return &camt053AccountID{Iban: iban}
+	}
+
+	return &camt053AccountID{Other: &struct {
+		ID string `xml:"Id"`
+	}{ID: other}}
+}
+
+func creditDebitMarker(amount *big.Rat) string {
+	if amount.Sign() < 0 {
+		return "DBIT"
+	}
+
+	return "CRDT"
+}
+
+// splitText splits the text into parts of at most the given number of runes
+func splitText(text string, size int) []string {
+	runes := []rune(text)
+	parts := make([]string, 0, len(runes)/size+1)
+	for len(runes) > size {
+		parts = append(parts, string(runes[:size]))
+		runes = runes[size:]
+	}
+
+	return append(parts, string(runes))
+}
+
+func writeOFX(w io.Writer, s *statement, _ *exportOptions) error {
+	if !s.BalanceKnown {
+		return errors.Wrap(errBalanceUnknown, "ofx")
+	}
+
+	return exportofx.Write(w, s.ofxStatement(), &exportofx.Options{Created: s.Created})
+}
+
+func writeQFX(w io.Writer, s *statement, opts *exportOptions) error {
+	if !s.BalanceKnown {
+		return errors.Wrap(errBalanceUnknown, "qfx")
+	}
+
+	return exportofx.Write(w, s.ofxStatement(), &exportofx.Options{
+		QFX:     true,
+		IntuBID: opts.intuBID,
+		Created: s.Created,
+	})
+}
+
+// ofxStatement returns the statement of the ofx package. The ledger balance is the closing balance
+// of the statement, the available ones are the reported balances
+func (s *statement) ofxStatement() *exportofx.Statement {
+	balances := []nordigen.BalanceResponse{{
+		BalanceAmount: nordigen.Amount{Amount: s.Closing.Amount.FloatString(s.Decimals), Currency: s.Currency},
+		BalanceType:   "closingBooked",
+		ReferenceDate: s.Closing.Date.Format("2006-01-02"),
+	}}
+	for _, b := range s.Balances {
+		if strings.HasSuffix(b.BalanceType, "Available") {
+			balances = append(balances, b)
+		}
+	}
+
+	details := &nordigen.AccountDetailsResponse{}
+	details.Account.ResourceID = s.AccountID
+	details.Account.Iban = s.Iban
+	details.Account.Currency = s.Currency
+
+	return &exportofx.Statement{
+		Details:      details,
+		Balances:     &nordigen.BalanceCollectionResponse{Balances: balances},
+		Transactions: s.Booked,
+		From:         s.From,
+		To:           s.To,
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"gromson/nordigen"
+)
+
+func TestWriteCamt053(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.Booked[0].CreditorAccount = &nordigen.AccountReference{Bban: "532013000"}
+
+	// When/Act
+	camt := writeTestStatement(t, "camt053", s, nil)
+
+	// Then/Assert
+	doc := &camt053Document{}
+	if err := xml.Unmarshal([]byte(camt), doc); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	stmt := doc.Statement.Stmt
+	if stmt.Account.ID.Iban != "DE89370400440532013000" || stmt.Account.Currency != "EUR" || stmt.Account.Owner.Name != "Jane Doe" {
+		t.Errorf("account expected, got %+v", stmt.Account)
+	}
+
+	if len(stmt.Balances) != 2 || stmt.Balances[0].Code != "OPBD" || stmt.Balances[0].Amount.Value != "1220.34" ||
+		stmt.Balances[0].CreditDebitMarker != "DBIT" || stmt.Balances[1].Amount.Value != "1234.56" {
+		t.Errorf("opening and closing balances expected, got %+v", stmt.Balances)
+	}
+
+	if len(stmt.Entries) != 3 {
+		t.Fatalf("3 entries expected, got %d", len(stmt.Entries))
+	}
+
+	groceries := stmt.Entries[0]
+	if groceries.Amount.Value != "45.10" || groceries.CreditDebitMarker != "DBIT" || groceries.Status != "BOOK" ||
+		groceries.BankTransactionCode.Code != "NOTPROVIDED" || groceries.Details.RelatedParties.Creditor.Name != "Grocery Store" ||
+		groceries.Details.RelatedParties.CreditorAccount.Other.ID != "532013000" {
+		t.Errorf("unexpected entry %+v", groceries)
+	}
+
+	if stmt.Entries[2].Status != "PDNG" || stmt.Entries[2].BookingDate != nil {
+		t.Errorf("pending entry expected, got %+v", stmt.Entries[2])
+	}
+
+	if !strings.Contains(camt, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">`) ||
+		strings.Contains(camt, "<DbtrAcct>") {
+		t.Errorf("namespace expected without empty elements\n%s", camt)
+	}
+}
+
+// ofxTestDocument the parts of the OFX document checked by the tests
+type ofxTestDocument struct {
+	IntuBID   string `xml:"SIGNONMSGSRSV1>SONRS>INTU.BID"`
+	Statement struct {
+		Currency string `xml:"CURDEF"`
+		Account  struct {
+			BankID    string `xml:"BANKID"`
+			AccountID string `xml:"ACCTID"`
+		} `xml:"BANKACCTFROM"`
+		Transactions []struct {
+			Type   string `xml:"TRNTYPE"`
+			Amount string `xml:"TRNAMT"`
+			ID     string `xml:"FITID"`
+			Name   string `xml:"NAME"`
+		} `xml:"BANKTRANLIST>STMTTRN"`
+		Ledger    string `xml:"LEDGERBAL>BALAMT"`
+		Available string `xml:"AVAILBAL>BALAMT"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS>STMTRS"`
+}
+
+func TestWriteOFX(t *testing.T) {
+	t.Run("ofx", testWriteOFX)
+	t.Run("qfx", testWriteQFX)
+}
+
+func testWriteOFX(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.Pending = nil
+	s.Booked[0].ID = [16]byte{}
+	s.Booked[0].CreditorName = "Fish & Chips <Ltd>"
+
+	// When/Act
+	ofx := writeTestStatement(t, "ofx", s, nil)
+	again := writeTestStatement(t, "ofx", s, nil)
+
+	// Then/Assert
+	if !strings.Contains(ofx, `<?OFX OFXHEADER="200" VERSION="220"`) || strings.Contains(ofx, "<INTU.BID>") {
+		t.Errorf("OFX header expected\n%s", ofx)
+	}
+
+	doc := &ofxTestDocument{}
+	if err := xml.Unmarshal([]byte(ofx[strings.Index(ofx, "<OFX>"):]), doc); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	stmt := doc.Statement
+	if stmt.Currency != "EUR" || stmt.Account.AccountID != "DE89370400440532013000" || stmt.Account.BankID != "37040044" ||
+		stmt.Ledger != "1234.56" || stmt.Available != "1200.00" {
+		t.Errorf("unexpected statement %+v", stmt)
+	}
+
+	transactions := stmt.Transactions
+	if len(transactions) != 2 || transactions[0].Type != "DEBIT" || transactions[0].Amount != "-45.10" ||
+		transactions[0].Name != "Fish & Chips <Ltd>" || transactions[1].ID != s.Booked[1].ID.String() {
+		t.Errorf("unexpected transactions %+v", transactions)
+	}
+
+	if len(transactions[0].ID) != 32 || ofx != again {
+		t.Errorf("stable FITID expected for the transaction without ID, got %s", transactions[0].ID)
+	}
+}
+
+func testWriteQFX(t *testing.T) {
+	// What/Arrange
+	s := testStatement(t)
+	s.Pending = nil
+
+	// When/Act
+	qfx := writeTestStatement(t, "qfx", s, &exportOptions{intuBID: "3000"})
+
+	// Then/Assert
+	doc := &ofxTestDocument{}
+	if err := xml.Unmarshal([]byte(qfx[strings.Index(qfx, "<OFX>"):]), doc); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if doc.IntuBID != "3000" || len(doc.Statement.Transactions) != 2 {
+		t.Errorf("QFX with the Intuit bank ID expected\n%s", qfx)
+	}
+}
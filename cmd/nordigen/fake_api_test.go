@@ -0,0 +1,312 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+// fakeApi in-memory imitation of the API used by the commands
+type fakeApi struct {
+	mu           sync.Mutex
+	institutions []nordigen.InstitutionResponse
+	agreements   map[uuid.UUID]*nordigen.EndUserAgreementResponse
+	requisitions map[uuid.UUID]*nordigen.RequisitionResponse
+	accounts     map[uuid.UUID]*fakeAccount
+	// requests the "METHOD /path" of the received requests, except the token ones
+	requests []string
+}
+
+type fakeAccount struct {
+	// rateLimited rejects the requests of the details, balances and transactions with 429
+	rateLimited  bool
+	account      nordigen.AccountResponse
+	details      nordigen.AccountDetailsResponse
+	balances     nordigen.BalanceCollectionResponse
+	transactions nordigen.TransactionCollectionResponse
+}
+
+func newFakeApi() *fakeApi {
+	return &fakeApi{
+		agreements:   make(map[uuid.UUID]*nordigen.EndUserAgreementResponse),
+		requisitions: make(map[uuid.UUID]*nordigen.RequisitionResponse),
+		accounts:     make(map[uuid.UUID]*fakeAccount),
+	}
+}
+
+func (a *fakeApi) start(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(a.serveHTTP))
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func (a *fakeApi) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if path[0] == "token" {
+		writeFake(w, http.StatusOK, map[string]interface{}{
+			"access": "access-token", "access_expires": 86400, "refresh": "refresh-token", "refresh_expires": 2592000,
+		})
+		return
+	}
+	a.requests = append(a.requests, r.Method+" "+r.URL.Path)
+
+	switch {
+	case path[0] == "institutions":
+		a.serveInstitutions(w, r, path[1:])
+	case path[0] == "agreements" && len(path) > 1:
+		a.serveAgreements(w, r, path[2:])
+	case path[0] == "requisitions":
+		a.serveRequisitions(w, r, path[1:])
+	case path[0] == "accounts" && len(path) > 1:
+		a.serveAccounts(w, r, path[1:])
+	default:
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+	}
+}
+
+func (a *fakeApi) serveInstitutions(w http.ResponseWriter, r *http.Request, path []string) {
+	country := r.URL.Query().Get("country")
+	list := make([]nordigen.InstitutionResponse, 0)
+	for _, institution := range a.institutions {
+		if len(path) > 0 && institution.ID == path[0] {
+			writeFake(w, http.StatusOK, institution)
+			return
+		}
+
+		for _, c := range institution.Countries {
+			if country == "" || c == country {
+				list = append(list, institution)
+				break
+			}
+		}
+	}
+
+	if len(path) > 0 {
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	writeFake(w, http.StatusOK, list)
+}
+
+func (a *fakeApi) serveAgreements(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		if r.Method == http.MethodPost {
+			req := &nordigen.CreateAgreementRequest{}
+			_ = json.NewDecoder(r.Body).Decode(req)
+			agreement := &nordigen.EndUserAgreementResponse{
+				ID:                 uuid.New(),
+				Created:            time.Now().UTC(),
+				MaxHistoricalDays:  req.MaxHistoricalDays,
+				AccessValidForDays: req.AccessValidForDays,
+				AccessScopes:       req.AccessScope,
+				InstitutionID:      req.InstitutionID,
+			}
+			a.agreements[agreement.ID] = agreement
+			writeFake(w, http.StatusOK, agreement)
+			return
+		}
+
+		list := make([]interface{}, 0, len(a.agreements))
+		for _, agreement := range a.agreements {
+			list = append(list, agreement)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].(*nordigen.EndUserAgreementResponse).InstitutionID < list[j].(*nordigen.EndUserAgreementResponse).InstitutionID
+		})
+		writeFakePage(w, r, list)
+		return
+	}
+
+	ID, _ := uuid.Parse(path[0])
+	agreement, ok := a.agreements[ID]
+	if !ok {
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		delete(a.agreements, ID)
+		writeFake(w, http.StatusOK, map[string]string{"summary": "deleted"})
+		return
+	}
+
+	writeFake(w, http.StatusOK, agreement)
+}
+
+func (a *fakeApi) serveRequisitions(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		if r.Method == http.MethodPost {
+			req := &nordigen.CreateRequisitionRequest{}
+			_ = json.NewDecoder(r.Body).Decode(req)
+			requisition := &nordigen.RequisitionResponse{
+				ID:            uuid.New(),
+				Created:       time.Now().UTC(),
+				RedirectUrl:   req.Redirect,
+				Status:        "CR",
+				InstitutionID: req.InstitutionID,
+				AgreementID:   req.Agreement,
+				Reference:     req.Reference,
+				Accounts:      []uuid.UUID{},
+				UserLanguage:  req.UserLanguage,
+			}
+			requisition.Link = "https://ob.nordigen.com/psd2/start/" + requisition.ID.String() + "/" + req.InstitutionID
+			a.requisitions[requisition.ID] = requisition
+			writeFake(w, http.StatusOK, requisition)
+			return
+		}
+
+		list := make([]interface{}, 0, len(a.requisitions))
+		for _, requisition := range a.requisitions {
+			list = append(list, requisition)
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].(*nordigen.RequisitionResponse).Reference < list[j].(*nordigen.RequisitionResponse).Reference
+		})
+		writeFakePage(w, r, list)
+		return
+	}
+
+	ID, _ := uuid.Parse(path[0])
+	requisition, ok := a.requisitions[ID]
+	if !ok {
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		delete(a.requisitions, ID)
+		writeFake(w, http.StatusOK, map[string]string{"summary": "deleted"})
+		return
+	}
+
+	writeFake(w, http.StatusOK, requisition)
+}
+
+func (a *fakeApi) serveAccounts(w http.ResponseWriter, r *http.Request, path []string) {
+	ID, _ := uuid.Parse(path[0])
+	account, ok := a.accounts[ID]
+	if !ok {
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	if len(path) == 1 {
+		writeFake(w, http.StatusOK, account.account)
+		return
+	}
+
+	if account.rateLimited {
+		writeFake(w, http.StatusTooManyRequests, map[string]string{"summary": "Rate limit exceeded"})
+		return
+	}
+
+	switch path[1] {
+	case "details":
+		writeFake(w, http.StatusOK, account.details)
+	case "balances":
+		writeFake(w, http.StatusOK, account.balances)
+	case "transactions":
+		writeFake(w, http.StatusOK, filterFakeTransactions(account.transactions, r.URL.Query().Get("date_from"), r.URL.Query().Get("date_to")))
+	default:
+		writeFake(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+	}
+}
+
+// filterFakeTransactions keeps the transactions booked, or valued if not booked, within the dates
+func filterFakeTransactions(all nordigen.TransactionCollectionResponse, from, to string) nordigen.TransactionCollectionResponse {
+	keep := func(transactions []nordigen.TransactionResponse) []nordigen.TransactionResponse {
+		kept := make([]nordigen.TransactionResponse, 0, len(transactions))
+		for _, transaction := range transactions {
+			date := transaction.BookingDate
+			if date == "" {
+				date = transaction.ValueDate
+			}
+			if (from == "" || date >= from) && (to == "" || date <= to) {
+				kept = append(kept, transaction)
+			}
+		}
+		return kept
+	}
+
+	result := nordigen.TransactionCollectionResponse{}
+	result.Transactions.Booked = keep(all.Transactions.Booked)
+	result.Transactions.Pending = keep(all.Transactions.Pending)
+
+	return result
+}
+
+func writeFakePage(w http.ResponseWriter, r *http.Request, list []interface{}) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	end := offset + limit
+	if end > len(list) || limit == 0 {
+		end = len(list)
+	}
+	if offset > end {
+		offset = end
+	}
+
+	writeFake(w, http.StatusOK, map[string]interface{}{"count": len(list), "results": list[offset:end]})
+}
+
+func writeFake(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
+
+// runTest runs the command line against the server with the credentials in the environment
+// and an empty config file
+func runTest(t *testing.T, srv *httptest.Server, env map[string]string, args ...string) (string, string, int) {
+	return runTestContext(context.Background(), t, srv, env, args...)
+}
+
+// runTestContext runs the command line like runTest until the context is done
+func runTestContext(ctx context.Context, t *testing.T, srv *httptest.Server, env map[string]string, args ...string) (string, string, int) {
+	configPath := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configPath, []byte("{}"), 0o600); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	getenv := func(key string) string {
+		if value, ok := env[key]; ok {
+			return value
+		}
+
+		switch key {
+		case envSecretID:
+			return "c2256760-abc0-49a2-968d-b4cb4cf715d0"
+		case envSecretKey:
+			return "88812918b15a"
+		case envBaseUrl:
+			return srv.URL
+		case envConfigPath:
+			return configPath
+		}
+
+		return ""
+	}
+
+	code := run(ctx, args, getenv, stdout, stderr)
+
+	return stdout.String(), stderr.String(), code
+}
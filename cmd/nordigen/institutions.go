@@ -0,0 +1,92 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"gromson/nordigen"
+)
+
+func init() {
+	register(&command{
+		name:        "institutions",
+		description: "list and get the institutions",
+		subcommands: []subcommand{
+			{name: "list", usage: "[-country DE] [-payments enabled|disabled]", run: institutionsList},
+			{name: "get", usage: "<institution ID>", run: institutionsGet},
+		},
+	})
+}
+
+func institutionsList(a *app, args []string) error {
+	fs := a.newFlagSet("institutions list", "[-country DE] [-payments enabled|disabled]")
+	country := fs.String("country", "", "ISO 3166 two-character country code, all countries if empty")
+	payments := fs.String("payments", "", "filter by payments support: enabled or disabled")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	var institutions []nordigen.InstitutionResponse
+	switch *payments {
+	case "":
+		institutions, err = n.Institution().List(strings.ToUpper(*country))
+	case "enabled":
+		institutions, err = n.Institution().ListWithEnabledPayments(strings.ToUpper(*country))
+	case "disabled":
+		institutions, err = n.Institution().ListWithDisabledPayments(strings.ToUpper(*country))
+	default:
+		fs.Usage()
+		return errUsage
+	}
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(institutions, institutionsTable(institutions...))
+}
+
+func institutionsGet(a *app, args []string) error {
+	fs := a.newFlagSet("institutions get", "<institution ID>")
+	values, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	institution, err := n.Institution().Get(values[0])
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(institution, institutionsTable(*institution))
+}
+
+func institutionsTable(institutions ...nordigen.InstitutionResponse) *table {
+	t := &table{header: []string{"ID", "NAME", "BIC", "COUNTRIES", "HISTORY_DAYS", "MAX_ACCESS_DAYS"}}
+	for _, institution := range institutions {
+		maxAccess := ""
+		if institution.MaxAccessValidForDays > 0 {
+			maxAccess = strconv.Itoa(institution.MaxAccessValidForDays)
+		}
+
+		t.add(
+			institution.ID,
+			institution.Name,
+			institution.BIC,
+			strings.Join(institution.Countries, ","),
+			strconv.Itoa(institution.TransactionTotalDays),
+			maxAccess,
+		)
+	}
+
+	return t
+}
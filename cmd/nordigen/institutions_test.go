@@ -0,0 +1,38 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"gromson/nordigen"
+)
+
+func TestInstitutionsCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	api.institutions = []nordigen.InstitutionResponse{
+		{ID: "N26_NTSBDEB1", Name: "N26 Bank", BIC: "NTSBDEB1XXX", TransactionTotalDays: 730, Countries: []string{"DE"}},
+		{ID: "REVOLUT_REVOGB21", Name: "Revolut", BIC: "REVOGB21", TransactionTotalDays: 730, Countries: []string{"GB"}},
+	}
+	srv := api.start(t)
+
+	// When/Act
+	list, _, listCode := runTest(t, srv, nil, "institutions", "list", "-country", "de")
+	get, _, getCode := runTest(t, srv, nil, "-format", "json", "institutions", "get", "REVOLUT_REVOGB21")
+	_, missingErr, missingCode := runTest(t, srv, nil, "institutions", "get", "MISSING")
+
+	// Then/Assert
+	if listCode != 0 || !strings.Contains(list, "N26_NTSBDEB1") || strings.Contains(list, "REVOLUT") {
+		t.Errorf("institutions of the country expected, got %d %s", listCode, list)
+	}
+
+	institution := &nordigen.InstitutionResponse{}
+	if err := json.Unmarshal([]byte(get), institution); err != nil || getCode != 0 || institution.Name != "Revolut" {
+		t.Errorf("institution expected as JSON, got %d %s", getCode, get)
+	}
+
+	if missingCode != 1 || !strings.Contains(missingErr, "error:") {
+		t.Errorf("API error expected to be reported, got %d %s", missingCode, missingErr)
+	}
+}
@@ -0,0 +1,232 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+const linkCallbackPath = "/callback"
+
+// openBrowser opens the URL in the default browser, replaced in tests
+var openBrowser = func(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+
+	return cmd.Start()
+}
+
+func init() {
+	register(&command{
+		name:        "link",
+		description: "link a bank interactively and print the accounts with balances",
+		run:         link,
+	})
+}
+
+// linkCallback the redirect back from the institution
+type linkCallback struct {
+	err error
+}
+
+func link(a *app, args []string) error {
+	fs := a.newFlagSet("link", "-institution ID [flags]")
+	institution := fs.String("institution", "", "institution ID, required")
+	maxHistoricalDays := fs.Int("max-historical-days", 0, "length of the transaction history in days, the API default if zero")
+	accessValidForDays := fs.Int("access-valid-for-days", 0, "length of the access in days, the API default if zero")
+	scope := fs.String("scope", "", "comma-separated access scopes, the API default if empty")
+	language := fs.String("language", "", "two-letter ISO 639-1 language of the authorization screens")
+	accountSelection := fs.Bool("account-selection", false, "let the end user select the accounts")
+	port := fs.Int("port", 0, "port of the localhost redirect listener, a free port if zero")
+	timeout := fs.Duration("timeout", 10*time.Minute, "time to complete the authorization")
+	noBrowser := fs.Bool("no-browser", false, "only print the link")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *institution == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	scopes, err := parseAccessScopes(*scope)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:"+strconv.Itoa(*port))
+	if err != nil {
+		return errors.Wrap(err, "error starting redirect listener")
+	}
+
+	params := &nordigen.LinkFlowParams{
+		ID:                 uuid.NewString(),
+		InstitutionID:      *institution,
+		Redirect:           "http://" + listener.Addr().String() + linkCallbackPath,
+		UserLanguage:       *language,
+		MaxHistoricalDays:  *maxHistoricalDays,
+		AccessValidForDays: *accessValidForDays,
+		AccessScope:        scopes,
+		AccountSelection:   *accountSelection,
+	}
+
+	callbacks := make(chan linkCallback, 1)
+	srv := &http.Server{Handler: linkCallbackHandler(params.ID, callbacks), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(listener) }()
+	defer func() { _ = srv.Close() }()
+
+	ctx, cancel := context.WithTimeout(a.ctx, *timeout)
+	defer cancel()
+
+	store := nordigen.NewMemoryLinkFlowStore()
+	flow, err := n.NewLinkFlow(ctx, store, params)
+	if err != nil {
+		return err
+	}
+
+	linkUrl, err := flow.Start(ctx)
+	if err != nil {
+		_ = flow.Abandon(context.Background())
+		return err
+	}
+
+	_, _ = fmt.Fprintf(a.stderr, "Open the link to authorize the access:\n\n  %s\n\nWaiting for the redirect to %s\n", linkUrl, params.Redirect)
+	if !*noBrowser {
+		if err := openBrowser(linkUrl); err != nil {
+			_, _ = fmt.Fprintln(a.stderr, "The browser can't be opened, open the link manually")
+		}
+	}
+
+	select {
+	case callback := <-callbacks:
+		if callback.err != nil {
+			// the end user won't authorize the requisition anymore, so it's not left behind
+			_ = flow.Abandon(context.Background())
+			return callback.err
+		}
+	case <-ctx.Done():
+		// the requisition is useless without the end user, so it's not left behind
+		_ = flow.Abandon(context.Background())
+		return errors.Wrap(ctx.Err(), "the authorization hasn't been completed")
+	}
+
+	state, err := flow.Wait(ctx, &nordigen.WaitOptions{Interval: time.Second, MaxInterval: 5 * time.Second, Timeout: time.Minute})
+	if err != nil {
+		return err
+	}
+
+	record := flow.Record()
+	if state != nordigen.LinkStateLinked {
+		return errors.Errorf("the requisition %s hasn't been linked, status %s", record.RequisitionID, record.RequisitionStatus)
+	}
+
+	_, _ = fmt.Fprintf(a.stderr, "Linked requisition %s\n\n", record.RequisitionID)
+
+	return printLinkedAccounts(a, n, record.AccountIDs, grantsBalances(scopes))
+}
+
+// grantsBalances reports whether the access scopes cover the balances, the API grants all the scopes if empty
+func grantsBalances(scopes []string) bool {
+	for _, scope := range scopes {
+		if scope == nordigen.AccessScopeBalances {
+			return true
+		}
+	}
+
+	return len(scopes) == 0
+}
+
+// parseAccessScopes splits the comma-separated access scopes and rejects the ones unknown to the API
+func parseAccessScopes(value string) ([]string, error) {
+	scopes := splitList(value)
+	for _, scope := range scopes {
+		if !nordigen.IsValidAccessScope(scope) {
+			return nil, errors.Errorf("invalid access scope %q, expected any of %s", scope, strings.Join(nordigen.AllAccessScopes, ","))
+		}
+	}
+
+	return scopes, nil
+}
+
+// linkCallbackHandler passes the redirect with the flow's reference to the channel once
+func linkCallbackHandler(reference string, callbacks chan<- linkCallback) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(linkCallbackPath, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("ref") != reference {
+			http.Error(w, "Unknown reference", http.StatusBadRequest)
+			return
+		}
+
+		callback := linkCallback{}
+		if code := query.Get("error"); code != "" {
+			callback.err = &nordigen.RedirectError{Code: code, Details: query.Get("details")}
+			_, _ = fmt.Fprintln(w, "The authorization failed, see the terminal. You can close this window.")
+		} else {
+			_, _ = fmt.Fprintln(w, "The authorization is complete. You can close this window.")
+		}
+
+		select {
+		case callbacks <- callback:
+		default:
+		}
+	})
+
+	return mux
+}
+
+// printLinkedAccounts prints the accounts, the balance columns are left empty without the balances scope
+func printLinkedAccounts(a *app, n *nordigen.Nordigen, accountIDs []uuid.UUID, withBalances bool) error {
+	type linkedAccount struct {
+		Account  *nordigen.AccountResponse  `json:"account"`
+		Balances []nordigen.BalanceResponse `json:"balances"`
+	}
+
+	accounts := make([]linkedAccount, 0, len(accountIDs))
+	t := &table{header: []string{"ACCOUNT", "IBAN", "INSTITUTION", "BALANCE", "AMOUNT", "CURRENCY"}}
+	for _, ID := range accountIDs {
+		account, err := n.Account().Get(ID)
+		if err != nil {
+			return err
+		}
+
+		balances := &nordigen.BalanceCollectionResponse{}
+		if withBalances {
+			if balances, err = n.Account().Balance(ID).Get(); err != nil {
+				return err
+			}
+		}
+
+		accounts = append(accounts, linkedAccount{Account: account, Balances: balances.Balances})
+		if len(balances.Balances) == 0 {
+			t.add(ID.String(), account.Iban, account.InstitutionID, "", "", "")
+		}
+		for _, balance := range balances.Balances {
+			t.add(ID.String(), account.Iban, account.InstitutionID, balance.BalanceType, balance.BalanceAmount.Amount, balance.BalanceAmount.Currency)
+		}
+	}
+
+	return a.out.print(accounts, t)
+}
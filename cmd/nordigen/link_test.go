@@ -0,0 +1,138 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+// browseAs replaces the browser with the end user finishing the authorization of the requisition
+func browseAs(t *testing.T, api *fakeApi, query string, status string, accounts ...uuid.UUID) {
+	original := openBrowser
+	t.Cleanup(func() { openBrowser = original })
+
+	openBrowser = func(link string) error {
+		api.mu.Lock()
+		redirect := ""
+		for _, requisition := range api.requisitions {
+			if strings.Contains(link, requisition.ID.String()) {
+				requisition.Status = status
+				requisition.Accounts = accounts
+				redirect = requisition.RedirectUrl + "?ref=" + requisition.Reference + query
+			}
+		}
+		api.mu.Unlock()
+
+		resp, err := http.Get(redirect)
+		if err != nil {
+			t.Errorf("unexpected error occurred: %s", err)
+			return nil
+		}
+
+		return resp.Body.Close()
+	}
+}
+
+func TestLinkCommand(t *testing.T) {
+	t.Run("link accounts", testLinkCommand)
+	t.Run("link accounts without the balances scope", testLinkCommandWithoutBalances)
+	t.Run("link cancelled by the end user", testLinkCommandCancelled)
+	t.Run("link with an invalid scope", testLinkCommandInvalidScope)
+}
+
+func testLinkCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	accountID := addTestAccount(api)
+	srv := api.start(t)
+	browseAs(t, api, "", nordigen.RequisitionStatusLinked, accountID)
+
+	// When/Act
+	stdout, stderr, code := runTest(t, srv, nil, "link", "-institution", "N26_NTSBDEB1", "-scope", "balances,details")
+
+	// Then/Assert
+	if code != 0 {
+		t.Fatalf("link expected to succeed, got %d %s", code, stderr)
+	}
+
+	if !strings.Contains(stderr, "https://ob.nordigen.com/psd2/start/") || !strings.Contains(stderr, "http://127.0.0.1:") {
+		t.Errorf("link and redirect expected to be printed, got %s", stderr)
+	}
+
+	if !strings.Contains(stdout, accountID.String()) || !strings.Contains(stdout, "closingBooked") || !strings.Contains(stdout, "1234.56") {
+		t.Errorf("linked accounts with balances expected, got %s", stdout)
+	}
+
+	for _, agreement := range api.agreements {
+		if len(agreement.AccessScopes) != 2 {
+			t.Errorf("agreement with the scopes expected, got %+v", agreement)
+		}
+	}
+}
+
+func testLinkCommandWithoutBalances(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	accountID := addTestAccount(api)
+	srv := api.start(t)
+	browseAs(t, api, "", nordigen.RequisitionStatusLinked, accountID)
+
+	// When/Act
+	stdout, stderr, code := runTest(t, srv, nil, "link", "-institution", "N26_NTSBDEB1", "-scope", "details,transactions")
+
+	// Then/Assert
+	if code != 0 {
+		t.Fatalf("link expected to succeed, got %d %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout, accountID.String()) || strings.Contains(stdout, "closingBooked") {
+		t.Errorf("linked accounts without balances expected, got %s", stdout)
+	}
+
+	for _, request := range api.requests {
+		if strings.Contains(request, "/balances") {
+			t.Errorf("balances not expected to be requested, got %s", request)
+		}
+	}
+}
+
+func testLinkCommandCancelled(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start(t)
+	browseAs(t, api, "&error=UserCancelledSession&details=cancelled", nordigen.RequisitionStatusCreated)
+
+	// When/Act
+	_, stderr, code := runTest(t, srv, nil, "link", "-institution", "N26_NTSBDEB1")
+
+	// Then/Assert
+	if code != 1 || !strings.Contains(stderr, "UserCancelledSession") {
+		t.Fatalf("the cancellation expected to be reported, got %d %s", code, stderr)
+	}
+
+	if len(api.requisitions) != 0 || len(api.agreements) != 0 {
+		t.Errorf("requisition and agreement of the cancelled link expected to be deleted, got %d and %d",
+			len(api.requisitions), len(api.agreements))
+	}
+}
+
+func testLinkCommandInvalidScope(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start(t)
+
+	// When/Act
+	_, stderr, code := runTest(t, srv, nil, "link", "-institution", "N26_NTSBDEB1", "-scope", "balances,history")
+
+	// Then/Assert
+	if code != 1 || !strings.Contains(stderr, `invalid access scope "history"`) {
+		t.Fatalf("the invalid scope expected to be reported, got %d %s", code, stderr)
+	}
+
+	if len(api.agreements) != 0 {
+		t.Errorf("no agreement expected to be created, got %d", len(api.agreements))
+	}
+}
@@ -0,0 +1,243 @@
+// Command nordigen inspects and manages the Nordigen API resources from the command line.
+//
+// Usage:
+//
+//	nordigen [-config file] [-format table|json|csv] <command> <subcommand> [flags] [arguments]
+//
+// The credentials are read from the NORDIGEN_SECRET_ID and NORDIGEN_SECRET_KEY environment variables
+// or from the JSON config file, see config.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+// errUsage returned by the commands for invalid arguments, the usage is printed
+var errUsage = errors.New("invalid usage")
+
+// command a top-level command with its subcommands
+type command struct {
+	name        string
+	description string
+	subcommands []subcommand
+	// run the command itself if it has no subcommands
+	run func(a *app, args []string) error
+}
+
+// subcommand e.g. "list" of "institutions"
+type subcommand struct {
+	name  string
+	usage string
+	run   func(a *app, args []string) error
+}
+
+// commands registered by the init functions of the command files
+var commands = map[string]*command{}
+
+func register(c *command) {
+	commands[c.name] = c
+}
+
+// app the state shared by the commands
+type app struct {
+	ctx    context.Context
+	stdout io.Writer
+	stderr io.Writer
+	getenv func(string) string
+	out    *output
+
+	configPath string
+	client     *nordigen.Nordigen
+}
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	code := run(ctx, os.Args[1:], os.Getenv, os.Stdout, os.Stderr)
+	stop()
+	os.Exit(code)
+}
+
+// run executes the command line and returns the exit code
+func run(ctx context.Context, args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	a := &app{ctx: ctx, stdout: stdout, stderr: stderr, getenv: getenv}
+
+	fs := flag.NewFlagSet("nordigen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.StringVar(&a.configPath, "config", "", "JSON config file with the credentials")
+	format := fs.String("format", "table", "output format: table, json or csv")
+	fs.Usage = func() { a.usage() }
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	out, err := newOutput(stdout, *format)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	a.out = out
+
+	if fs.NArg() == 0 {
+		a.usage()
+		return 2
+	}
+
+	cmd, ok := commands[fs.Arg(0)]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n", fs.Arg(0))
+		a.usage()
+		return 2
+	}
+
+	if err := a.runCommand(cmd, fs.Args()[1:]); err != nil {
+		if errors.Is(err, errUsage) {
+			return 2
+		}
+
+		_, _ = fmt.Fprintln(stderr, "error:", err)
+		return 1
+	}
+
+	return 0
+}
+
+func (a *app) runCommand(cmd *command, args []string) error {
+	if cmd.run != nil {
+		return cmd.run(a, args)
+	}
+
+	if len(args) > 0 {
+		for _, sub := range cmd.subcommands {
+			if sub.name == args[0] {
+				return sub.run(a, args[1:])
+			}
+		}
+
+		_, _ = fmt.Fprintf(a.stderr, "unknown subcommand %q\n", args[0])
+	}
+
+	a.commandUsage(cmd)
+
+	return errUsage
+}
+
+func (a *app) usage() {
+	_, _ = fmt.Fprintln(a.stderr, "Usage: nordigen [-config file] [-format table|json|csv] <command> <subcommand> [flags] [arguments]")
+	_, _ = fmt.Fprintln(a.stderr, "\nCommands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		_, _ = fmt.Fprintf(a.stderr, "  %-14s %s\n", name, commands[name].description)
+	}
+
+	_, _ = fmt.Fprintln(a.stderr, "\nCredentials: NORDIGEN_SECRET_ID and NORDIGEN_SECRET_KEY or the config file")
+}
+
+func (a *app) commandUsage(cmd *command) {
+	_, _ = fmt.Fprintf(a.stderr, "Usage of %s:\n", cmd.name)
+	for _, sub := range cmd.subcommands {
+		_, _ = fmt.Fprintf(a.stderr, "  nordigen %s %s %s\n", cmd.name, sub.name, sub.usage)
+	}
+}
+
+// nordigen returns the API client, created with the configured credentials on the first use
+func (a *app) nordigen() (*nordigen.Nordigen, error) {
+	if a.client != nil {
+		return a.client, nil
+	}
+
+	cfg, err := loadConfig(a.configPath, a.getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := cfg.client()
+	if err != nil {
+		return nil, err
+	}
+	a.client = client
+
+	return client, nil
+}
+
+// newFlagSet creates the flag set of a subcommand printing its usage on errors
+func (a *app) newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		_, _ = fmt.Fprintf(a.stderr, "Usage: nordigen %s %s\n", name, usage)
+		fs.PrintDefaults()
+	}
+
+	return fs
+}
+
+// parseArgs parses the flags mixed with the positional arguments, e.g. "get ID -from 2022-01-01",
+// and checks the number of positional arguments
+func parseArgs(fs *flag.FlagSet, args []string, positional int) ([]string, error) {
+	values := make([]string, 0, positional)
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, errUsage
+		}
+
+		if fs.NArg() == 0 {
+			break
+		}
+
+		values = append(values, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+
+	if len(values) != positional {
+		fs.Usage()
+		return nil, errUsage
+	}
+
+	return values, nil
+}
+
+// parseIDArg parses the flags and the only positional argument as an UUID
+func parseIDArg(fs *flag.FlagSet, args []string) (uuid.UUID, error) {
+	values, err := parseArgs(fs, args, 1)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	ID, err := uuid.Parse(values[0])
+	if err != nil {
+		return uuid.Nil, errors.Wrapf(err, "invalid ID %q", values[0])
+	}
+
+	return ID, nil
+}
+
+// splitList splits a comma-separated flag value, dropping empty items
+func splitList(value string) []string {
+	items := make([]string, 0)
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
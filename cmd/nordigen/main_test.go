@@ -0,0 +1,61 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestRun_usage(t *testing.T) {
+	// What/Arrange
+	srv := newFakeApi().start(t)
+
+	// When/Act
+	_, noCommandErr, noCommand := runTest(t, srv, nil)
+	_, unknownErr, unknown := runTest(t, srv, nil, "unknown")
+	_, noSubcommandErr, noSubcommand := runTest(t, srv, nil, "agreements")
+	_, _, badFormat := runTest(t, srv, nil, "-format", "xml", "agreements", "list")
+
+	// Then/Assert
+	if noCommand != 2 || !strings.Contains(noCommandErr, "institutions") || !strings.Contains(noCommandErr, "token") {
+		t.Errorf("usage with the commands expected, got %d %s", noCommand, noCommandErr)
+	}
+
+	if unknown != 2 || !strings.Contains(unknownErr, `unknown command "unknown"`) {
+		t.Errorf("unknown command expected to be reported, got %d %s", unknown, unknownErr)
+	}
+
+	if noSubcommand != 2 || !strings.Contains(noSubcommandErr, "nordigen agreements create -institution ID") {
+		t.Errorf("subcommands usage expected, got %d %s", noSubcommand, noSubcommandErr)
+	}
+
+	if badFormat != 2 {
+		t.Errorf("exit code 2 expected for an unknown format, got %d", badFormat)
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	// What/Arrange
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	from := fs.String("from", "", "")
+	to := fs.String("to", "", "")
+
+	// When/Act
+	values, err := parseArgs(fs, []string{"-from", "2022-01-01", "ID", "-to", "2022-02-01"}, 1)
+	_, tooManyErr := parseArgs(fs, []string{"ID", "ID2"}, 1)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(values) != 1 || values[0] != "ID" || *from != "2022-01-01" || *to != "2022-02-01" {
+		t.Errorf("flags mixed with the arguments expected to be parsed, got %v %s %s", values, *from, *to)
+	}
+
+	if tooManyErr != errUsage {
+		t.Errorf("errUsage expected for unexpected arguments, got %v", tooManyErr)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+	formatCSV   = "csv"
+)
+
+// output prints the results in the chosen format
+type output struct {
+	w      io.Writer
+	format string
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case formatTable, formatJSON, formatCSV:
+		return &output{w: w, format: format}, nil
+	default:
+		return nil, errors.Errorf("unknown format %q, table, json or csv expected", format)
+	}
+}
+
+// table the tabular representation of a result
+type table struct {
+	header []string
+	rows   [][]string
+}
+
+func (t *table) add(row ...string) {
+	t.rows = append(t.rows, row)
+}
+
+// print writes the value as JSON or the table as an aligned table or CSV
+func (o *output) print(value interface{}, t *table) error {
+	switch o.format {
+	case formatJSON:
+		enc := json.NewEncoder(o.w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(value)
+	case formatCSV:
+		cw := csv.NewWriter(o.w)
+		if err := cw.Write(t.header); err != nil {
+			return err
+		}
+		if err := cw.WriteAll(t.rows); err != nil {
+			return err
+		}
+		return cw.Error()
+	default:
+		tw := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+		if _, err := io.WriteString(tw, strings.Join(t.header, "\t")+"\n"); err != nil {
+			return err
+		}
+		for _, row := range t.rows {
+			if _, err := io.WriteString(tw, strings.Join(row, "\t")+"\n"); err != nil {
+				return err
+			}
+		}
+		return tw.Flush()
+	}
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+
+	return t.UTC().Format(time.RFC3339)
+}
+
+func formatTimePtr(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+
+	return formatTime(*t)
+}
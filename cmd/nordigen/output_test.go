@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOutput(t *testing.T) {
+	// What/Arrange
+	value := []map[string]string{{"id": "1", "name": "Bank, Ltd"}}
+	t2 := &table{header: []string{"ID", "NAME"}}
+	t2.add("1", "Bank, Ltd")
+
+	expected := map[string]string{
+		formatTable: "ID  NAME\n1   Bank, Ltd\n",
+		formatCSV:   "ID,NAME\n1,\"Bank, Ltd\"\n",
+		formatJSON:  "[\n  {\n    \"id\": \"1\",\n    \"name\": \"Bank, Ltd\"\n  }\n]\n",
+	}
+
+	for format, want := range expected {
+		buf := &bytes.Buffer{}
+		out, err := newOutput(buf, format)
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %s", err)
+		}
+
+		// When/Act
+		if err := out.print(value, t2); err != nil {
+			t.Fatalf("unexpected error occurred: %s", err)
+		}
+
+		// Then/Assert
+		if buf.String() != want {
+			t.Errorf("%s output expected %q, got %q", format, want, buf.String())
+		}
+	}
+}
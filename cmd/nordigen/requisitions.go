@@ -0,0 +1,172 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+func init() {
+	register(&command{
+		name:        "requisitions",
+		description: "list, get, create and delete the requisitions",
+		subcommands: []subcommand{
+			{name: "list", usage: "[-status LN]", run: requisitionsList},
+			{name: "get", usage: "<requisition ID>", run: requisitionsGet},
+			{
+				name:  "create",
+				usage: "-institution ID -redirect URL [-agreement ID] [-reference REF] [-language EN] [-account-selection]",
+				run:   requisitionsCreate,
+			},
+			{name: "delete", usage: "<requisition ID>", run: requisitionsDelete},
+		},
+	})
+}
+
+func requisitionsList(a *app, args []string) error {
+	fs := a.newFlagSet("requisitions list", "[-status LN]")
+	status := fs.String("status", "", "comma-separated requisition statuses to keep, all if empty")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	list, err := n.Requisition().List()
+	if err != nil {
+		return err
+	}
+
+	requisitions, err := list.All(a.ctx)
+	if err != nil {
+		return err
+	}
+
+	if statuses := splitList(*status); len(statuses) > 0 {
+		kept := make([]nordigen.RequisitionResponse, 0, len(requisitions))
+		for _, requisition := range requisitions {
+			for _, s := range statuses {
+				if strings.EqualFold(s, requisition.Status) {
+					kept = append(kept, requisition)
+					break
+				}
+			}
+		}
+		requisitions = kept
+	}
+
+	return a.out.print(requisitions, requisitionsTable(requisitions...))
+}
+
+func requisitionsGet(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("requisitions get", "<requisition ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	requisition, err := n.Requisition().Get(ID)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(requisition, requisitionsTable(*requisition))
+}
+
+func requisitionsCreate(a *app, args []string) error {
+	fs := a.newFlagSet("requisitions create", "-institution ID -redirect URL [flags]")
+	institution := fs.String("institution", "", "institution ID, required")
+	redirect := fs.String("redirect", "", "redirect URL after the end user authorization, required")
+	agreement := fs.String("agreement", "", "end user agreement ID, the default agreement if empty")
+	reference := fs.String("reference", "", "reference of the requisition, a random UUID if empty")
+	language := fs.String("language", "", "two-letter ISO 639-1 language of the end user")
+	accountSelection := fs.Bool("account-selection", false, "let the end user select the accounts")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *institution == "" || *redirect == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	builder := nordigen.NewRequisitionRequest(*institution, *redirect).
+		WithUserLanguage(*language).
+		WithAccountSelection(*accountSelection)
+
+	if *reference == "" {
+		*reference = uuid.NewString()
+	}
+	builder = builder.WithReference(*reference)
+
+	if *agreement != "" {
+		agreementID, err := uuid.Parse(*agreement)
+		if err != nil {
+			return errors.Wrapf(err, "invalid agreement ID %q", *agreement)
+		}
+		builder = builder.WithAgreementID(agreementID)
+	}
+
+	request, err := builder.Build()
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	requisition, err := n.Requisition().Create(request)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(requisition, requisitionsTable(*requisition))
+}
+
+func requisitionsDelete(a *app, args []string) error {
+	ID, err := parseIDArg(a.newFlagSet("requisitions delete", "<requisition ID>"), args)
+	if err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	return n.Requisition().Delete(ID)
+}
+
+func requisitionsTable(requisitions ...nordigen.RequisitionResponse) *table {
+	t := &table{header: []string{"ID", "INSTITUTION", "STATUS", "CREATED", "REFERENCE", "AGREEMENT", "ACCOUNTS", "LINK"}}
+	for _, requisition := range requisitions {
+		accounts := make([]string, 0, len(requisition.Accounts))
+		for _, account := range requisition.Accounts {
+			accounts = append(accounts, account.String())
+		}
+
+		t.add(
+			requisition.ID.String(),
+			requisition.InstitutionID,
+			requisition.Status,
+			formatTime(requisition.Created),
+			requisition.Reference,
+			requisition.AgreementID.String(),
+			strings.Join(accounts, ","),
+			requisition.Link,
+		)
+	}
+
+	return t
+}
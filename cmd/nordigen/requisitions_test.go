@@ -0,0 +1,40 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRequisitionsCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start(t)
+
+	// When/Act
+	created, createErr, createCode := runTest(t, srv, nil, "requisitions", "create",
+		"-institution", "N26_NTSBDEB1", "-redirect", "https://example.com/callback", "-reference", "user-1", "-language", "de")
+	_, _, _ = runTest(t, srv, nil, "requisitions", "create",
+		"-institution", "N26_NTSBDEB1", "-redirect", "https://example.com/callback", "-reference", "user-2")
+
+	for _, requisition := range api.requisitions {
+		if requisition.Reference == "user-2" {
+			requisition.Status = "LN"
+		}
+	}
+
+	linked, _, listCode := runTest(t, srv, nil, "requisitions", "list", "-status", "ln")
+	_, _, missingRedirect := runTest(t, srv, nil, "requisitions", "create", "-institution", "N26_NTSBDEB1")
+
+	// Then/Assert
+	if createCode != 0 || !strings.Contains(created, "https://ob.nordigen.com/psd2/start/") {
+		t.Errorf("requisition with the link expected, got %d %s %s", createCode, created, createErr)
+	}
+
+	if listCode != 0 || !strings.Contains(linked, "user-2") || strings.Contains(linked, "user-1") {
+		t.Errorf("linked requisitions expected, got %d %s", listCode, linked)
+	}
+
+	if missingRedirect != 2 {
+		t.Errorf("usage error expected without a redirect, got %d", missingRedirect)
+	}
+}
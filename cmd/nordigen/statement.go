@@ -0,0 +1,206 @@
+package main
+
+import (
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+// minAmountDecimals the decimals of the formatted amounts unless the API reports more
+const minAmountDecimals = 2
+
+// closingBalanceTypes the balance types used as the closing balance of a statement, the preferred first.
+// Only the booked balances are consistent with the booked transactions the opening balance is derived from
+var closingBalanceTypes = []string{"closingBooked", "interimBooked"}
+
+// statement the exported transactions of an account
+type statement struct {
+	AccountID     uuid.UUID
+	Iban          string
+	Currency      string
+	OwnerName     string
+	InstitutionID string
+	From          time.Time
+	To            time.Time
+	Created       time.Time
+	Booked        []nordigen.TransactionResponse
+	Pending       []nordigen.TransactionResponse
+	// Balances the balances reported by the API
+	Balances []nordigen.BalanceResponse
+	// Opening and Closing balances of the booked transactions
+	Opening statementBalance
+	Closing statementBalance
+	// BalanceKnown false if the closing balance isn't reported by the API, the opening balance is zero then
+	BalanceKnown bool
+	// Decimals of the balances, the most decimals of the booked amounts and the reported balance, at least 2
+	Decimals int
+}
+
+// statementBalance a balance of the statement
+type statementBalance struct {
+	Amount *big.Rat
+	Date   time.Time
+}
+
+// newStatement builds the statement of the transactions, deriving the opening balance from the closing one
+func newStatement(
+	accountID uuid.UUID,
+	account *nordigen.AccountDetailsInfoResponse,
+	balances []nordigen.BalanceResponse,
+	from, to time.Time,
+	booked, pending []nordigen.TransactionResponse,
+) (*statement, error) {
+	s := &statement{
+		AccountID: accountID,
+		From:      from,
+		To:        to,
+		Created:   time.Now().UTC(),
+		Booked:    booked,
+		Pending:   pending,
+		Balances:  balances,
+		Decimals:  minAmountDecimals,
+	}
+
+	if account != nil {
+		s.Iban, s.Currency, s.OwnerName = account.Iban, account.Currency, account.OwnerName
+	}
+
+	if s.Currency == "" {
+		for _, t := range append(append([]nordigen.TransactionResponse{}, booked...), pending...) {
+			if t.Amount.Currency != "" {
+				s.Currency = t.Amount.Currency
+				break
+			}
+		}
+	}
+
+	total := new(big.Rat)
+	for _, t := range booked {
+		amount, err := parseAmount(t.Amount.Amount)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(total, amount)
+		s.Decimals = maxInt(s.Decimals, amountDecimals(t.Amount.Amount))
+	}
+
+	s.Closing = statementBalance{Amount: total, Date: to}
+	s.Opening = statementBalance{Amount: new(big.Rat), Date: from}
+
+	if balance := closingBalance(balances, s.Currency); balance != nil {
+		closing, err := parseAmount(balance.BalanceAmount.Amount)
+		if err != nil {
+			return nil, err
+		}
+
+		s.BalanceKnown = true
+		s.Decimals = maxInt(s.Decimals, amountDecimals(balance.BalanceAmount.Amount))
+		s.Closing.Amount = closing
+		s.Opening.Amount = new(big.Rat).Sub(closing, total)
+	}
+
+	return s, nil
+}
+
+// closingBalance returns the balance of the preferred type in the currency or nil
+func closingBalance(balances []nordigen.BalanceResponse, currency string) *nordigen.BalanceResponse {
+	for _, balanceType := range closingBalanceTypes {
+		for i := range balances {
+			b := &balances[i]
+			if b.BalanceType == balanceType && (currency == "" || strings.EqualFold(b.BalanceAmount.Currency, currency)) {
+				return b
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseAmount parses the decimal amount of the API exactly
+func parseAmount(value string) (*big.Rat, error) {
+	amount, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return nil, errors.Errorf("invalid amount %q", value)
+	}
+
+	return amount, nil
+}
+
+// formatAmount formats the amount with at least 2 decimals, more if the original value had more,
+// using the decimal separator
+func formatAmount(amount *big.Rat, original string, separator string) string {
+	formatted := amount.FloatString(maxInt(minAmountDecimals, amountDecimals(original)))
+	if separator != "." {
+		formatted = strings.Replace(formatted, ".", separator, 1)
+	}
+
+	return formatted
+}
+
+// amountDecimals returns the number of decimals of the amount of the API
+func amountDecimals(value string) int {
+	value = strings.TrimSpace(value)
+	if i := strings.IndexByte(value, '.'); i >= 0 {
+		return len(value) - i - 1
+	}
+
+	return 0
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+// transactionDate returns the booking date of the transaction, the value date if it's not booked yet
+func transactionDate(t *nordigen.TransactionResponse) string {
+	if t.BookingDate != "" {
+		return t.BookingDate
+	}
+
+	return t.ValueDate
+}
+
+// counterparty returns the name and the account reference of the other party of the transaction
+func counterparty(t *nordigen.TransactionResponse, amount *big.Rat) (string, *nordigen.AccountReference) {
+	if amount.Sign() < 0 {
+		return t.CreditorName, t.CreditorAccount
+	}
+
+	return t.DebtorName, t.DebtorAccount
+}
+
+// accountIdentifier returns the IBAN or the other identifier of the account reference
+func accountIdentifier(ref *nordigen.AccountReference) string {
+	if ref == nil {
+		return ""
+	}
+
+	for _, value := range []string{ref.Iban, ref.Bban, ref.MaskedPan, ref.Pan, ref.MSISDN, ref.Other} {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
+
+// description returns the unstructured remittance information of the transaction
+func description(t *nordigen.TransactionResponse) string {
+	if t.RemittanceInformationUnstructured != "" {
+		return t.RemittanceInformationUnstructured
+	}
+
+	if len(t.RemittanceInformationUnstructuredArray) > 0 {
+		return strings.Join(t.RemittanceInformationUnstructuredArray, " ")
+	}
+
+	return t.AdditionalInformation
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+func testStatement(t *testing.T) *statement {
+	api := newFakeApi()
+	account := api.accounts[addTestAccount(api)]
+
+	s, err := newStatement(
+		account.account.ID,
+		&account.details.Account,
+		account.balances.Balances,
+		time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2022, 3, 31, 0, 0, 0, 0, time.UTC),
+		account.transactions.Transactions.Booked,
+		account.transactions.Transactions.Pending,
+	)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	s.InstitutionID = account.account.InstitutionID
+	s.Created = time.Date(2022, 4, 1, 8, 30, 0, 0, time.UTC)
+
+	return s
+}
+
+func TestNewStatement(t *testing.T) {
+	t.Run("balances", testNewStatementBalances)
+	t.Run("unknown balance", testNewStatementUnknownBalance)
+	t.Run("no booked balance", testNewStatementNoBookedBalance)
+	t.Run("invalid amount", testNewStatementInvalidAmount)
+}
+
+func testNewStatementBalances(t *testing.T) {
+	// What/Arrange & When/Act
+	s := testStatement(t)
+
+	// Then/Assert
+	if !s.BalanceKnown || s.Closing.Amount.FloatString(2) != "1234.56" {
+		t.Errorf("closingBooked expected as the closing balance, got %s", s.Closing.Amount.FloatString(2))
+	}
+
+	// 1234.56 - (2500.00 - 45.10)
+	if s.Opening.Amount.FloatString(2) != "-1220.34" {
+		t.Errorf("opening balance derived from the booked transactions expected, got %s", s.Opening.Amount.FloatString(2))
+	}
+
+	if s.Iban != "DE89370400440532013000" || s.Currency != "EUR" || s.OwnerName != "Jane Doe" {
+		t.Errorf("account details expected, got %+v", s)
+	}
+}
+
+func testNewStatementUnknownBalance(t *testing.T) {
+	// What/Arrange
+	booked := []nordigen.TransactionResponse{
+		{Amount: nordigen.Amount{Amount: "10.005", Currency: "SEK"}, BookingDate: "2022-03-02"},
+		{Amount: nordigen.Amount{Amount: "-2.5", Currency: "SEK"}, BookingDate: "2022-03-03"},
+	}
+
+	// When/Act
+	s, err := newStatement(uuid.New(), nil, nil, time.Now(), time.Now(), booked, nil)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if s.BalanceKnown || s.Opening.Amount.Sign() != 0 || s.Closing.Amount.Cmp(big.NewRat(7505, 1000)) != 0 {
+		t.Errorf("zero opening balance expected, got %s - %s", s.Opening.Amount.FloatString(3), s.Closing.Amount.FloatString(3))
+	}
+
+	if s.Currency != "SEK" {
+		t.Errorf("currency of the transactions expected, got %s", s.Currency)
+	}
+
+	if s.Decimals != 3 {
+		t.Errorf("decimals of the transactions expected, got %d", s.Decimals)
+	}
+}
+
+func testNewStatementNoBookedBalance(t *testing.T) {
+	// What/Arrange
+	booked := []nordigen.TransactionResponse{{Amount: nordigen.Amount{Amount: "10.00", Currency: "EUR"}, BookingDate: "2022-03-02"}}
+	balances := []nordigen.BalanceResponse{
+		{BalanceAmount: nordigen.Amount{Amount: "900.00", Currency: "EUR"}, BalanceType: "interimAvailable"},
+		{BalanceAmount: nordigen.Amount{Amount: "950.00", Currency: "EUR"}, BalanceType: "expected"},
+	}
+
+	// When/Act
+	s, err := newStatement(uuid.New(), nil, balances, time.Now(), time.Now(), booked, nil)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if s.BalanceKnown || s.Opening.Amount.Sign() != 0 {
+		t.Errorf("unknown balance expected without a booked balance, got %s", s.Opening.Amount.FloatString(2))
+	}
+}
+
+func testNewStatementInvalidAmount(t *testing.T) {
+	// What/Arrange
+	booked := []nordigen.TransactionResponse{{Amount: nordigen.Amount{Amount: "1,00", Currency: "EUR"}}}
+
+	// When/Act
+	_, err := newStatement(uuid.New(), nil, nil, time.Now(), time.Now(), booked, nil)
+
+	// Then/Assert
+	if err == nil {
+		t.Error("error expected for an invalid amount")
+	}
+}
+
+func Test_formatAmount(t *testing.T) {
+	// What/Arrange
+	cases := []struct {
+		original, separator, expected string
+	}{
+		{"-45.1", ".", "-45.10"},
+		{"2500", ",", "2500,00"},
+		{"0.125", ".", "0.125"},
+	}
+
+	for _, c := range cases {
+		amount, err := parseAmount(c.original)
+		if err != nil {
+			t.Fatalf("unexpected error occurred: %s", err)
+		}
+
+		// When/Act
+		formatted := formatAmount(amount, c.original, c.separator)
+
+		// Then/Assert
+		if formatted != c.expected {
+			t.Errorf("%s expected for %s, got %s", c.expected, c.original, formatted)
+		}
+	}
+}
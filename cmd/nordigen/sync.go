@@ -0,0 +1,525 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+	"gromson/nordigen/rest"
+)
+
+const (
+	// defaultDailyLimit the number of requests per account and endpoint allowed by the API each day
+	defaultDailyLimit  = 4
+	defaultHistoryDays = 90
+	defaultSyncListen  = "127.0.0.1:8080"
+	// syncOverlapDays the transactions of the last days before the last booked one are fetched again,
+	// some ASPSPs book the transactions with a past date
+	syncOverlapDays = 7
+	// syncRefreshTimeout the time to finish an account's refresh, also after the shutdown signal
+	syncRefreshTimeout = 2 * time.Minute
+	// syncResolveInterval how often the requisitions are checked for new accounts
+	syncResolveInterval = time.Hour
+	syncShutdownTimeout = 10 * time.Second
+)
+
+func init() {
+	register(&command{
+		name:        "serve",
+		description: "refresh the configured accounts within the daily limits, serving the health and status",
+		run:         serve,
+	})
+	register(&command{
+		name:        "sync",
+		description: "refresh the configured accounts which are due and exit",
+		run:         syncOnce,
+	})
+}
+
+// syncAccountState the refresh schedule of an account
+type syncAccountState struct {
+	AccountID     uuid.UUID `json:"account_id"`
+	RequisitionID uuid.UUID `json:"requisition_id"`
+	// Day the UTC date the Calls are counted for
+	Day   string `json:"day"`
+	Calls int    `json:"calls"`
+	// NextRefresh the time the account can be refreshed at
+	NextRefresh time.Time `json:"next_refresh"`
+	LastRefresh time.Time `json:"last_refresh,omitempty"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+	// Unlinked the account is no longer linked, its state is kept for the rest of the day, so the Calls
+	// still count if it's linked again
+	Unlinked bool `json:"unlinked,omitempty"`
+}
+
+// due reports whether the account can be refreshed at the time without exceeding the daily limit
+func (st *syncAccountState) due(now time.Time, limit int) bool {
+	if now.Before(st.NextRefresh) {
+		return false
+	}
+
+	return st.Day != utcDay(now) || st.Calls < limit
+}
+
+// record counts the refresh and schedules the next one spreading the daily limit over the day.
+// The rest of the day is skipped once the API reports the limit is exceeded
+func (st *syncAccountState) record(now time.Time, limit int, err error, rateLimited bool) {
+	if day := utcDay(now); st.Day != day {
+		st.Day, st.Calls = day, 0
+	}
+
+	st.Calls++
+	st.LastRefresh = now
+	st.NextRefresh = now.Add(24 * time.Hour / time.Duration(limit))
+
+	if err != nil {
+		st.LastError = err.Error()
+	} else {
+		st.LastSuccess, st.LastError = now, ""
+	}
+
+	if rateLimited || st.Calls >= limit {
+		st.Calls = limit
+		if midnight := nextUTCMidnight(now); st.NextRefresh.Before(midnight) {
+			st.NextRefresh = midnight
+		}
+	}
+}
+
+func utcDay(t time.Time) string {
+	return t.UTC().Format("2006-01-02")
+}
+
+func nextUTCMidnight(t time.Time) time.Time {
+	return truncateDate(t.UTC()).AddDate(0, 0, 1)
+}
+
+// syncer refreshes the accounts of the configured requisitions, keeping a single authenticated client
+type syncer struct {
+	client *nordigen.Nordigen
+	store  *syncStore
+	cfg    syncConfig
+	log    io.Writer
+	now    func() time.Time
+
+	mu         sync.Mutex
+	state      *syncState
+	agreements map[uuid.UUID]*nordigen.EndUserAgreementResponse
+	started    time.Time
+	resolved   time.Time
+	resolveErr error
+}
+
+// syncStatus the status served by the serve command
+type syncStatus struct {
+	Started      time.Time          `json:"started"`
+	Resolved     time.Time          `json:"resolved,omitempty"`
+	ResolveError string             `json:"resolve_error,omitempty"`
+	Accounts     []syncAccountState `json:"accounts"`
+}
+
+func newSyncer(client *nordigen.Nordigen, cfg syncConfig, log io.Writer) (*syncer, error) {
+	if len(cfg.Requisitions) == 0 && len(cfg.References) == 0 {
+		return nil, errors.New("no accounts to refresh, configure sync.requisitions or sync.references")
+	}
+
+	if cfg.DailyLimit <= 0 {
+		cfg.DailyLimit = defaultDailyLimit
+	}
+	if cfg.HistoryDays <= 0 {
+		cfg.HistoryDays = defaultHistoryDays
+	}
+
+	store, err := newSyncStore(cfg.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := store.loadState()
+	if err != nil {
+		return nil, err
+	}
+
+	return &syncer{
+		client:     client,
+		store:      store,
+		cfg:        cfg,
+		log:        log,
+		now:        time.Now,
+		state:      state,
+		agreements: make(map[uuid.UUID]*nordigen.EndUserAgreementResponse),
+		started:    time.Now(),
+	}, nil
+}
+
+// resolve finds the accounts of the linked requisitions. New accounts are refreshed right away,
+// the accounts no longer linked are not refreshed anymore but their data and the calls of the day are kept
+func (s *syncer) resolve(ctx context.Context) error {
+	requisitions, err := s.requisitions(ctx)
+
+	// the agreements limit the fetched parts to the granted scopes, all the parts are fetched without them
+	agreements := make(map[uuid.UUID]*nordigen.EndUserAgreementResponse)
+	for _, requisition := range requisitions {
+		if requisition.Status == nordigen.RequisitionStatusLinked {
+			if agreement, err := s.client.EndUserAgreement().Get(requisition.AgreementID); err == nil {
+				agreements[requisition.ID] = agreement
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.resolveErr = err
+	if err != nil {
+		return err
+	}
+	s.resolved = s.now()
+
+	linked := make(map[uuid.UUID]bool)
+	for _, requisition := range requisitions {
+		if requisition.Status != nordigen.RequisitionStatusLinked {
+			continue
+		}
+
+		for _, ID := range requisition.Accounts {
+			linked[ID] = true
+			s.agreements[ID] = agreements[requisition.ID]
+			if st, ok := s.state.Accounts[ID]; ok {
+				st.RequisitionID, st.Unlinked = requisition.ID, false
+			} else {
+				s.state.Accounts[ID] = &syncAccountState{AccountID: ID, RequisitionID: requisition.ID}
+			}
+		}
+	}
+
+	today := utcDay(s.resolved)
+	for ID, st := range s.state.Accounts {
+		if linked[ID] {
+			continue
+		}
+
+		delete(s.agreements, ID)
+		if st.Day == today {
+			st.Unlinked = true
+		} else {
+			delete(s.state.Accounts, ID)
+		}
+	}
+
+	return s.store.saveState(s.state)
+}
+
+// requisitions returns the configured requisitions, those given by the references included
+func (s *syncer) requisitions(ctx context.Context) ([]nordigen.RequisitionResponse, error) {
+	requisitions := make([]nordigen.RequisitionResponse, 0, len(s.cfg.Requisitions))
+	for _, ID := range s.cfg.Requisitions {
+		requisition, err := s.client.Requisition().Get(ID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error getting requisition %s", ID)
+		}
+		requisitions = append(requisitions, *requisition)
+	}
+
+	if len(s.cfg.References) == 0 {
+		return requisitions, nil
+	}
+
+	references := make(map[string]bool, len(s.cfg.References))
+	for _, reference := range s.cfg.References {
+		references[reference] = true
+	}
+
+	list, err := s.client.Requisition().List()
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing requisitions")
+	}
+
+	all, err := list.All(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing requisitions")
+	}
+
+	for _, requisition := range all {
+		if references[requisition.Reference] {
+			requisitions = append(requisitions, requisition)
+		}
+	}
+
+	return requisitions, nil
+}
+
+// due returns the accounts which can be refreshed now
+func (s *syncer) due() []uuid.UUID {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	IDs := make([]uuid.UUID, 0)
+	for ID, st := range s.state.Accounts {
+		if !st.Unlinked && st.due(now, s.cfg.DailyLimit) {
+			IDs = append(IDs, ID)
+		}
+	}
+	sort.Slice(IDs, func(i, j int) bool { return IDs[i].String() < IDs[j].String() })
+
+	return IDs
+}
+
+// runDue refreshes the due accounts one by one until the context is done.
+// A started refresh is finished even if the context is done meanwhile
+func (s *syncer) runDue(ctx context.Context) int {
+	refreshed := 0
+	for _, ID := range s.due() {
+		if ctx.Err() != nil {
+			break
+		}
+
+		refreshCtx, cancel := context.WithTimeout(context.Background(), syncRefreshTimeout)
+		err := s.refresh(refreshCtx, ID)
+		cancel()
+
+		if err != nil {
+			_, _ = fmt.Fprintf(s.log, "account %s: %s\n", ID, err)
+		}
+		refreshed++
+	}
+
+	return refreshed
+}
+
+// refresh fetches the account and stores its data. The refresh counts against the daily limit even if it fails
+func (s *syncer) refresh(ctx context.Context, ID uuid.UUID) error {
+	stored, err := s.store.loadAccount(ID)
+	if err != nil {
+		return err
+	}
+
+	from := truncateDate(s.now()).AddDate(0, 0, -s.cfg.HistoryDays)
+	if last := stored.lastBookingDate(); last != nil {
+		from = last.AddDate(0, 0, -syncOverlapDays)
+	}
+
+	s.mu.Lock()
+	agreement := s.agreements[ID]
+	s.mu.Unlock()
+
+	snapshot, snapshotErr := s.client.Account().Snapshot(ctx, ID, &nordigen.AccountSnapshotOptions{
+		Agreement: agreement,
+		DateFrom:  &from,
+	})
+
+	if stored == nil {
+		stored = &syncedAccount{}
+	}
+	stored.merge(snapshot)
+
+	saveErr := s.store.saveAccount(ID, stored)
+	if snapshotErr == nil {
+		snapshotErr = saveErr
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if st, ok := s.state.Accounts[ID]; ok {
+		st.record(s.now(), s.cfg.DailyLimit, snapshotErr, snapshotRateLimited(snapshot))
+	}
+
+	if err := s.store.saveState(s.state); err != nil {
+		return err
+	}
+
+	return snapshotErr
+}
+
+// snapshotRateLimited reports whether the API rejected any part of the snapshot with 429 Too Many Requests
+func snapshotRateLimited(snapshot *nordigen.AccountSnapshot) bool {
+	for _, err := range []error{snapshot.AccountErr, snapshot.DetailsErr, snapshot.BalancesErr, snapshot.TransactionsErr} {
+		var apiErr *rest.ApiError
+		if errors.As(err, &apiErr) && apiErr.StatusCode() == http.StatusTooManyRequests {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *syncer) status() *syncStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status := &syncStatus{Started: s.started, Resolved: s.resolved, Accounts: make([]syncAccountState, 0, len(s.state.Accounts))}
+	if s.resolveErr != nil {
+		status.ResolveError = s.resolveErr.Error()
+	}
+
+	for _, st := range s.state.Accounts {
+		status.Accounts = append(status.Accounts, *st)
+	}
+	sort.Slice(status.Accounts, func(i, j int) bool {
+		return status.Accounts[i].AccountID.String() < status.Accounts[j].AccountID.String()
+	})
+
+	return status
+}
+
+// loop resolves the accounts and refreshes the due ones every tick until the context is done
+func (s *syncer) loop(ctx context.Context, tick time.Duration) {
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	var resolved time.Time
+	for {
+		if resolved.IsZero() || time.Since(resolved) >= syncResolveInterval {
+			if err := s.resolve(ctx); err != nil {
+				_, _ = fmt.Fprintf(s.log, "error resolving accounts: %s\n", err)
+			} else {
+				resolved = time.Now()
+			}
+		}
+
+		s.runDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// handler serves the health and the status:
+//
+//	/healthz  200 while the process is running
+//	/readyz   200 once the accounts have been resolved, 503 before
+//	/status   the schedule of the accounts as JSON
+func (s *syncer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if s.status().Resolved.IsZero() {
+			http.Error(w, "accounts not resolved yet", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(s.status())
+	})
+
+	return mux
+}
+
+// syncFlags adds the flags overriding the sync config
+func syncFlags(a *app, name, usage string) (*flagSetWithSync, error) {
+	cfg, err := loadConfig(a.configPath, a.getenv)
+	if err != nil {
+		return nil, err
+	}
+
+	f := &flagSetWithSync{FlagSet: a.newFlagSet(name, usage), cfg: cfg.Sync}
+	f.StringVar(&f.cfg.Store, "store", f.cfg.Store, "directory of the refreshed data, sync.store of the config if empty")
+	f.IntVar(&f.cfg.DailyLimit, "daily-limit", f.cfg.DailyLimit, "refreshes of an account per day, 4 if zero")
+
+	return f, nil
+}
+
+func serve(a *app, args []string) error {
+	f, err := syncFlags(a, "serve", "[-store DIR] [-listen ADDR] [-daily-limit 4] [-tick 1m]")
+	if err != nil {
+		return err
+	}
+	listen := f.String("listen", f.cfg.Listen, "address of the health and status endpoints, "+defaultSyncListen+" if empty")
+	tick := f.Duration("tick", time.Minute, "how often the due accounts are checked")
+	if _, err := parseArgs(f.FlagSet, args, 0); err != nil {
+		return err
+	}
+
+	if *listen == "" {
+		*listen = defaultSyncListen
+	}
+
+	s, err := f.syncer(a)
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", *listen)
+	if err != nil {
+		return errors.Wrap(err, "error starting the status listener")
+	}
+
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() { _ = srv.Serve(listener) }()
+
+	_, _ = fmt.Fprintf(a.stderr, "Refreshing %d requisitions and %d references, status at http://%s/status\n",
+		len(s.cfg.Requisitions), len(s.cfg.References), listener.Addr())
+
+	s.loop(a.ctx, *tick)
+
+	_, _ = fmt.Fprintln(a.stderr, "Shutting down")
+	ctx, cancel := context.WithTimeout(context.Background(), syncShutdownTimeout)
+	defer cancel()
+
+	return errors.Wrap(srv.Shutdown(ctx), "error shutting down the status listener")
+}
+
+func syncOnce(a *app, args []string) error {
+	f, err := syncFlags(a, "sync", "[-store DIR] [-daily-limit 4]")
+	if err != nil {
+		return err
+	}
+	if _, err := parseArgs(f.FlagSet, args, 0); err != nil {
+		return err
+	}
+
+	s, err := f.syncer(a)
+	if err != nil {
+		return err
+	}
+
+	if err := s.resolve(a.ctx); err != nil {
+		return err
+	}
+
+	s.runDue(a.ctx)
+
+	status := s.status()
+	t := &table{header: []string{"ACCOUNT", "LAST_REFRESH", "CALLS_TODAY", "NEXT_REFRESH", "ERROR"}}
+	for _, st := range status.Accounts {
+		t.add(st.AccountID.String(), formatTime(st.LastRefresh), strconv.Itoa(st.Calls), formatTime(st.NextRefresh), st.LastError)
+	}
+
+	return a.out.print(status, t)
+}
+
+// flagSetWithSync the flags of the serve and sync commands with the sync config they override
+type flagSetWithSync struct {
+	*flag.FlagSet
+	cfg syncConfig
+}
+
+func (f *flagSetWithSync) syncer(a *app) (*syncer, error) {
+	n, err := a.nordigen()
+	if err != nil {
+		return nil, err
+	}
+
+	return newSyncer(n, f.cfg, a.stderr)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+// syncStore keeps the refreshed accounts and their schedule in a directory:
+//
+//	state.json          the schedule and the outcome of the last refreshes
+//	accounts/<ID>.json  the data of an account
+type syncStore struct {
+	dir string
+}
+
+// syncState the schedule of the accounts
+type syncState struct {
+	Accounts map[uuid.UUID]*syncAccountState `json:"accounts"`
+}
+
+// syncedAccount the data of an account accumulated by the refreshes
+type syncedAccount struct {
+	Account  *nordigen.AccountResponse        `json:"account"`
+	Details  *nordigen.AccountDetailsResponse `json:"details,omitempty"`
+	Balances []nordigen.BalanceResponse       `json:"balances"`
+	// Booked all the booked transactions fetched so far, ordered by the booking date
+	Booked []nordigen.TransactionResponse `json:"booked"`
+	// Pending the transactions pending at the last refresh
+	Pending []nordigen.TransactionResponse `json:"pending"`
+	Updated time.Time                      `json:"updated"`
+}
+
+func newSyncStore(dir string) (*syncStore, error) {
+	if dir == "" {
+		return nil, errors.New("the store directory isn't configured, use -store or sync.store in the config")
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "accounts"), 0o700); err != nil {
+		return nil, errors.Wrap(err, "error creating the store")
+	}
+
+	return &syncStore{dir: dir}, nil
+}
+
+// loadState returns the stored schedule, an empty one on the first run
+func (s *syncStore) loadState() (*syncState, error) {
+	state := &syncState{}
+	if err := s.read("state.json", state); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	if state.Accounts == nil {
+		state.Accounts = make(map[uuid.UUID]*syncAccountState)
+	}
+
+	return state, nil
+}
+
+func (s *syncStore) saveState(state *syncState) error {
+	return s.write("state.json", state)
+}
+
+// loadAccount returns the stored data of the account, nil if it hasn't been refreshed yet
+func (s *syncStore) loadAccount(ID uuid.UUID) (*syncedAccount, error) {
+	account := &syncedAccount{}
+	if err := s.read(accountFileName(ID), account); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *syncStore) saveAccount(ID uuid.UUID, account *syncedAccount) error {
+	return s.write(accountFileName(ID), account)
+}
+
+func accountFileName(ID uuid.UUID) string {
+	return filepath.Join("accounts", ID.String()+".json")
+}
+
+func (s *syncStore) read(name string, v interface{}) error {
+	data, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return err
+	}
+
+	return errors.Wrapf(json.Unmarshal(data, v), "error reading %s", name)
+}
+
+// write replaces the file atomically, so a crash never leaves a truncated file behind
+func (s *syncStore) write(name string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.dir, name)
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+
+	if err := tmp.Close(); err != nil {
+		return errors.Wrapf(err, "error writing %s", name)
+	}
+
+	return errors.Wrapf(os.Rename(tmp.Name(), path), "error writing %s", name)
+}
+
+// merge applies the successfully fetched parts of the snapshot. The booked transactions are added
+// to the known ones, the n-th transaction of a key replacing the n-th known one, as the keys of identical
+// transactions without an ID are equal
+func (a *syncedAccount) merge(snapshot *nordigen.AccountSnapshot) {
+	if snapshot.AccountErr == nil {
+		a.Account = snapshot.Account
+	}
+	if snapshot.DetailsErr == nil {
+		a.Details = snapshot.Details
+	}
+	if snapshot.BalancesErr == nil {
+		a.Balances = snapshot.Balances.Balances
+	}
+
+	if snapshot.TransactionsErr == nil {
+		index := make(map[string][]int, len(a.Booked))
+		for i := range a.Booked {
+			key := a.Booked[i].Key()
+			index[key] = append(index[key], i)
+		}
+
+		seen := make(map[string]int)
+		for _, t := range snapshot.Transactions.Transactions.Booked {
+			key := t.Key()
+			n := seen[key]
+			seen[key]++
+			if n < len(index[key]) {
+				a.Booked[index[key][n]] = t
+				continue
+			}
+			index[key] = append(index[key], len(a.Booked))
+			a.Booked = append(a.Booked, t)
+		}
+
+		sortByDate(a.Booked)
+		a.Pending = snapshot.Transactions.Transactions.Pending
+	}
+
+	a.Updated = snapshot.FetchedAt
+}
+
+// lastBookingDate returns the booking date of the most recent booked transaction, nil if there are none
+func (a *syncedAccount) lastBookingDate() *time.Time {
+	if a == nil || len(a.Booked) == 0 {
+		return nil
+	}
+
+	date, err := time.Parse("2006-01-02", transactionDate(&a.Booked[len(a.Booked)-1]))
+	if err != nil {
+		return nil
+	}
+
+	return &date
+}
+
+func sortByDate(transactions []nordigen.TransactionResponse) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactionDate(&transactions[i]) < transactionDate(&transactions[j])
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+func TestSyncStore(t *testing.T) {
+	t.Run("round trip", testSyncStoreRoundTrip)
+	t.Run("merge", testSyncedAccountMerge)
+	t.Run("merge identical transactions", testSyncedAccountMergeIdentical)
+}
+
+func testSyncStoreRoundTrip(t *testing.T) {
+	// What/Arrange
+	store, err := newSyncStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	ID := uuid.New()
+
+	// When/Act
+	missing, missingErr := store.loadAccount(ID)
+	emptyState, stateErr := store.loadState()
+	saveErr := store.saveAccount(ID, &syncedAccount{Booked: []nordigen.TransactionResponse{{BookingDate: "2022-03-01"}}})
+	loaded, loadErr := store.loadAccount(ID)
+
+	// Then/Assert
+	if missing != nil || missingErr != nil {
+		t.Errorf("nil expected for a missing account, got %v %v", missing, missingErr)
+	}
+
+	if stateErr != nil || len(emptyState.Accounts) != 0 {
+		t.Errorf("empty state expected on the first run, got %v %v", emptyState, stateErr)
+	}
+
+	if saveErr != nil || loadErr != nil || loaded.lastBookingDate().Format("2006-01-02") != "2022-03-01" {
+		t.Errorf("stored account expected, got %v %v %v", loaded, saveErr, loadErr)
+	}
+
+	if _, err := newSyncStore(""); err == nil {
+		t.Error("error expected for an unconfigured store")
+	}
+}
+
+func testSyncedAccountMerge(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	fake := api.accounts[addTestAccount(api)]
+	stored := &syncedAccount{
+		Booked:  fake.transactions.Transactions.Booked[1:],
+		Pending: []nordigen.TransactionResponse{{ValueDate: "2022-02-28"}},
+	}
+
+	transactions := fake.transactions
+	transactions.Transactions.Booked = append([]nordigen.TransactionResponse{}, fake.transactions.Transactions.Booked...)
+	transactions.Transactions.Booked[1].RemittanceInformationUnstructured = "Salary March corrected"
+	snapshot := &nordigen.AccountSnapshot{
+		Account:      &fake.account,
+		BalancesErr:  errors.New("failed"),
+		Details:      &fake.details,
+		Transactions: &transactions,
+		FetchedAt:    time.Now(),
+	}
+
+	// When/Act
+	stored.merge(snapshot)
+
+	// Then/Assert
+	if len(stored.Booked) != 2 || stored.Booked[0].BookingDate != "2022-03-01" ||
+		stored.Booked[1].RemittanceInformationUnstructured != "Salary March corrected" {
+		t.Errorf("deduplicated booked transactions ordered by date expected, got %+v", stored.Booked)
+	}
+
+	if len(stored.Pending) != 1 || stored.Pending[0].ValueDate != "2022-03-30" {
+		t.Errorf("pending transactions of the snapshot expected, got %+v", stored.Pending)
+	}
+
+	if stored.Balances != nil || stored.Details == nil || stored.Account == nil {
+		t.Errorf("only the fetched parts expected to be merged, got %+v", stored)
+	}
+}
+
+func testSyncedAccountMergeIdentical(t *testing.T) {
+	// What/Arrange
+	coffee := nordigen.TransactionResponse{
+		Amount:                            nordigen.Amount{Amount: "-3.50", Currency: "EUR"},
+		BookingDate:                       "2022-03-02",
+		RemittanceInformationUnstructured: "Coffee",
+	}
+	stored := &syncedAccount{Booked: []nordigen.TransactionResponse{coffee}}
+	snapshot := &nordigen.AccountSnapshot{
+		BalancesErr:  errors.New("failed"),
+		Transactions: &nordigen.TransactionCollectionResponse{},
+		FetchedAt:    time.Now(),
+	}
+	snapshot.Transactions.Transactions.Booked = []nordigen.TransactionResponse{coffee, coffee}
+
+	// When/Act
+	stored.merge(snapshot)
+	stored.merge(snapshot)
+
+	// Then/Assert
+	if len(stored.Booked) != 2 {
+		t.Errorf("each of the identical transactions expected once, got %d", len(stored.Booked))
+	}
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+// addTestRequisition adds a requisition of the accounts with the reference and the status
+func addTestRequisition(api *fakeApi, reference, status string, accounts ...uuid.UUID) uuid.UUID {
+	ID := uuid.New()
+	api.requisitions[ID] = &nordigen.RequisitionResponse{ID: ID, Reference: reference, Status: status, Accounts: accounts}
+
+	return ID
+}
+
+// writeSyncConfig writes the config file with the sync section and returns the environment using it
+func writeSyncConfig(t *testing.T, cfg syncConfig) map[string]string {
+	path := filepath.Join(t.TempDir(), "config.json")
+	data, _ := json.Marshal(map[string]interface{}{"sync": cfg})
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	return map[string]string{envConfigPath: path}
+}
+
+func TestSyncAccountState(t *testing.T) {
+	// What/Arrange
+	st := &syncAccountState{}
+	morning := time.Date(2022, 3, 1, 8, 0, 0, 0, time.UTC)
+
+	// When/Act & Then/Assert
+	if !st.due(morning, 4) {
+		t.Fatal("a new account expected to be due")
+	}
+
+	st.record(morning, 4, nil, false)
+	if st.Calls != 1 || !st.NextRefresh.Equal(morning.Add(6*time.Hour)) || st.due(morning.Add(time.Hour), 4) {
+		t.Errorf("next refresh expected in 6 hours, got %+v", st)
+	}
+
+	midnight := time.Date(2022, 3, 2, 0, 0, 0, 0, time.UTC)
+	st.record(morning.Add(6*time.Hour), 4, nil, true)
+	if st.Calls != 4 || !st.NextRefresh.Equal(midnight) || st.due(midnight.Add(-time.Minute), 4) {
+		t.Errorf("the rest of the day expected to be skipped once rate limited, got %+v", st)
+	}
+
+	if !st.due(midnight, 4) {
+		t.Error("the account expected to be due the next day")
+	}
+
+	st.record(midnight, 4, context.DeadlineExceeded, false)
+	if st.Calls != 1 || st.Day != "2022-03-02" || st.LastError == "" || !st.LastSuccess.Equal(morning.Add(6*time.Hour)) {
+		t.Errorf("the calls expected to be counted per day, got %+v", st)
+	}
+}
+
+func TestSyncCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	first, second, limited := addTestAccount(api), addTestAccount(api), addTestAccount(api)
+	api.accounts[limited].rateLimited = true
+	byID := addTestRequisition(api, "other", nordigen.RequisitionStatusLinked, first)
+	addTestRequisition(api, "customer-42", nordigen.RequisitionStatusLinked, second, limited)
+	addTestRequisition(api, "customer-42", "EX", uuid.New())
+	srv := api.start(t)
+
+	store := t.TempDir()
+	env := writeSyncConfig(t, syncConfig{
+		Requisitions: []uuid.UUID{byID},
+		References:   []string{"customer-42"},
+		Store:        store,
+		// the test transactions are booked in 2022
+		HistoryDays: 10000,
+	})
+
+	// When/Act
+	out, stderr, code := runTest(t, srv, env, "-format", "json", "sync")
+	api.requests = nil
+	_, _, againCode := runTest(t, srv, env, "sync")
+	again := strings.Join(api.requests, "\n")
+
+	// Then/Assert
+	if code != 0 {
+		t.Fatalf("successful sync expected, got %d: %s", code, stderr)
+	}
+
+	status := &syncStatus{}
+	if err := json.Unmarshal([]byte(out), status); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(status.Accounts) != 3 {
+		t.Fatalf("the accounts of the linked requisitions expected, got %+v", status.Accounts)
+	}
+
+	for _, st := range status.Accounts {
+		if st.AccountID == limited && (st.Calls != defaultDailyLimit || st.LastError == "") {
+			t.Errorf("the rate limited account expected to be skipped for the rest of the day, got %+v", st)
+		}
+		if st.AccountID != limited && (st.Calls != 1 || st.LastError != "") {
+			t.Errorf("a single refresh expected, got %+v", st)
+		}
+	}
+
+	data, err := newSyncStore(store)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	account, err := data.loadAccount(first)
+	if err != nil || account == nil || len(account.Booked) != 2 || len(account.Balances) != 2 || account.Details.Account.OwnerName != "Jane Doe" {
+		t.Errorf("stored account expected, got %+v %v", account, err)
+	}
+
+	if againCode != 0 || strings.Contains(again, "/accounts/") {
+		t.Errorf("no refresh expected before the next slot, got %d\n%s", againCode, again)
+	}
+}
+
+func TestSyncCommandNotConfigured(t *testing.T) {
+	// What/Arrange
+	srv := newFakeApi().start(t)
+
+	// When/Act
+	_, noAccounts, noAccountsCode := runTest(t, srv, writeSyncConfig(t, syncConfig{Store: t.TempDir()}), "sync")
+	_, noStore, noStoreCode := runTest(t, srv, writeSyncConfig(t, syncConfig{References: []string{"customer-42"}}), "sync")
+
+	// Then/Assert
+	if noAccountsCode != 1 || !strings.Contains(noAccounts, "no accounts") {
+		t.Errorf("error expected without accounts, got %d %s", noAccountsCode, noAccounts)
+	}
+
+	if noStoreCode != 1 || !strings.Contains(noStore, "store") {
+		t.Errorf("error expected without the store, got %d %s", noStoreCode, noStore)
+	}
+}
+
+func TestServeCommand(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api)
+	addTestRequisition(api, "customer-42", nordigen.RequisitionStatusLinked, ID)
+	srv := api.start(t)
+
+	store := t.TempDir()
+	env := writeSyncConfig(t, syncConfig{References: []string{"customer-42"}, Store: store})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// When/Act
+	done := make(chan int, 1)
+	var stderr string
+	go func() {
+		var code int
+		_, stderr, code = runTestContext(ctx, t, srv, env, "serve", "-listen", "127.0.0.1:0", "-tick", "10ms")
+		done <- code
+	}()
+
+	accountFile := filepath.Join(store, accountFileName(ID))
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if _, err := os.Stat(accountFile); err == nil {
+			break
+		}
+	}
+	cancel()
+
+	// Then/Assert
+	select {
+	case code := <-done:
+		if code != 0 || !strings.Contains(stderr, "Shutting down") {
+			t.Errorf("graceful shutdown expected, got %d: %s", code, stderr)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the daemon expected to stop")
+	}
+
+	if _, err := os.Stat(accountFile); err != nil {
+		t.Errorf("refreshed account expected in the store: %s", err)
+	}
+}
+
+func TestSyncer_handler(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api)
+	addTestRequisition(api, "customer-42", nordigen.RequisitionStatusLinked, ID)
+	srv := api.start(t)
+
+	n, err := nordigen.New("c2256760-abc0-49a2-968d-b4cb4cf715d0", "88812918b15a")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	if err := n.SetBaseUrl(srv.URL); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	s, err := newSyncer(n, syncConfig{References: []string{"customer-42"}, Store: t.TempDir()}, &strings.Builder{})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	handler := s.handler()
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+
+	// When/Act
+	health := get("/healthz")
+	notReady := get("/readyz")
+	_ = s.resolve(context.Background())
+	ready := get("/readyz")
+	status := get("/status")
+
+	// Then/Assert
+	if health.Code != http.StatusOK || notReady.Code != http.StatusServiceUnavailable || ready.Code != http.StatusOK {
+		t.Errorf("health and readiness expected, got %d %d %d", health.Code, notReady.Code, ready.Code)
+	}
+
+	if status.Header().Get("Content-Type") != "application/json" || !strings.Contains(status.Body.String(), ID.String()) {
+		t.Errorf("status of the account expected, got %s", status.Body.String())
+	}
+}
+
+func TestSyncer_resolveUnlinked(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	ID := addTestAccount(api)
+	requisitionID := addTestRequisition(api, "customer-42", nordigen.RequisitionStatusLinked, ID)
+	srv := api.start(t)
+
+	n, err := nordigen.New("c2256760-abc0-49a2-968d-b4cb4cf715d0", "88812918b15a")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	if err := n.SetBaseUrl(srv.URL); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	s, err := newSyncer(n, syncConfig{References: []string{"customer-42"}, Store: t.TempDir()}, &strings.Builder{})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if err := s.resolve(context.Background()); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	s.state.Accounts[ID].record(s.now(), defaultDailyLimit, nil, false)
+
+	// When/Act
+	api.requisitions[requisitionID].Status = nordigen.RequisitionStatusExpired
+	unlinkedErr := s.resolve(context.Background())
+	unlinked := *s.state.Accounts[ID]
+	due := s.due()
+
+	api.requisitions[requisitionID].Status = nordigen.RequisitionStatusLinked
+	relinkedErr := s.resolve(context.Background())
+
+	// Then/Assert
+	if unlinkedErr != nil || relinkedErr != nil {
+		t.Fatalf("unexpected error occurred: %v %v", unlinkedErr, relinkedErr)
+	}
+
+	if !unlinked.Unlinked || unlinked.Calls != 1 || len(due) != 0 {
+		t.Errorf("the unlinked account expected to keep its calls without being refreshed, got %+v %v", unlinked, due)
+	}
+
+	if st := s.state.Accounts[ID]; st.Unlinked || st.Calls != 1 {
+		t.Errorf("the calls of the day expected to count after linking again, got %+v", st)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"gromson/nordigen"
+)
+
+const envRefreshToken = "NORDIGEN_REFRESH_TOKEN"
+
+func init() {
+	register(&command{
+		name:        "token",
+		description: "issue and refresh the access tokens",
+		subcommands: []subcommand{
+			{name: "new", usage: "", run: tokenNew},
+			{name: "refresh", usage: "[-refresh TOKEN]", run: tokenRefresh},
+		},
+	})
+}
+
+func tokenNew(a *app, args []string) error {
+	if _, err := parseArgs(a.newFlagSet("token new", ""), args, 0); err != nil {
+		return err
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := n.NewToken()
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(tokens, tokensTable(tokens))
+}
+
+func tokenRefresh(a *app, args []string) error {
+	fs := a.newFlagSet("token refresh", "[-refresh TOKEN]")
+	refresh := fs.String("refresh", "", "refresh token, "+envRefreshToken+" if empty")
+	if _, err := parseArgs(fs, args, 0); err != nil {
+		return err
+	}
+
+	if *refresh == "" {
+		*refresh = a.getenv(envRefreshToken)
+	}
+
+	if *refresh == "" {
+		fs.Usage()
+		return errUsage
+	}
+
+	n, err := a.nordigen()
+	if err != nil {
+		return err
+	}
+
+	tokens, err := n.RefreshAccessTokenWith(*refresh)
+	if err != nil {
+		return err
+	}
+
+	return a.out.print(tokens, tokensTable(tokens))
+}
+
+func tokensTable(tokens *nordigen.Tokens) *table {
+	t := &table{header: []string{"TOKEN", "VALUE", "EXPIRES"}}
+	t.add("access", tokens.Access, formatTime(tokens.AccessExpires))
+	t.add("refresh", tokens.Refresh, formatTime(tokens.RefreshExpires))
+
+	return t
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"gromson/nordigen"
+)
+
+func TestTokenCommand(t *testing.T) {
+	// What/Arrange
+	srv := newFakeApi().start(t)
+
+	// When/Act
+	issued, _, newCode := runTest(t, srv, nil, "-format", "json", "token", "new")
+	refreshed, _, refreshCode := runTest(t, srv, map[string]string{envRefreshToken: "refresh-token"}, "-format", "json", "token", "refresh")
+	_, _, missingCode := runTest(t, srv, nil, "token", "refresh")
+
+	// Then/Assert
+	tokens := &nordigen.Tokens{}
+	if err := json.Unmarshal([]byte(issued), tokens); err != nil || newCode != 0 || tokens.Access != "access-token" || tokens.Refresh != "refresh-token" {
+		t.Errorf("issued tokens expected, got %d %s", newCode, issued)
+	}
+
+	tokens = &nordigen.Tokens{}
+	if err := json.Unmarshal([]byte(refreshed), tokens); err != nil || refreshCode != 0 || tokens.Access != "access-token" || !tokens.RefreshExpires.IsZero() {
+		t.Errorf("refreshed access token expected, got %d %s", refreshCode, refreshed)
+	}
+
+	if missingCode != 2 {
+		t.Errorf("usage error expected without a refresh token, got %d", missingCode)
+	}
+}
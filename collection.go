@@ -1,6 +1,7 @@
 package nordigen
 
 import (
+	"context"
 	"net/http"
 	"net/url"
 	"strconv"
@@ -131,3 +132,24 @@ func (c *CollectionResponse[Response]) exec() error {
 
 	return nil
 }
+
+// All reads the remaining items of the collection, checking the context between the pages
+func (c *CollectionResponse[Response]) All(ctx context.Context) ([]Response, error) {
+	items := make([]Response, 0, c.Count())
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		item, err := c.Next()
+		if err != nil {
+			return nil, err
+		}
+
+		if item == nil {
+			return items, nil
+		}
+
+		items = append(items, *item)
+	}
+}
@@ -0,0 +1,331 @@
+package nordigen
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Consent the access to an institution granted by the end user with a linked requisition and its agreement
+type Consent struct {
+	Requisition *RequisitionResponse
+	// Agreement nil if the requisition uses the default agreement or the agreement could not be found
+	Agreement *EndUserAgreementResponse
+	// Expires the time the access expires at. Zero if unknown, i.e. the agreement is missing or not accepted
+	Expires time.Time
+}
+
+func newConsent(requisition *RequisitionResponse, agreement *EndUserAgreementResponse) *Consent {
+	c := &Consent{Requisition: requisition, Agreement: agreement}
+	if agreement != nil {
+		c.Expires, _ = AgreementExpiry(agreement)
+	}
+
+	return c
+}
+
+// ConsentRenewal a new agreement and requisition replacing the consent before it expires
+type ConsentRenewal struct {
+	Previous    *Consent
+	Agreement   *EndUserAgreementResponse
+	Requisition *RequisitionResponse
+}
+
+// Link returns the link to send to the end user to grant the access again
+func (r *ConsentRenewal) Link() string {
+	return r.Requisition.Link
+}
+
+// RenewOptions overrides of the previous requisition's values for the renewal
+type RenewOptions struct {
+	// Redirect the previous requisition's redirect URL if empty
+	Redirect string
+	// Reference of the new requisition. The API requires unique references, a new one is generated if empty:
+	// signed by Signer for the subject of the previous reference, or the previous reference with
+	// a random renewalSuffix without a signer, so it still starts with the previous one
+	Reference string
+	// Signer generates the reference if set
+	Signer *ReferenceSigner
+	// UserLanguage the previous requisition's language if empty
+	UserLanguage string
+}
+
+// ConsentRegistry tracks the expiry of the linked requisitions and renews them. Safe for concurrent use
+type ConsentRegistry struct {
+	client   *Nordigen
+	mu       sync.RWMutex
+	consents map[uuid.UUID]*Consent
+	now      func() time.Time
+}
+
+// NewConsentRegistry creates an empty registry, fill it with Refresh or Add
+func NewConsentRegistry(n *Nordigen) *ConsentRegistry {
+	return &ConsentRegistry{
+		client:   n,
+		consents: make(map[uuid.UUID]*Consent),
+		now:      time.Now,
+	}
+}
+
+// Refresh replaces the registry's consents with all the linked requisitions of the API and their agreements
+func (r *ConsentRegistry) Refresh(ctx context.Context) error {
+	agreementList, err := r.client.EndUserAgreement().List()
+	if err != nil {
+		return errors.Wrap(err, "error listing agreements")
+	}
+
+	agreements, err := agreementList.All(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error listing agreements")
+	}
+
+	requisitionList, err := r.client.Requisition().List()
+	if err != nil {
+		return errors.Wrap(err, "error listing requisitions")
+	}
+
+	requisitions, err := requisitionList.All(ctx)
+	if err != nil {
+		return errors.Wrap(err, "error listing requisitions")
+	}
+
+	agreementsByID := make(map[uuid.UUID]*EndUserAgreementResponse, len(agreements))
+	for i := range agreements {
+		agreementsByID[agreements[i].ID] = &agreements[i]
+	}
+
+	consents := make(map[uuid.UUID]*Consent)
+	for i := range requisitions {
+		requisition := &requisitions[i]
+		if requisition.Status != RequisitionStatusLinked {
+			continue
+		}
+
+		consents[requisition.ID] = newConsent(requisition, agreementsByID[requisition.AgreementID])
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.consents = consents
+
+	return nil
+}
+
+// Add registers the requisition and its agreement, the agreement is optional
+func (r *ConsentRegistry) Add(requisition *RequisitionResponse, agreement *EndUserAgreementResponse) *Consent {
+	c := newConsent(requisition, agreement)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.consents[requisition.ID] = c
+
+	return c
+}
+
+// Remove the consent of the requisition from the registry
+func (r *ConsentRegistry) Remove(requisitionID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.consents, requisitionID)
+}
+
+// Get returns the consent of the requisition and false if it's not registered
+func (r *ConsentRegistry) Get(requisitionID uuid.UUID) (*Consent, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	c, ok := r.consents[requisitionID]
+
+	return c, ok
+}
+
+// Consents returns all the registered consents, the earliest expiring first and the unknown expiry last
+func (r *ConsentRegistry) Consents() []*Consent {
+	return r.filter(func(*Consent) bool { return true })
+}
+
+// Expiring returns the consents expiring within the given number of days, including the expired ones,
+// the earliest expiring first. The consents with unknown expiry are not included
+func (r *ConsentRegistry) Expiring(days int) []*Consent {
+	deadline := r.now().AddDate(0, 0, days)
+
+	return r.filter(func(c *Consent) bool {
+		return !c.Expires.IsZero() && c.Expires.Before(deadline)
+	})
+}
+
+func (r *ConsentRegistry) filter(predicate func(*Consent) bool) []*Consent {
+	r.mu.RLock()
+	consents := make([]*Consent, 0, len(r.consents))
+	for _, c := range r.consents {
+		if predicate(c) {
+			consents = append(consents, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(consents, func(i, j int) bool {
+		a, b := consents[i].Expires, consents[j].Expires
+		if a.IsZero() != b.IsZero() {
+			return b.IsZero()
+		}
+		if !a.Equal(b) {
+			return a.Before(b)
+		}
+
+		return consents[i].Requisition.ID.String() < consents[j].Requisition.ID.String()
+	})
+
+	return consents
+}
+
+// Renew creates a new agreement with the previous agreement's terms and a new requisition for the same
+// institution with a new reference, see RenewOptions.Reference. The end user must follow
+// ConsentRenewal.Link to grant the access again.
+// The previous requisition must be kept until CanDeletePrevious reports true
+func (r *ConsentRegistry) Renew(ctx context.Context, consent *Consent, opts *RenewOptions) (*ConsentRenewal, error) {
+	if opts == nil {
+		opts = &RenewOptions{}
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	previous := consent.Requisition
+	renewal := &ConsentRenewal{Previous: consent}
+
+	reference, err := renewalReference(previous, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	request := NewRequisitionRequest(previous.InstitutionID, firstNonEmpty(opts.Redirect, previous.RedirectUrl)).
+		WithReference(reference).
+		WithUserLanguage(firstNonEmpty(opts.UserLanguage, previous.UserLanguage)).
+		WithAccountSelection(previous.AccountSelection).
+		WithRedirectImmediate(previous.RedirectImmediate)
+
+	// validated before creating the agreement to not leave it unused
+	if _, err := request.Build(); err != nil {
+		return nil, err
+	}
+
+	if consent.Agreement != nil {
+		agreement, err := r.client.EndUserAgreement().Create(&CreateAgreementRequest{
+			InstitutionID:      previous.InstitutionID,
+			MaxHistoricalDays:  consent.Agreement.MaxHistoricalDays,
+			AccessValidForDays: consent.Agreement.AccessValidForDays,
+			AccessScope:        consent.Agreement.AccessScopes,
+		})
+		if err != nil {
+			return nil, errors.Wrap(err, "error creating agreement")
+		}
+
+		renewal.Agreement = agreement
+		request.WithAgreement(agreement)
+	}
+
+	payload, err := request.Build()
+	if err == nil {
+		renewal.Requisition, err = r.client.Requisition().Create(payload)
+	}
+
+	if err != nil {
+		if renewal.Agreement != nil {
+			// best effort, the unused agreement is left for the clean-up otherwise
+			_ = r.client.EndUserAgreement().Delete(renewal.Agreement.ID)
+		}
+
+		return nil, errors.Wrap(err, "error creating requisition")
+	}
+
+	return renewal, nil
+}
+
+// renewalSuffix separates the previous reference from the random part of the renewed one
+const renewalSuffix = "-renewal-"
+
+// renewalReference returns the reference of the renewed requisition
+func renewalReference(previous *RequisitionResponse, opts *RenewOptions) (string, error) {
+	if opts.Reference != "" {
+		return opts.Reference, nil
+	}
+
+	if opts.Signer == nil {
+		// the suffix of an earlier renewal is replaced, so the reference doesn't grow with each renewal
+		base := previous.Reference
+		if i := strings.LastIndex(base, renewalSuffix); i >= 0 {
+			base = base[:i]
+		}
+
+		reference := base + renewalSuffix + strings.ReplaceAll(uuid.NewString(), "-", "")[:12]
+		if len(reference) > maxReferenceLength {
+			return "", errors.Errorf("the renewed reference exceeds %d characters, set RenewOptions.Reference", maxReferenceLength)
+		}
+
+		return reference, nil
+	}
+
+	// the previous reference may be expired or not signed at all
+	subject, err := opts.Signer.Subject(previous.Reference)
+	if err != nil {
+		subject = previous.Reference
+	}
+
+	reference, err := opts.Signer.Sign(subject)
+
+	return reference, errors.Wrap(err, "error signing reference")
+}
+
+// CanDeletePrevious reports whether the previous requisition of the renewal can be deleted, that is
+// the new requisition is linked or the previous access has already expired.
+// Once the new requisition is linked it replaces the previous consent in the registry
+func (r *ConsentRegistry) CanDeletePrevious(ctx context.Context, renewal *ConsentRenewal) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	requisition, err := r.client.Requisition().Get(renewal.Requisition.ID)
+	if err != nil {
+		return false, errors.Wrap(err, "error getting requisition")
+	}
+	renewal.Requisition = requisition
+
+	if requisition.Status == RequisitionStatusLinked {
+		if renewal.Agreement != nil && renewal.Agreement.Accepted == nil {
+			if agreement, err := r.client.EndUserAgreement().Get(renewal.Agreement.ID); err == nil {
+				renewal.Agreement = agreement
+			}
+		}
+
+		r.mu.Lock()
+		delete(r.consents, renewal.Previous.Requisition.ID)
+		r.consents[requisition.ID] = newConsent(requisition, renewal.Agreement)
+		r.mu.Unlock()
+
+		return true, nil
+	}
+
+	expires := renewal.Previous.Expires
+
+	return !expires.IsZero() && !r.now().Before(expires), nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
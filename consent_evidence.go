@@ -0,0 +1,189 @@
+package nordigen
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ConsentEvidence the record of the end user accepting an agreement, kept for compliance audits
+type ConsentEvidence struct {
+	AgreementID        uuid.UUID `json:"agreement_id"`
+	InstitutionID      string    `json:"institution_id"`
+	AccessScopes       []string  `json:"access_scope"`
+	MaxHistoricalDays  int       `json:"max_historical_days"`
+	AccessValidForDays int       `json:"access_valid_for_days"`
+	IPAddress          string    `json:"ip_address"`
+	UserAgent          string    `json:"user_agent"`
+	// AgreementCreated the time the agreement has been created
+	AgreementCreated time.Time `json:"agreement_created"`
+	// Accepted the acceptance time reported by the API
+	Accepted *time.Time `json:"accepted,omitempty"`
+	// Recorded the time the evidence has been recorded
+	Recorded time.Time `json:"recorded"`
+}
+
+// NewConsentEvidence records the acceptance of the agreement with the given request
+func NewConsentEvidence(agreement *EndUserAgreementResponse, request *AcceptEndUserAgreementRequest) *ConsentEvidence {
+	evidence := &ConsentEvidence{
+		AgreementID:        agreement.ID,
+		InstitutionID:      agreement.InstitutionID,
+		AccessScopes:       agreement.AccessScopes,
+		MaxHistoricalDays:  agreement.MaxHistoricalDays,
+		AccessValidForDays: agreement.AccessValidForDays,
+		UserAgent:          request.UserAgent,
+		AgreementCreated:   agreement.Created,
+		Accepted:           agreement.Accepted,
+		Recorded:           time.Now().UTC(),
+	}
+
+	if request.IPAddress != nil {
+		evidence.IPAddress = request.IPAddress.String()
+	}
+
+	return evidence
+}
+
+// ConsentEvidenceStore persists the consent evidence
+type ConsentEvidenceStore interface {
+	// SaveConsentEvidence creates or replaces the evidence of the same agreement
+	SaveConsentEvidence(ctx context.Context, evidence *ConsentEvidence) error
+	// ListConsentEvidence returns all the evidence
+	ListConsentEvidence(ctx context.Context) ([]*ConsentEvidence, error)
+}
+
+// MemoryConsentEvidenceStore keeps the consent evidence in memory. Safe for concurrent use
+type MemoryConsentEvidenceStore struct {
+	mu       sync.RWMutex
+	evidence map[uuid.UUID]ConsentEvidence
+}
+
+// NewMemoryConsentEvidenceStore creates an empty in-memory store
+func NewMemoryConsentEvidenceStore() *MemoryConsentEvidenceStore {
+	return &MemoryConsentEvidenceStore{
+		evidence: make(map[uuid.UUID]ConsentEvidence),
+	}
+}
+
+// SaveConsentEvidence stores a copy of the evidence
+func (s *MemoryConsentEvidenceStore) SaveConsentEvidence(_ context.Context, evidence *ConsentEvidence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.evidence[evidence.AgreementID] = *evidence
+
+	return nil
+}
+
+// ListConsentEvidence returns copies of all the evidence in the order of recording
+func (s *MemoryConsentEvidenceStore) ListConsentEvidence(_ context.Context) ([]*ConsentEvidence, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]*ConsentEvidence, 0, len(s.evidence))
+	for _, evidence := range s.evidence {
+		evidence := evidence
+		list = append(list, &evidence)
+	}
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Recorded.Before(list[j].Recorded) })
+
+	return list, nil
+}
+
+// AcceptFromRequest accepts the agreement on behalf of the end user sending the HTTP request
+// and saves the consent evidence to the store if given.
+// If saving fails the accepted agreement and the evidence are returned along with the error
+func (r *EndUserAgreementResource) AcceptFromRequest(
+	ctx context.Context,
+	ID uuid.UUID,
+	req *http.Request,
+	trusted TrustedProxies,
+	store ConsentEvidenceStore,
+) (*EndUserAgreementResponse, *ConsentEvidence, error) {
+	payload, err := NewAcceptRequest(req, trusted)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	agreement, err := r.Accept(ID, payload)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	evidence := NewConsentEvidence(agreement, payload)
+	if store != nil {
+		if err := store.SaveConsentEvidence(ctx, evidence); err != nil {
+			return agreement, evidence, errors.Wrap(err, "error saving consent evidence")
+		}
+	}
+
+	return agreement, evidence, nil
+}
+
+var consentEvidenceCSVHeader = []string{
+	"agreement_id",
+	"institution_id",
+	"access_scope",
+	"max_historical_days",
+	"access_valid_for_days",
+	"ip_address",
+	"user_agent",
+	"agreement_created",
+	"accepted",
+	"recorded",
+}
+
+// WriteConsentEvidenceCSV exports the evidence as CSV with a header row, the times in RFC 3339.
+// The text cells starting with a formula character are prefixed with a single quote,
+// so spreadsheets don't evaluate the end user's input, e.g. the User-Agent header
+func WriteConsentEvidenceCSV(w io.Writer, evidence []*ConsentEvidence) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(consentEvidenceCSVHeader); err != nil {
+		return err
+	}
+
+	for _, e := range evidence {
+		accepted := ""
+		if e.Accepted != nil {
+			accepted = e.Accepted.UTC().Format(time.RFC3339)
+		}
+
+		if err := cw.Write([]string{
+			e.AgreementID.String(),
+			escapeCSVFormula(e.InstitutionID),
+			escapeCSVFormula(strings.Join(e.AccessScopes, " ")),
+			strconv.Itoa(e.MaxHistoricalDays),
+			strconv.Itoa(e.AccessValidForDays),
+			escapeCSVFormula(e.IPAddress),
+			escapeCSVFormula(e.UserAgent),
+			e.AgreementCreated.UTC().Format(time.RFC3339),
+			accepted,
+			e.Recorded.UTC().Format(time.RFC3339),
+		}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
+
+// escapeCSVFormula prefixes the cell with a single quote if a spreadsheet would evaluate it as a formula
+func escapeCSVFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
@@ -0,0 +1,98 @@
+package nordigen
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndUserAgreementResource_AcceptFromRequest(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	agreement := api.addAgreement(&EndUserAgreementResponse{
+		InstitutionID:      "TEST_INSTITUTION",
+		MaxHistoricalDays:  90,
+		AccessValidForDays: 30,
+		AccessScopes:       []string{AccessScopeBalances, AccessScopeTransactions},
+	})
+
+	trusted, _ := ParseTrustedProxies("10.0.0.0/8")
+	r := httptest.NewRequest("POST", "/consent", nil)
+	r.RemoteAddr = "10.0.0.2:5123"
+	r.Header.Set("X-Forwarded-For", "198.51.100.1")
+	r.Header.Set("User-Agent", "Mozilla/5.0")
+
+	store := NewMemoryConsentEvidenceStore()
+
+	underTest := createTestNordigen(srv).EndUserAgreement()
+
+	// When/Act
+	accepted, evidence, err := underTest.AcceptFromRequest(context.Background(), agreement.ID, r, trusted, store)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if accepted.Accepted == nil || evidence.Accepted == nil || !evidence.Accepted.Equal(*accepted.Accepted) {
+		t.Fatal("acceptance time of the API expected in the evidence")
+	}
+
+	if evidence.IPAddress != "198.51.100.1" || evidence.UserAgent != "Mozilla/5.0" ||
+		evidence.InstitutionID != "TEST_INSTITUTION" || len(evidence.AccessScopes) != 2 {
+		t.Fatalf("evidence expected with the end user's address and the agreement's terms, %v recorded", evidence)
+	}
+
+	list, _ := store.ListConsentEvidence(context.Background())
+	if len(list) != 1 || list[0].AgreementID != agreement.ID {
+		t.Fatalf("evidence expected to be saved, %v saved", list)
+	}
+
+	out := &bytes.Buffer{}
+	if err := WriteConsentEvidenceCSV(out, list); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil || len(rows) != 2 || rows[1][2] != "balances transactions" || rows[1][5] != "198.51.100.1" {
+		t.Fatalf("header and evidence rows expected in the CSV, %v exported", rows)
+	}
+}
+
+func TestWriteConsentEvidenceCSV(t *testing.T) {
+	// What/Arrange
+	evidence := []*ConsentEvidence{{
+		InstitutionID: "TEST_INSTITUTION",
+		IPAddress:     "198.51.100.1",
+		UserAgent:     "=HYPERLINK(\"https://example.com\")",
+	}, {
+		UserAgent: "@SUM(1+1)",
+	}}
+	out := &bytes.Buffer{}
+
+	// When/Act
+	err := WriteConsentEvidenceCSV(out, evidence)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	rows, err := csv.NewReader(out).ReadAll()
+	if err != nil || len(rows) != 3 {
+		t.Fatalf("header and evidence rows expected in the CSV, %v exported", rows)
+	}
+
+	if rows[1][6] != "'=HYPERLINK(\"https://example.com\")" || rows[2][6] != "'@SUM(1+1)" {
+		t.Fatalf("formulas expected to be escaped, %q and %q exported", rows[1][6], rows[2][6])
+	}
+
+	if rows[1][1] != "TEST_INSTITUTION" || rows[1][5] != "198.51.100.1" {
+		t.Fatalf("plain cells expected unchanged, %v exported", rows[1])
+	}
+}
@@ -0,0 +1,238 @@
+package nordigen
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestConsentRegistry(t *testing.T) {
+	t.Parallel()
+	t.Run("consent registry expiring", testConsentRegistryExpiring)
+	t.Run("consent registry renewal", testConsentRegistryRenewal)
+	t.Run("consent registry renewal of expired consent", testConsentRegistryRenewalExpired)
+	t.Run("consent registry renewal with signed reference", testConsentRegistryRenewalSigned)
+}
+
+func addTestConsent(api *fakeApi, acceptedDaysAgo int, status string) *RequisitionResponse {
+	accepted := time.Now().AddDate(0, 0, -acceptedDaysAgo)
+	agreement := api.addAgreement(&EndUserAgreementResponse{
+		MaxHistoricalDays:  180,
+		AccessValidForDays: 90,
+		AccessScopes:       []string{AccessScopeBalances, AccessScopeTransactions},
+		Accepted:           &accepted,
+		InstitutionID:      "TEST_INSTITUTION",
+	})
+
+	return api.addRequisition(&RequisitionResponse{
+		Status:        status,
+		InstitutionID: "TEST_INSTITUTION",
+		AgreementID:   agreement.ID,
+		RedirectUrl:   "https://example.com/callback",
+		Reference:     "user-" + uuid.NewString(),
+		Accounts:      []uuid.UUID{uuid.New()},
+	})
+}
+
+func testConsentRegistryExpiring(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	expiring := addTestConsent(api, 85, RequisitionStatusLinked)
+	addTestConsent(api, 10, RequisitionStatusLinked)
+	addTestConsent(api, 85, RequisitionStatusCreated)
+	api.addRequisition(&RequisitionResponse{Status: RequisitionStatusLinked, InstitutionID: "TEST_INSTITUTION"})
+
+	underTest := NewConsentRegistry(createTestNordigen(srv))
+
+	// When/Act
+	err := underTest.Refresh(context.Background())
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	consents := underTest.Consents()
+	if len(consents) != 3 {
+		t.Fatalf("3 linked requisitions expected to be registered, %d registered", len(consents))
+	}
+
+	if !consents[2].Expires.IsZero() || consents[2].Agreement != nil {
+		t.Fatal("requisition with the default agreement expected last with unknown expiry")
+	}
+
+	soon := underTest.Expiring(7)
+	if len(soon) != 1 || soon[0].Requisition.ID != expiring.ID {
+		t.Fatalf("only the requisition accepted 85 days ago expected to expire within 7 days, %v returned", soon)
+	}
+
+	if days := time.Until(soon[0].Expires).Hours() / 24; days < 4 || days > 5 {
+		t.Fatalf("consent expected to expire in 5 days, expires in %.1f days", days)
+	}
+}
+
+func testConsentRegistryRenewal(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	previous := addTestConsent(api, 85, RequisitionStatusLinked)
+
+	underTest := NewConsentRegistry(createTestNordigen(srv))
+	if err := underTest.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	consent, _ := underTest.Get(previous.ID)
+
+	// When/Act
+	renewal, err := underTest.Renew(context.Background(), consent, nil)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	beforeLinked, err := underTest.CanDeletePrevious(context.Background(), renewal)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	api.setRequisitionStatus(renewal.Requisition.ID, RequisitionStatusLinked, uuid.New())
+	afterLinked, err := underTest.CanDeletePrevious(context.Background(), renewal)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if renewal.Link() == "" || renewal.Requisition.ID == previous.ID {
+		t.Fatal("new requisition with a link expected")
+	}
+
+	if renewal.Requisition.Reference == previous.Reference ||
+		!strings.HasPrefix(renewal.Requisition.Reference, previous.Reference+renewalSuffix) ||
+		renewal.Requisition.InstitutionID != previous.InstitutionID {
+		t.Fatalf("new requisition expected for the same institution with a reference derived from the previous one, %v created", renewal.Requisition)
+	}
+
+	if renewal.Agreement.ID == consent.Agreement.ID ||
+		renewal.Agreement.MaxHistoricalDays != 180 ||
+		len(renewal.Agreement.AccessScopes) != 2 ||
+		renewal.Requisition.AgreementID != renewal.Agreement.ID {
+		t.Fatalf("new agreement expected with the previous terms, %v created", renewal.Agreement)
+	}
+
+	if beforeLinked || !afterLinked {
+		t.Fatalf("previous requisition expected to be deletable only after linking the new one, %t and %t reported", beforeLinked, afterLinked)
+	}
+
+	if _, ok := underTest.Get(previous.ID); ok {
+		t.Fatal("previous consent expected to be replaced in the registry")
+	}
+
+	if _, ok := underTest.Get(renewal.Requisition.ID); !ok {
+		t.Fatal("new consent expected to be registered")
+	}
+}
+
+func testConsentRegistryRenewalExpired(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	previous := addTestConsent(api, 100, RequisitionStatusLinked)
+
+	underTest := NewConsentRegistry(createTestNordigen(srv))
+	if err := underTest.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	consent, _ := underTest.Get(previous.ID)
+
+	renewal, err := underTest.Renew(context.Background(), consent, &RenewOptions{Reference: "renewed"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	deletable, err := underTest.CanDeletePrevious(context.Background(), renewal)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if !deletable {
+		t.Fatal("previous requisition expected to be deletable after its access has expired")
+	}
+
+	if renewal.Requisition.Reference != "renewed" {
+		t.Fatalf("reference expected to be overridden, %s set", renewal.Requisition.Reference)
+	}
+}
+
+func testConsentRegistryRenewalSigned(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	signer := NewReferenceSigner(testReferenceKeyNew)
+	signer.now = func() time.Time { return time.Now().AddDate(0, 0, -80) }
+	reference, err := signer.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer.now = nil
+
+	previous := addTestConsent(api, 80, RequisitionStatusLinked)
+	previous.Reference = reference
+
+	underTest := NewConsentRegistry(createTestNordigen(srv))
+	if err := underTest.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	consent, _ := underTest.Get(previous.ID)
+
+	// When/Act
+	renewal, err := underTest.Renew(context.Background(), consent, &RenewOptions{Signer: signer})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	signed, err := signer.Verify(context.Background(), renewal.Requisition.Reference)
+	if err != nil || signed.Subject != "user-42" {
+		t.Fatalf("new reference signed for the previous subject expected, %v returned", err)
+	}
+}
+
+func Test_renewalReference(t *testing.T) {
+	// What/Arrange
+	previous := &RequisitionResponse{Reference: "user-42"}
+
+	// When/Act
+	first, firstErr := renewalReference(previous, &RenewOptions{})
+	second, secondErr := renewalReference(&RequisitionResponse{Reference: first}, &RenewOptions{})
+	_, longErr := renewalReference(&RequisitionResponse{Reference: strings.Repeat("x", maxReferenceLength)}, &RenewOptions{})
+
+	// Then/Assert
+	if firstErr != nil || secondErr != nil {
+		t.Fatalf("unexpected error occurred: %v %v", firstErr, secondErr)
+	}
+
+	if !strings.HasPrefix(first, "user-42"+renewalSuffix) || !strings.HasPrefix(second, "user-42"+renewalSuffix) ||
+		len(second) != len(first) || second == first {
+		t.Errorf("unique references derived from the previous one expected, got %s and %s", first, second)
+	}
+
+	if longErr == nil {
+		t.Error("error expected for a reference exceeding the maximum length")
+	}
+}
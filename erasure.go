@@ -0,0 +1,357 @@
+package nordigen
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrErasureIncomplete returned when some data of the end user could not be erased,
+// call EraseEndUser again to resume
+var ErrErasureIncomplete = errors.New("end user erasure incomplete")
+
+// ErasedResource a requisition or an agreement of the erased end user
+type ErasedResource struct {
+	ID       uuid.UUID  `json:"id"`
+	ErasedAt *time.Time `json:"erased_at,omitempty"`
+	// Error of the last failed attempt
+	Error string `json:"error,omitempty"`
+}
+
+// ErasureReceipt the record of erasing the data of an end user
+type ErasureReceipt struct {
+	UserID       string            `json:"user_id"`
+	Started      time.Time         `json:"started"`
+	Completed    *time.Time        `json:"completed,omitempty"`
+	Attempts     int               `json:"attempts"`
+	Requisitions []*ErasedResource `json:"requisitions"`
+	Agreements   []*ErasedResource `json:"agreements"`
+	AccountIDs   []uuid.UUID       `json:"account_ids"`
+	// LocalDataErasedAt the time the local stores have been purged
+	LocalDataErasedAt *time.Time `json:"local_data_erased_at,omitempty"`
+}
+
+// IsComplete reports whether all the data of the end user has been erased
+func (r *ErasureReceipt) IsComplete() bool {
+	return r.Completed != nil
+}
+
+// RequisitionIDs returns the IDs of the end user's requisitions
+func (r *ErasureReceipt) RequisitionIDs() []uuid.UUID {
+	return erasedIDs(r.Requisitions)
+}
+
+// AgreementIDs returns the IDs of the end user's agreements
+func (r *ErasureReceipt) AgreementIDs() []uuid.UUID {
+	return erasedIDs(r.Agreements)
+}
+
+func erasedIDs(resources []*ErasedResource) []uuid.UUID {
+	IDs := make([]uuid.UUID, 0, len(resources))
+	for _, res := range resources {
+		IDs = append(IDs, res.ID)
+	}
+
+	return IDs
+}
+
+// EndUserDataStore a local store holding the data of the end users, e.g. accounts and transactions
+type EndUserDataStore interface {
+	// EraseEndUserData deletes the data of the receipt's requisitions and accounts. Must be idempotent
+	EraseEndUserData(ctx context.Context, receipt *ErasureReceipt) error
+}
+
+// EraseOptions options of erasing an end user
+type EraseOptions struct {
+	// MatchReference reports whether the requisition reference belongs to the end user.
+	// The reference must be equal to the user ID, or be its renewal see RenewOptions.Reference, if nil
+	MatchReference func(reference string) bool
+	// Store persists the receipt before deleting anything, so an erasure interrupted after deleting
+	// the requisitions still purges their accounts from the local stores when resumed
+	Store ErasureStore
+	// LinkFlows the link flows of the erased requisitions and with the end user's reference are deleted from.
+	// The agreements and requisitions created by the unfinished flows are erased along with them
+	LinkFlows LinkFlowStore
+	// LocalStores purged of the end user's data. The client's ScopeGuard is always purged
+	LocalStores []EndUserDataStore
+	// Limiter throttles the deletions. Deletions are not throttled if nil
+	Limiter RateLimiter
+}
+
+// EraseEndUser deletes the requisitions whose reference belongs to the end user and their agreements
+// through the API and purges the end user's data from the local stores.
+// The operation is idempotent: the resources which don't exist anymore are considered erased
+// and the requisitions created since the previous run are erased too.
+// ErrErasureIncomplete is returned along with the receipt if anything failed, call EraseEndUser again to resume
+func (n *Nordigen) EraseEndUser(ctx context.Context, userID string, opts *EraseOptions) (*ErasureReceipt, error) {
+	if opts == nil {
+		opts = &EraseOptions{}
+	}
+
+	match := opts.MatchReference
+	if match == nil {
+		match = func(reference string) bool {
+			return reference == userID || strings.HasPrefix(reference, userID+renewalSuffix)
+		}
+	}
+
+	receipt, err := n.loadErasure(ctx, userID, opts.Store)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := n.Requisition().List()
+	if err != nil {
+		return receipt, errors.Wrap(err, "error listing requisitions")
+	}
+
+	requisitions, err := list.All(ctx)
+	if err != nil {
+		return receipt, errors.Wrap(err, "error listing requisitions")
+	}
+
+	found := false
+	for i := range requisitions {
+		requisition := &requisitions[i]
+		if match(requisition.Reference) && receipt.addRequisition(requisition) {
+			found = true
+		}
+	}
+
+	if opts.LinkFlows != nil {
+		records, err := opts.LinkFlows.ListLinkFlows(ctx)
+		if err != nil {
+			return receipt, errors.Wrap(err, "error listing link flows")
+		}
+
+		for _, record := range records {
+			if match(record.Reference) && receipt.addLinkFlow(record) {
+				found = true
+			}
+		}
+	}
+
+	if receipt.IsComplete() && !found {
+		return receipt, nil
+	}
+
+	receipt.Completed = nil
+	receipt.Attempts++
+	if err := n.saveErasure(ctx, receipt, opts.Store); err != nil {
+		return receipt, err
+	}
+
+	failed := 0
+	for _, res := range receipt.Requisitions {
+		if !n.eraseResource(ctx, res, opts.Limiter, n.Requisition().Delete) {
+			failed++
+		}
+	}
+
+	for _, res := range receipt.Agreements {
+		if !n.eraseResource(ctx, res, opts.Limiter, n.EndUserAgreement().Delete) {
+			failed++
+		}
+	}
+
+	if err := ctx.Err(); err != nil {
+		_ = n.saveErasure(ctx, receipt, opts.Store)
+		return receipt, err
+	}
+
+	localErr := n.eraseLocalData(ctx, receipt, match, opts)
+	if localErr == nil {
+		now := time.Now()
+		receipt.LocalDataErasedAt = &now
+	}
+
+	if failed == 0 && localErr == nil {
+		now := time.Now()
+		receipt.Completed = &now
+	}
+
+	if err := n.saveErasure(ctx, receipt, opts.Store); err != nil {
+		return receipt, err
+	}
+
+	if localErr != nil {
+		return receipt, errors.Wrap(ErrErasureIncomplete, localErr.Error())
+	}
+
+	if failed > 0 {
+		return receipt, errors.Wrap(ErrErasureIncomplete, strconv.Itoa(failed)+" resources could not be deleted")
+	}
+
+	return receipt, nil
+}
+
+// addRequisition adds the requisition, its agreement and accounts to the receipt.
+// Returns false if the requisition is already in the receipt
+func (r *ErasureReceipt) addRequisition(requisition *RequisitionResponse) bool {
+	if containsErased(r.Requisitions, requisition.ID) {
+		return false
+	}
+
+	r.Requisitions = append(r.Requisitions, &ErasedResource{ID: requisition.ID})
+
+	if requisition.AgreementID != uuid.Nil && !containsErased(r.Agreements, requisition.AgreementID) {
+		r.Agreements = append(r.Agreements, &ErasedResource{ID: requisition.AgreementID})
+	}
+
+	for _, accountID := range requisition.Accounts {
+		known := false
+		for _, ID := range r.AccountIDs {
+			known = known || ID == accountID
+		}
+
+		if !known {
+			r.AccountIDs = append(r.AccountIDs, accountID)
+		}
+	}
+
+	return true
+}
+
+// addLinkFlow adds the agreement and the requisition created by the link flow to the receipt,
+// an unfinished flow may have created the agreement without a requisition using it.
+// Returns false if there is nothing new to erase
+func (r *ErasureReceipt) addLinkFlow(record *LinkFlowRecord) bool {
+	added := false
+	if record.RequisitionID != uuid.Nil && !containsErased(r.Requisitions, record.RequisitionID) {
+		r.Requisitions = append(r.Requisitions, &ErasedResource{ID: record.RequisitionID})
+		added = true
+	}
+
+	if record.AgreementID != uuid.Nil && !containsErased(r.Agreements, record.AgreementID) {
+		r.Agreements = append(r.Agreements, &ErasedResource{ID: record.AgreementID})
+		added = true
+	}
+
+	return added
+}
+
+func containsErased(resources []*ErasedResource, ID uuid.UUID) bool {
+	for _, res := range resources {
+		if res.ID == ID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (n *Nordigen) loadErasure(ctx context.Context, userID string, store ErasureStore) (*ErasureReceipt, error) {
+	if store != nil {
+		receipt, err := store.LoadErasure(ctx, userID)
+		if err == nil {
+			return receipt, nil
+		}
+
+		if !errors.Is(err, ErrErasureNotFound) {
+			return nil, errors.Wrap(err, "error loading erasure receipt")
+		}
+	}
+
+	return &ErasureReceipt{
+		UserID:       userID,
+		Started:      time.Now(),
+		Requisitions: make([]*ErasedResource, 0),
+		Agreements:   make([]*ErasedResource, 0),
+		AccountIDs:   make([]uuid.UUID, 0),
+	}, nil
+}
+
+func (n *Nordigen) saveErasure(ctx context.Context, receipt *ErasureReceipt, store ErasureStore) error {
+	if store == nil {
+		return nil
+	}
+
+	return errors.Wrap(store.SaveErasure(ctx, receipt), "error saving erasure receipt")
+}
+
+// eraseResource deletes the resource unless it has already been erased, a missing resource is considered erased
+func (n *Nordigen) eraseResource(ctx context.Context, res *ErasedResource, limiter RateLimiter, del func(uuid.UUID) error) bool {
+	if res.ErasedAt != nil {
+		return true
+	}
+
+	if err := waitForLimiter(ctx, limiter); err != nil {
+		return false
+	}
+
+	if err := ignoreNotFound(del(res.ID)); err != nil {
+		res.Error = err.Error()
+		return false
+	}
+
+	now := time.Now()
+	res.ErasedAt = &now
+	res.Error = ""
+
+	return true
+}
+
+func (n *Nordigen) eraseLocalData(ctx context.Context, receipt *ErasureReceipt, match func(string) bool, opts *EraseOptions) error {
+	if n.ScopeGuard != nil {
+		n.ScopeGuard.Forget(receipt.AccountIDs...)
+	}
+
+	if opts.LinkFlows != nil {
+		if err := eraseLinkFlows(ctx, opts.LinkFlows, receipt, match); err != nil {
+			return errors.Wrap(err, "error erasing link flows")
+		}
+	}
+
+	for _, store := range opts.LocalStores {
+		if err := store.EraseEndUserData(ctx, receipt); err != nil {
+			return errors.Wrap(err, "error erasing local data")
+		}
+	}
+
+	return nil
+}
+
+// eraseLinkFlows deletes the flows of the receipt's requisitions and the unfinished flows with the end user's reference
+func eraseLinkFlows(ctx context.Context, store LinkFlowStore, receipt *ErasureReceipt, match func(string) bool) error {
+	records, err := store.ListLinkFlows(ctx)
+	if err != nil {
+		return err
+	}
+
+	requisitions := make(map[uuid.UUID]bool, len(receipt.Requisitions))
+	for _, ID := range receipt.RequisitionIDs() {
+		requisitions[ID] = true
+	}
+
+	for _, record := range records {
+		if !requisitions[record.RequisitionID] && !match(record.Reference) {
+			continue
+		}
+
+		if err := store.DeleteLinkFlow(ctx, record.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EraseEndUserData forgets the receipt's accounts
+func (g *ScopeGuard) EraseEndUserData(_ context.Context, receipt *ErasureReceipt) error {
+	g.Forget(receipt.AccountIDs...)
+
+	return nil
+}
+
+// EraseEndUserData removes the consents of the receipt's requisitions
+func (r *ConsentRegistry) EraseEndUserData(_ context.Context, receipt *ErasureReceipt) error {
+	for _, ID := range receipt.RequisitionIDs() {
+		r.Remove(ID)
+	}
+
+	return nil
+}
@@ -0,0 +1,66 @@
+package nordigen
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrErasureNotFound returned by ErasureStore when there is no receipt for the end user
+var ErrErasureNotFound = errors.New("erasure receipt not found")
+
+// ErasureStore persists the erasure receipts, so an interrupted erasure can be resumed
+type ErasureStore interface {
+	// SaveErasure creates or replaces the receipt of the same end user
+	SaveErasure(ctx context.Context, receipt *ErasureReceipt) error
+	// LoadErasure returns the receipt of the end user or ErrErasureNotFound
+	LoadErasure(ctx context.Context, userID string) (*ErasureReceipt, error)
+}
+
+// MemoryErasureStore keeps erasure receipts in memory as JSON. Safe for concurrent use
+type MemoryErasureStore struct {
+	mu       sync.RWMutex
+	receipts map[string][]byte
+}
+
+// NewMemoryErasureStore creates an empty in-memory store
+func NewMemoryErasureStore() *MemoryErasureStore {
+	return &MemoryErasureStore{
+		receipts: make(map[string][]byte),
+	}
+}
+
+// SaveErasure stores a copy of the receipt
+func (s *MemoryErasureStore) SaveErasure(_ context.Context, receipt *ErasureReceipt) error {
+	payload, err := json.Marshal(receipt)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling erasure receipt")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.receipts[receipt.UserID] = payload
+
+	return nil
+}
+
+// LoadErasure returns a copy of the receipt of the end user
+func (s *MemoryErasureStore) LoadErasure(_ context.Context, userID string) (*ErasureReceipt, error) {
+	s.mu.RLock()
+	payload, ok := s.receipts[userID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrErasureNotFound
+	}
+
+	receipt := &ErasureReceipt{}
+	if err := json.Unmarshal(payload, receipt); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling erasure receipt")
+	}
+
+	return receipt, nil
+}
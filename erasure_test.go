@@ -0,0 +1,196 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// failingDataStore fails the first erasures and records the erased accounts
+type failingDataStore struct {
+	failures int
+	accounts []uuid.UUID
+}
+
+func (s *failingDataStore) EraseEndUserData(_ context.Context, receipt *ErasureReceipt) error {
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("store unavailable")
+	}
+
+	s.accounts = receipt.AccountIDs
+
+	return nil
+}
+
+type erasureFixture struct {
+	api         *fakeApi
+	linked      *RequisitionResponse
+	abandoned   *RequisitionResponse
+	otherUser   *RequisitionResponse
+	accountID   uuid.UUID
+	agreementID uuid.UUID
+}
+
+func createErasureFixture() *erasureFixture {
+	f := &erasureFixture{api: newFakeApi(), accountID: uuid.New()}
+
+	f.agreementID = f.api.addAgreement(&EndUserAgreementResponse{InstitutionID: "TEST_INSTITUTION"}).ID
+	f.linked = f.api.addRequisition(&RequisitionResponse{
+		Status:      RequisitionStatusLinked,
+		Reference:   "user-42",
+		AgreementID: f.agreementID,
+		Accounts:    []uuid.UUID{f.accountID},
+	})
+	// a renewal of the linked requisition, see renewalReference
+	f.abandoned = f.api.addRequisition(&RequisitionResponse{Status: RequisitionStatusCreated, Reference: "user-42" + renewalSuffix + "3f2a9c1b7d4e"})
+	f.otherUser = f.api.addRequisition(&RequisitionResponse{Status: RequisitionStatusLinked, Reference: "user-7"})
+
+	return f
+}
+
+func TestNordigen_EraseEndUser(t *testing.T) {
+	t.Parallel()
+	t.Run("erasing end user", testEraseEndUserOk)
+	t.Run("erasing end user resumed", testEraseEndUserResumed)
+	t.Run("erasing unfinished link flow", testEraseEndUserUnfinishedFlow)
+}
+
+func testEraseEndUserOk(t *testing.T) {
+	// What/Arrange
+	f := createErasureFixture()
+	srv := f.api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	client.ScopeGuard = NewScopeGuard()
+	client.ScopeGuard.Remember(&EndUserAgreementResponse{ID: f.agreementID}, f.accountID)
+
+	flows := NewMemoryLinkFlowStore()
+	_ = flows.SaveLinkFlow(context.Background(), &LinkFlowRecord{
+		LinkFlowParams: LinkFlowParams{ID: "flow-1", Reference: "user-42"},
+		RequisitionID:  f.linked.ID,
+	})
+	_ = flows.SaveLinkFlow(context.Background(), &LinkFlowRecord{
+		LinkFlowParams: LinkFlowParams{ID: "flow-2", Reference: "user-7"},
+		RequisitionID:  f.otherUser.ID,
+	})
+
+	opts := &EraseOptions{Store: NewMemoryErasureStore(), LinkFlows: flows}
+
+	// When/Act
+	receipt, err := client.EraseEndUser(context.Background(), "user-42", opts)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	again, againErr := client.EraseEndUser(context.Background(), "user-42", opts)
+
+	// Then/Assert
+	if againErr != nil {
+		t.Fatalf("unexpected error occurred on the repeated erasure: %s", againErr)
+	}
+
+	if !receipt.IsComplete() || receipt.LocalDataErasedAt == nil {
+		t.Fatal("erasure expected to be complete")
+	}
+
+	if len(receipt.Requisitions) != 2 || len(receipt.Agreements) != 1 || len(receipt.AccountIDs) != 1 {
+		t.Fatalf("2 requisitions, 1 agreement and 1 account expected in the receipt, %v returned", receipt)
+	}
+
+	if len(f.api.deleted) != 3 {
+		t.Fatalf("3 resources expected to be deleted once, %v deleted", f.api.deleted)
+	}
+
+	if _, ok := f.api.requisitions[f.otherUser.ID]; !ok {
+		t.Fatal("requisition of the other user expected to be kept")
+	}
+
+	if _, ok := client.ScopeGuard.Grant(f.accountID); ok {
+		t.Fatal("account expected to be removed from the scope guard")
+	}
+
+	if records, _ := flows.ListLinkFlows(context.Background()); len(records) != 1 || records[0].ID != "flow-2" {
+		t.Fatalf("only the link flow of the other user expected to be kept, %v kept", records)
+	}
+
+	if again.Attempts != 1 || !again.IsComplete() {
+		t.Fatalf("repeated erasure expected to return the completed receipt, %v returned", again)
+	}
+}
+
+func testEraseEndUserResumed(t *testing.T) {
+	// What/Arrange
+	f := createErasureFixture()
+	srv := f.api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	local := &failingDataStore{failures: 1}
+	opts := &EraseOptions{Store: NewMemoryErasureStore(), LocalStores: []EndUserDataStore{local}}
+
+	// When/Act
+	interrupted, interruptedErr := client.EraseEndUser(context.Background(), "user-42", opts)
+	resumed, err := client.EraseEndUser(context.Background(), "user-42", opts)
+
+	// Then/Assert
+	if !errors.Is(interruptedErr, ErrErasureIncomplete) || interrupted.IsComplete() {
+		t.Fatalf("ErrErasureIncomplete expected after the local store failure, %v returned", interruptedErr)
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if !resumed.IsComplete() || resumed.Attempts != 2 {
+		t.Fatalf("resumed erasure expected to complete on the second attempt, %v returned", resumed)
+	}
+
+	if len(local.accounts) != 1 || local.accounts[0] != f.accountID {
+		t.Fatalf("accounts of the already deleted requisitions expected to be erased locally, %v erased", local.accounts)
+	}
+
+	if len(f.api.deleted) != 3 {
+		t.Fatalf("3 resources expected to be deleted once, %v deleted", f.api.deleted)
+	}
+}
+
+func testEraseEndUserUnfinishedFlow(t *testing.T) {
+	// What/Arrange
+	f := createErasureFixture()
+	srv := f.api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	orphan := f.api.addAgreement(&EndUserAgreementResponse{InstitutionID: "TEST_INSTITUTION"})
+
+	flows := NewMemoryLinkFlowStore()
+	_ = flows.SaveLinkFlow(context.Background(), &LinkFlowRecord{
+		LinkFlowParams: LinkFlowParams{ID: "flow-1", Reference: "user-42"},
+		AgreementID:    orphan.ID,
+		RequisitionID:  uuid.New(),
+	})
+
+	// When/Act
+	receipt, err := client.EraseEndUser(context.Background(), "user-42", &EraseOptions{LinkFlows: flows})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(receipt.Requisitions) != 3 || len(receipt.Agreements) != 2 || !receipt.IsComplete() {
+		t.Fatalf("the agreement and the requisition of the flow expected in the receipt, %v returned", receipt)
+	}
+
+	if _, ok := f.api.agreements[orphan.ID]; ok {
+		t.Fatal("agreement of the unfinished flow expected to be deleted")
+	}
+
+	if records, _ := flows.ListLinkFlows(context.Background()); len(records) != 0 {
+		t.Fatalf("link flow expected to be deleted, %v kept", records)
+	}
+}
@@ -2,11 +2,13 @@ package nordigen
 
 import (
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"github.com/pkg/errors"
+	"gromson/nordigen/rest"
 )
 
 // ApiClientErrorResponse represents the 400 and 404 error responses from the API
@@ -112,3 +114,32 @@ func createApiError(res *http.Response, ierr error) error {
 
 	return ierr
 }
+
+// isApiErrorStatus reports whether the error is rest.ApiError with the given HTTP status code
+func isApiErrorStatus(err error, statusCode int) bool {
+	var apiErr *rest.ApiError
+
+	return errors.As(err, &apiErr) && apiErr.StatusCode() == statusCode
+}
+
+// isRetryableError reports whether the request failed because of rate limiting, a server or a network error
+func isRetryableError(err error) bool {
+	var apiErr *rest.ApiError
+	if !errors.As(err, &apiErr) {
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+
+	code := apiErr.StatusCode()
+
+	return code == http.StatusTooManyRequests || code >= http.StatusInternalServerError
+}
+
+// ignoreNotFound returns nil if the error is a 404 API error, e.g. when deleting already deleted resources
+func ignoreNotFound(err error) error {
+	if isApiErrorStatus(err, http.StatusNotFound) {
+		return nil
+	}
+
+	return err
+}
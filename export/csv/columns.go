@@ -0,0 +1,210 @@
+package csv
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+const (
+	// FieldStatus the pseudo-field of the transaction's status: booked, pending or information
+	FieldStatus = "Status"
+)
+
+var (
+	transactionType = reflect.TypeOf(nordigen.TransactionResponse{})
+	amountType      = reflect.TypeOf(nordigen.Amount{})
+	uuidType        = reflect.TypeOf(uuid.UUID{})
+	stringsType     = reflect.TypeOf([]string{})
+	timeType        = reflect.TypeOf(time.Time{})
+)
+
+// Column a column of the CSV
+type Column struct {
+	// Header of the column, the Field if empty
+	Header string
+	// Field the path of the transaction's field, e.g. "BookingDate" or "CreditorAccount.Iban".
+	// The Go and the JSON names are accepted, case-insensitive. "Status" is the transaction's status
+	Field string
+	// Value computes the value of the column instead of the Field if set. The value is written as is
+	Value func(t *nordigen.TransactionResponse, status Status) string
+}
+
+// DefaultColumns the columns written if none are configured
+var DefaultColumns = []Column{
+	{Header: "status", Field: FieldStatus},
+	{Header: "booking_date", Field: "BookingDate"},
+	{Header: "value_date", Field: "ValueDate"},
+	{Header: "amount", Field: "Amount"},
+	{Header: "currency", Field: "Amount.Currency"},
+	{Header: "creditor_name", Field: "CreditorName"},
+	{Header: "creditor_iban", Field: "CreditorAccount.Iban"},
+	{Header: "debtor_name", Field: "DebtorName"},
+	{Header: "debtor_iban", Field: "DebtorAccount.Iban"},
+	{Header: "description", Field: "RemittanceInformationUnstructured"},
+	{Header: "transaction_id", Field: "ID"},
+}
+
+// ParseColumns parses the comma-separated list of the columns in the "Field" or "Field:Header" form,
+// e.g. "BookingDate:Date,Amount,CreditorAccount.Iban:IBAN"
+func ParseColumns(value string) ([]Column, error) {
+	columns := make([]Column, 0)
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		column := Column{Field: item}
+		if i := strings.IndexByte(item, ':'); i >= 0 {
+			column.Field, column.Header = strings.TrimSpace(item[:i]), strings.TrimSpace(item[i+1:])
+		}
+
+		if _, err := resolveField(column.Field); err != nil {
+			return nil, err
+		}
+
+		columns = append(columns, column)
+	}
+
+	if len(columns) == 0 {
+		return nil, errors.New("no columns")
+	}
+
+	return columns, nil
+}
+
+// field a resolved path of a transaction's field
+type field struct {
+	// index of the struct field at each level of the path, empty for the status
+	index []int
+	// leaf the type of the field
+	leaf reflect.Type
+	// name of the last field of the path
+	name string
+}
+
+// resolveField resolves the path against TransactionResponse
+func resolveField(path string) (*field, error) {
+	if strings.EqualFold(path, FieldStatus) {
+		return &field{name: FieldStatus}, nil
+	}
+
+	f := &field{}
+	typ := transactionType
+	for _, name := range strings.Split(path, ".") {
+		for typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+
+		if typ.Kind() != reflect.Struct {
+			return nil, errors.Errorf("unknown field %q", path)
+		}
+
+		sf, ok := structField(typ, name)
+		if !ok {
+			return nil, errors.Errorf("unknown field %q", path)
+		}
+
+		f.index = append(f.index, sf.Index[0])
+		f.name = sf.Name
+		typ = sf.Type
+	}
+
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	if typ.Kind() == reflect.Struct && typ != amountType && typ != timeType {
+		return nil, errors.Errorf("field %q is a structure, choose one of its fields", path)
+	}
+
+	f.leaf = typ
+
+	return f, nil
+}
+
+// structField finds the field by its Go or JSON name
+func structField(typ reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		jsonName := strings.Split(sf.Tag.Get("json"), ",")[0]
+		if strings.EqualFold(sf.Name, name) || jsonName != "" && strings.EqualFold(jsonName, name) {
+			return sf, true
+		}
+	}
+
+	return reflect.StructField{}, false
+}
+
+// isAmount reports whether the field is the amount of an Amount, written in the configured amount mode
+func (f *field) isAmount() bool {
+	return f.leaf == amountType || f.name == "Amount" && f.leaf.Kind() == reflect.String
+}
+
+// isText true for the free text fields, which are escaped against formula injection
+func (f *field) isText() bool {
+	return f.leaf == stringsType || f.leaf.Kind() == reflect.String && !strings.HasSuffix(f.name, "Date")
+}
+
+// value returns the field of the transaction, nil if a pointer on the path is nil
+func (f *field) value(t *nordigen.TransactionResponse) (reflect.Value, bool) {
+	v := reflect.ValueOf(t).Elem()
+	for _, i := range f.index {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}, false
+		}
+		v = v.Elem()
+	}
+
+	return v, true
+}
+
+// format formats the non-amount value in the locale
+func (f *field) format(v reflect.Value, locale *Locale) string {
+	switch {
+	case f.leaf == uuidType:
+		if ID := v.Interface().(uuid.UUID); ID != uuid.Nil {
+			return ID.String()
+		}
+		return ""
+	case f.leaf == stringsType:
+		return strings.Join(v.Interface().([]string), " ")
+	case f.leaf.Kind() == reflect.String && strings.HasSuffix(f.name, "Date"):
+		return formatDate(v.String(), locale.DateFormat)
+	case f.leaf.Kind() == reflect.String:
+		return v.String()
+	case f.leaf == timeType:
+		if tm := v.Interface().(time.Time); !tm.IsZero() {
+			return tm.Format(locale.DateFormat)
+		}
+		return ""
+	}
+
+	return fmt.Sprint(v.Interface())
+}
+
+// formatDate reformats the YYYY-MM-DD date of the API, other values are kept as they are
+func formatDate(value, layout string) string {
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil || layout == "" {
+		return value
+	}
+
+	return date.Format(layout)
+}
@@ -0,0 +1,43 @@
+package csv
+
+import (
+	"testing"
+)
+
+func TestParseColumns(t *testing.T) {
+	// What/Arrange & When/Act
+	columns, err := ParseColumns("BookingDate:Date, transactionAmount ,creditorAccount.iban:IBAN,status")
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(columns) != 4 || columns[0].Field != "BookingDate" || columns[0].Header != "Date" ||
+		columns[1].Field != "transactionAmount" || columns[1].Header != "" || columns[2].Header != "IBAN" {
+		t.Errorf("unexpected columns %+v", columns)
+	}
+
+	for _, invalid := range []string{"", "Unknown", "CreditorAccount", "CreditorName.Iban", "Amount.Unknown"} {
+		if _, err := ParseColumns(invalid); err == nil {
+			t.Errorf("error expected for %q", invalid)
+		}
+	}
+}
+
+func Test_resolveField(t *testing.T) {
+	// What/Arrange & When/Act
+	amount, amountErr := resolveField("Amount")
+	nestedAmount, _ := resolveField("transactionAmount.amount")
+	currency, _ := resolveField("Amount.Currency")
+	date, _ := resolveField("valueDate")
+
+	// Then/Assert
+	if amountErr != nil || !amount.isAmount() || !nestedAmount.isAmount() || currency.isAmount() {
+		t.Errorf("amount fields expected to be recognized, %v", amountErr)
+	}
+
+	if date.name != "ValueDate" || len(date.index) != 1 {
+		t.Errorf("JSON name expected to resolve to the Go field, got %+v", date)
+	}
+}
@@ -0,0 +1,273 @@
+// Package csv writes transactions as CSV with configurable columns, amount layout and locale.
+// The rows are streamed to the underlying writer, so large exports are not kept in memory
+package csv
+
+import (
+	stdcsv "encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+// Status of a transaction
+type Status string
+
+const (
+	StatusBooked      Status = "booked"
+	StatusPending     Status = "pending"
+	StatusInformation Status = "information"
+)
+
+// AmountMode how the amount columns are written
+type AmountMode int
+
+const (
+	// SignedAmount a single column, negative for debits
+	SignedAmount AmountMode = iota
+	// DebitCredit two columns with the absolute amount, one of them is empty
+	DebitCredit
+)
+
+const bom = "\ufeff"
+
+// Locale the number and date format of the CSV
+type Locale struct {
+	// DecimalSeparator of the amounts, "." if empty
+	DecimalSeparator string
+	// DateFormat Go layout of the dates, "2006-01-02" if empty
+	DateFormat string
+	// Comma the field delimiter, ',' if zero
+	Comma rune
+}
+
+var (
+	// LocaleDefault ISO dates and a decimal point
+	LocaleDefault = Locale{DecimalSeparator: ".", DateFormat: "2006-01-02", Comma: ','}
+	// LocaleDE German spreadsheets, e.g. "01.03.2022;-45,10"
+	LocaleDE = Locale{DecimalSeparator: ",", DateFormat: "02.01.2006", Comma: ';'}
+	// LocaleFR French spreadsheets, e.g. "01/03/2022;-45,10"
+	LocaleFR = Locale{DecimalSeparator: ",", DateFormat: "02/01/2006", Comma: ';'}
+)
+
+// Options of the CSV
+type Options struct {
+	// Columns of the CSV, DefaultColumns if empty
+	Columns []Column
+	// Amount how the amounts are written, SignedAmount by default
+	Amount AmountMode
+	// DebitHeader and CreditHeader the suffixes of the amount columns' headers in the DebitCredit mode,
+	// "debit" and "credit" by default
+	DebitHeader  string
+	CreditHeader string
+	// Locale the number and date format, LocaleDefault by default
+	Locale Locale
+	// BOM writes the UTF-8 byte order mark, so spreadsheets detect the encoding
+	BOM bool
+	// NoHeader skips the header row, e.g. when appending to an existing file
+	NoHeader bool
+}
+
+// Writer writes transactions as CSV rows
+type Writer struct {
+	csv     *stdcsv.Writer
+	opts    Options
+	columns []writerColumn
+	row     []string
+}
+
+// writerColumn a column with its resolved field
+type writerColumn struct {
+	Column
+	field *field
+}
+
+// NewWriter creates the writer and writes the BOM and the header if required
+func NewWriter(w io.Writer, opts *Options) (*Writer, error) {
+	o := Options{}
+	if opts != nil {
+		o = *opts
+	}
+
+	if len(o.Columns) == 0 {
+		o.Columns = DefaultColumns
+	}
+	if o.DebitHeader == "" {
+		o.DebitHeader = "debit"
+	}
+	if o.CreditHeader == "" {
+		o.CreditHeader = "credit"
+	}
+	if o.Locale.DecimalSeparator == "" {
+		o.Locale.DecimalSeparator = LocaleDefault.DecimalSeparator
+	}
+	if o.Locale.DateFormat == "" {
+		o.Locale.DateFormat = LocaleDefault.DateFormat
+	}
+	if o.Locale.Comma == 0 {
+		o.Locale.Comma = LocaleDefault.Comma
+	}
+
+	if o.Locale.DecimalSeparator == string(o.Locale.Comma) {
+		return nil, errors.New("the decimal separator can't be the field delimiter")
+	}
+
+	cw := &Writer{csv: stdcsv.NewWriter(w), opts: o}
+	cw.csv.Comma = o.Locale.Comma
+
+	header := make([]string, 0, len(o.Columns))
+	for _, c := range o.Columns {
+		column := writerColumn{Column: c}
+		if c.Header == "" {
+			column.Header = c.Field
+		}
+
+		if c.Value == nil {
+			f, err := resolveField(c.Field)
+			if err != nil {
+				return nil, err
+			}
+			column.field = f
+		}
+
+		cw.columns = append(cw.columns, column)
+		if column.isAmount() && o.Amount == DebitCredit {
+			header = append(header, column.Header+" "+o.DebitHeader, column.Header+" "+o.CreditHeader)
+		} else {
+			header = append(header, column.Header)
+		}
+	}
+	cw.row = make([]string, len(header))
+
+	if o.BOM {
+		if _, err := io.WriteString(w, bom); err != nil {
+			return nil, err
+		}
+	}
+
+	if !o.NoHeader {
+		if err := cw.csv.Write(header); err != nil {
+			return nil, err
+		}
+	}
+
+	return cw, nil
+}
+
+func (c *writerColumn) isAmount() bool {
+	return c.field != nil && c.field.isAmount()
+}
+
+// Write writes the transaction as a row
+func (w *Writer) Write(status Status, t *nordigen.TransactionResponse) error {
+	i := 0
+	for _, c := range w.columns {
+		switch {
+		case c.Value != nil:
+			w.row[i] = c.Value(t, status)
+		case c.field.name == FieldStatus:
+			w.row[i] = string(status)
+		case c.isAmount():
+			amount := ""
+			if v, ok := c.field.value(t); ok {
+				if v.Type() == amountType {
+					amount = v.Interface().(nordigen.Amount).Amount
+				} else {
+					amount = v.String()
+				}
+			}
+
+			if w.opts.Amount == DebitCredit {
+				w.row[i], w.row[i+1] = w.debitCredit(amount)
+				i++
+			} else {
+				w.row[i] = w.formatAmount(amount)
+			}
+		default:
+			w.row[i] = ""
+			if v, ok := c.field.value(t); ok {
+				w.row[i] = c.field.format(v, &w.opts.Locale)
+			}
+			if c.field.isText() {
+				w.row[i] = escapeFormula(w.row[i])
+			}
+		}
+		i++
+	}
+
+	return w.csv.Write(w.row)
+}
+
+// WriteCollection writes the booked, pending and information transactions of the collection
+func (w *Writer) WriteCollection(c *nordigen.TransactionCollectionResponse) error {
+	for _, group := range []struct {
+		status       Status
+		transactions []nordigen.TransactionResponse
+	}{
+		{StatusBooked, c.Transactions.Booked},
+		{StatusPending, c.Transactions.Pending},
+		{StatusInformation, c.Transactions.Information},
+	} {
+		for i := range group.transactions {
+			if err := w.Write(group.status, &group.transactions[i]); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Flush writes the buffered rows to the underlying writer
+func (w *Writer) Flush() error {
+	w.csv.Flush()
+
+	return w.csv.Error()
+}
+
+// escapeFormula prefixes the cell with a single quote if a spreadsheet would evaluate it as a formula
+func escapeFormula(cell string) string {
+	if cell != "" && strings.ContainsRune("=+-@\t\r", rune(cell[0])) {
+		return "'" + cell
+	}
+
+	return cell
+}
+
+// formatAmount replaces the decimal point of the API's amount with the locale's separator
+func (w *Writer) formatAmount(amount string) string {
+	amount = strings.TrimSpace(amount)
+	if w.opts.Locale.DecimalSeparator == "." {
+		return amount
+	}
+
+	return strings.Replace(amount, ".", w.opts.Locale.DecimalSeparator, 1)
+}
+
+// debitCredit returns the absolute amount in the debit or the credit column
+func (w *Writer) debitCredit(amount string) (string, string) {
+	amount = strings.TrimSpace(amount)
+	switch {
+	case amount == "":
+		return "", ""
+	case strings.HasPrefix(amount, "-"):
+		return w.formatAmount(amount[1:]), ""
+	default:
+		return "", w.formatAmount(strings.TrimPrefix(amount, "+"))
+	}
+}
+
+// WriteCollection writes the transactions of the collection as CSV with the options
+func WriteCollection(w io.Writer, c *nordigen.TransactionCollectionResponse, opts *Options) error {
+	cw, err := NewWriter(w, opts)
+	if err != nil {
+		return err
+	}
+
+	if err := cw.WriteCollection(c); err != nil {
+		return err
+	}
+
+	return cw.Flush()
+}
@@ -0,0 +1,193 @@
+package csv
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+func testCollection() *nordigen.TransactionCollectionResponse {
+	c := &nordigen.TransactionCollectionResponse{}
+	c.Transactions.Booked = []nordigen.TransactionResponse{
+		{
+			ID:                                uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6"),
+			Amount:                            nordigen.Amount{Amount: "-45.10", Currency: "EUR"},
+			BookingDate:                       "2022-03-01",
+			ValueDate:                         "2022-03-02",
+			CreditorName:                      "Bäckerei; Müller",
+			CreditorAccount:                   &nordigen.AccountReference{Iban: "DE89370400440532013000"},
+			RemittanceInformationUnstructured: "Brot \"Dinkel\"",
+		},
+		{
+			Amount:      nordigen.Amount{Amount: "2500.00", Currency: "EUR"},
+			BookingDate: "2022-03-25",
+			DebtorName:  "Employer GmbH",
+		},
+	}
+	c.Transactions.Pending = []nordigen.TransactionResponse{
+		{Amount: nordigen.Amount{Amount: "-9.99", Currency: "EUR"}, ValueDate: "2022-03-30"},
+	}
+
+	return c
+}
+
+func TestWriteCollection(t *testing.T) {
+	t.Run("default", testWriteCollectionDefault)
+	t.Run("locale", testWriteCollectionLocale)
+	t.Run("computed column", testWriteCollectionComputed)
+	t.Run("formula", testWriteCollectionFormula)
+	t.Run("invalid options", testWriteCollectionInvalid)
+}
+
+func testWriteCollectionDefault(t *testing.T) {
+	// What/Arrange
+	buf := &bytes.Buffer{}
+
+	// When/Act
+	err := WriteCollection(buf, testCollection(), nil)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	expected := "status,booking_date,value_date,amount,currency,creditor_name,creditor_iban,debtor_name,debtor_iban,description,transaction_id\n" +
+		`booked,2022-03-01,2022-03-02,-45.10,EUR,Bäckerei; Müller,DE89370400440532013000,,,"Brot ""Dinkel""",3fa85f64-5717-4562-b3fc-2c963f66afa6` + "\n" +
+		"booked,2022-03-25,,2500.00,EUR,,,Employer GmbH,,,\n" +
+		"pending,,2022-03-30,-9.99,EUR,,,,,,\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected CSV\n%s", buf.String())
+	}
+}
+
+func testWriteCollectionLocale(t *testing.T) {
+	// What/Arrange
+	buf := &bytes.Buffer{}
+	columns, _ := ParseColumns("BookingDate:Buchungstag,Amount:Betrag,CreditorName:Empfänger,CreditorAccount.Iban:IBAN")
+
+	// When/Act
+	err := WriteCollection(buf, testCollection(), &Options{
+		Columns:      columns,
+		Amount:       DebitCredit,
+		DebitHeader:  "Soll",
+		CreditHeader: "Haben",
+		Locale:       LocaleDE,
+		BOM:          true,
+	})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	expected := "\ufeffBuchungstag;Betrag Soll;Betrag Haben;Empfänger;IBAN\n" +
+		`01.03.2022;45,10;;"Bäckerei; Müller";DE89370400440532013000` + "\n" +
+		"25.03.2022;;2500,00;;\n" +
+		";9,99;;;\n"
+	if buf.String() != expected {
+		t.Errorf("unexpected CSV\n%s", buf.String())
+	}
+}
+
+func testWriteCollectionComputed(t *testing.T) {
+	// What/Arrange
+	buf := &bytes.Buffer{}
+	counterparty := Column{Header: "counterparty", Value: func(t *nordigen.TransactionResponse, status Status) string {
+		return t.CreditorName + t.DebtorName
+	}}
+
+	// When/Act
+	w, err := NewWriter(buf, &Options{Columns: []Column{{Field: "Status"}, counterparty}, NoHeader: true})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	collection := testCollection()
+	writeErr := w.Write(StatusBooked, &collection.Transactions.Booked[1])
+	flushErr := w.Flush()
+
+	// Then/Assert
+	if writeErr != nil || flushErr != nil || buf.String() != "booked,Employer GmbH\n" {
+		t.Errorf("computed column without header expected, got %q %v %v", buf.String(), writeErr, flushErr)
+	}
+}
+
+func testWriteCollectionFormula(t *testing.T) {
+	// What/Arrange
+	buf := &bytes.Buffer{}
+	c := &nordigen.TransactionCollectionResponse{}
+	c.Transactions.Booked = []nordigen.TransactionResponse{{
+		Amount:                            nordigen.Amount{Amount: "-12.50", Currency: "EUR"},
+		BookingDate:                       "2022-03-01",
+		CreditorName:                      "=HYPERLINK(\"http://example.com\")",
+		RemittanceInformationUnstructured: "-rent",
+		AdditionalInformation:             "@ref",
+	}}
+	columns, _ := ParseColumns("Amount,CreditorName,RemittanceInformationUnstructured,AdditionalInformation")
+
+	// When/Act
+	err := WriteCollection(buf, c, &Options{Columns: columns, NoHeader: true})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	expected := `-12.50,"'=HYPERLINK(""http://example.com"")",'-rent,'@ref` + "\n"
+	if buf.String() != expected {
+		t.Errorf("escaped text and a numeric amount expected, got %q", buf.String())
+	}
+}
+
+func testWriteCollectionInvalid(t *testing.T) {
+	// What/Arrange
+	cases := map[string]*Options{
+		"unknown field":       {Columns: []Column{{Field: "Unknown"}}},
+		"separator conflicts": {Locale: Locale{DecimalSeparator: ",", Comma: ','}},
+	}
+
+	for name, opts := range cases {
+		// When/Act
+		_, err := NewWriter(&bytes.Buffer{}, opts)
+
+		// Then/Assert
+		if err == nil {
+			t.Errorf("%s: error expected", name)
+		}
+	}
+}
+
+// countingWriter counts the bytes written to it
+type countingWriter struct {
+	n int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += len(p)
+	return len(p), nil
+}
+
+func TestWriter_streaming(t *testing.T) {
+	// What/Arrange
+	out := &countingWriter{}
+	w, err := NewWriter(out, nil)
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	transaction := &testCollection().Transactions.Booked[0]
+
+	// When/Act
+	for i := 0; i < 1000; i++ {
+		if err := w.Write(StatusBooked, transaction); err != nil {
+			t.Fatalf("unexpected error occurred: %s", err)
+		}
+	}
+	beforeFlush := out.n
+	_ = w.Flush()
+
+	// Then/Assert
+	if beforeFlush == 0 || beforeFlush > out.n || out.n-beforeFlush > 8192 {
+		t.Errorf("rows expected to be streamed before the flush, %d of %d bytes written", beforeFlush, out.n)
+	}
+}
@@ -0,0 +1,404 @@
+// Package ofx writes account statements as OFX 2.x documents, or as QFX for the Intuit products,
+// for importing into personal finance tools
+package ofx
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"gromson/nordigen"
+)
+
+const (
+	header = xml.Header +
+		`<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>` + "\n"
+
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102150405"
+
+	maxNameLength   = 32
+	maxMemoLength   = 255
+	maxBankIDLength = 9
+)
+
+var (
+	// ErrNoLedgerBalance returned when none of the balances is a booked one, the ledger balance is mandatory
+	ErrNoLedgerBalance = errors.New("no booked balance for the ledger balance")
+	// ErrMissingIntuBID returned for QFX without the Intuit bank ID
+	ErrMissingIntuBID = errors.New("QFX requires the Intuit bank ID")
+)
+
+// LedgerBalanceTypes the booked balance types used as the ledger balance, the preferred first
+var LedgerBalanceTypes = []string{"closingBooked", "interimBooked"}
+
+// AvailableBalanceTypes the balance types used as the available balance, the preferred first
+var AvailableBalanceTypes = []string{"interimAvailable", "closingAvailable", "forwardAvailable", "expected"}
+
+// Statement the data of the account
+type Statement struct {
+	Details  *nordigen.AccountDetailsResponse
+	Balances *nordigen.BalanceCollectionResponse
+	// Transactions the booked transactions
+	Transactions []nordigen.TransactionResponse
+	// From and To the period of the statement, the dates of the first and the last transaction if zero
+	From time.Time
+	To   time.Time
+}
+
+// Options of the document
+type Options struct {
+	// QFX adds the Intuit elements required by Quicken and QuickBooks
+	QFX bool
+	// IntuBID the Intuit bank ID, required for QFX
+	IntuBID string
+	// Org and FID identify the financial institution in the signon response, written if Org is set
+	Org string
+	FID string
+	// BankID the BANKID of the account, at most 9 characters. The 8 characters following the IBAN's
+	// check digits if empty, i.e. the bank code of German IBANs
+	BankID string
+	// Created the server time of the document, now if zero
+	Created time.Time
+}
+
+type document struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  signOn   `xml:"SIGNONMSGSRSV1>SONRS"`
+	Bank    struct {
+		TransactionID string    `xml:"TRNUID"`
+		Status        status    `xml:"STATUS"`
+		Statement     statement `xml:"STMTRS"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+type signOn struct {
+	Status   status       `xml:"STATUS"`
+	Server   string       `xml:"DTSERVER"`
+	Language string       `xml:"LANGUAGE"`
+	FI       *institution `xml:"FI,omitempty"`
+	IntuBID  string       `xml:"INTU.BID,omitempty"`
+}
+
+type institution struct {
+	Org string `xml:"ORG"`
+	FID string `xml:"FID,omitempty"`
+}
+
+type status struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type statement struct {
+	Currency string `xml:"CURDEF"`
+	Account  struct {
+		BankID    string `xml:"BANKID"`
+		AccountID string `xml:"ACCTID"`
+		Type      string `xml:"ACCTTYPE"`
+	} `xml:"BANKACCTFROM"`
+	Transactions struct {
+		Start        string        `xml:"DTSTART"`
+		End          string        `xml:"DTEND"`
+		Transactions []transaction `xml:"STMTTRN"`
+	} `xml:"BANKTRANLIST"`
+	Ledger    balance  `xml:"LEDGERBAL"`
+	Available *balance `xml:"AVAILBAL,omitempty"`
+}
+
+type transaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	User   string `xml:"DTUSER,omitempty"`
+	Amount string `xml:"TRNAMT"`
+	ID     string `xml:"FITID"`
+	Name   string `xml:"NAME,omitempty"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+type balance struct {
+	Amount string `xml:"BALAMT"`
+	Date   string `xml:"DTASOF"`
+}
+
+// Write writes the statement as an OFX 2.x document, QFX if configured
+func Write(w io.Writer, s *Statement, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+
+	if opts.QFX && opts.IntuBID == "" {
+		return ErrMissingIntuBID
+	}
+
+	if len(opts.BankID) > maxBankIDLength {
+		return errors.Errorf("bank ID %q exceeds %d characters", opts.BankID, maxBankIDLength)
+	}
+
+	doc, err := newDocument(s, opts)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, "\n")
+
+	return err
+}
+
+func newDocument(s *Statement, opts *Options) (*document, error) {
+	created := opts.Created
+	if created.IsZero() {
+		created = time.Now()
+	}
+
+	info := nordigen.AccountDetailsInfoResponse{}
+	if s.Details != nil {
+		info = s.Details.Account
+	}
+
+	var balances []nordigen.BalanceResponse
+	if s.Balances != nil {
+		balances = s.Balances.Balances
+	}
+
+	doc := &document{}
+	doc.SignOn.Status = status{Code: 0, Severity: "INFO"}
+	doc.SignOn.Server = created.UTC().Format(dateTimeFormat)
+	doc.SignOn.Language = "ENG"
+	if opts.Org != "" {
+		doc.SignOn.FI = &institution{Org: opts.Org, FID: opts.FID}
+	}
+	if opts.QFX {
+		doc.SignOn.IntuBID = opts.IntuBID
+	}
+
+	doc.Bank.TransactionID = "0"
+	doc.Bank.Status = status{Code: 0, Severity: "INFO"}
+
+	stmt := &doc.Bank.Statement
+	stmt.Currency = strings.ToUpper(info.Currency)
+	stmt.Account.BankID = bankID(info.Iban, opts.BankID)
+	stmt.Account.AccountID = info.Iban
+	if stmt.Account.AccountID == "" {
+		stmt.Account.AccountID = info.ResourceID.String()
+	}
+	stmt.Account.Type = accountType(info.CashAccountType)
+
+	ledger := pickBalance(balances, LedgerBalanceTypes, stmt.Currency)
+	if ledger == nil {
+		return nil, ErrNoLedgerBalance
+	}
+	if stmt.Currency == "" {
+		stmt.Currency = strings.ToUpper(ledger.BalanceAmount.Currency)
+	}
+	stmt.Ledger = newBalance(ledger, created)
+	if available := pickBalance(balances, AvailableBalanceTypes, stmt.Currency); available != nil {
+		b := newBalance(available, created)
+		stmt.Available = &b
+	}
+
+	transactions := append([]nordigen.TransactionResponse{}, s.Transactions...)
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return postedDate(&transactions[i]) < postedDate(&transactions[j])
+	})
+
+	from, to := s.From, s.To
+	occurrences := make(map[string]int)
+	for i := range transactions {
+		t := &transactions[i]
+		posted, err := time.Parse("2006-01-02", postedDate(t))
+		if err != nil {
+			return nil, errors.Errorf("transaction %s has no valid booking date", FITID(t))
+		}
+
+		if from.IsZero() || posted.Before(from) {
+			from = posted
+		}
+		if to.IsZero() || posted.After(to) {
+			to = posted
+		}
+
+		tr := newTransaction(t, posted)
+		if t.ID == uuid.Nil {
+			key := t.Key()
+			tr.ID = occurrenceFITID(key, occurrences[key])
+			occurrences[key]++
+		}
+		stmt.Transactions.Transactions = append(stmt.Transactions.Transactions, tr)
+	}
+
+	if from.IsZero() {
+		from, to = created, created
+	}
+	stmt.Transactions.Start = from.Format(dateFormat)
+	stmt.Transactions.End = to.Format(dateFormat)
+
+	return doc, nil
+}
+
+func newTransaction(t *nordigen.TransactionResponse, posted time.Time) transaction {
+	amount := strings.TrimSpace(t.Amount.Amount)
+	tr := transaction{
+		Type:   "CREDIT",
+		Posted: posted.Format(dateFormat),
+		Amount: amount,
+		ID:     FITID(t),
+		Name:   truncate(clean(counterparty(t, amount)), maxNameLength),
+		Memo:   truncate(clean(memo(t)), maxMemoLength),
+	}
+	if strings.HasPrefix(amount, "-") {
+		tr.Type = "DEBIT"
+	}
+
+	if value, err := time.Parse("2006-01-02", t.ValueDate); err == nil && t.ValueDate != t.BookingDate {
+		tr.User = value.Format(dateFormat)
+	}
+
+	return tr
+}
+
+// FITID returns the stable ID of the transaction, the finance tools skip the already imported ones by it.
+// The transaction ID if provided by the ASPSP, the hash of its content otherwise. Write numbers
+// the identical transactions without an ID, so each of them gets a distinct FITID
+func FITID(t *nordigen.TransactionResponse) string {
+	if t.ID != uuid.Nil {
+		return t.ID.String()
+	}
+
+	return occurrenceFITID(t.Key(), 0)
+}
+
+// occurrenceFITID returns the FITID of the n-th of the identical transactions without an ID,
+// the first one keeps the FITID of its content
+func occurrenceFITID(key string, n int) string {
+	if n > 0 {
+		key += "\x00" + strconv.Itoa(n)
+	}
+
+	sum := sha256.Sum256([]byte(key))
+
+	return hex.EncodeToString(sum[:16])
+}
+
+// pickBalance returns the balance of the first matching type in the currency, any currency if empty
+func pickBalance(balances []nordigen.BalanceResponse, types []string, currency string) *nordigen.BalanceResponse {
+	for _, balanceType := range types {
+		for i := range balances {
+			b := &balances[i]
+			if b.BalanceType == balanceType && (currency == "" || strings.EqualFold(b.BalanceAmount.Currency, currency)) {
+				return b
+			}
+		}
+	}
+
+	return nil
+}
+
+func newBalance(b *nordigen.BalanceResponse, created time.Time) balance {
+	date := created.UTC().Format(dateTimeFormat)
+	if reference, err := time.Parse("2006-01-02", b.ReferenceDate); err == nil {
+		date = reference.Format(dateFormat)
+	} else if !b.LastChangeDateTime.IsZero() {
+		date = b.LastChangeDateTime.UTC().Format(dateTimeFormat)
+	}
+
+	return balance{Amount: strings.TrimSpace(b.BalanceAmount.Amount), Date: date}
+}
+
+// accountType maps the ISO 20022 cash account type to the OFX one
+func accountType(cashAccountType string) string {
+	switch strings.ToUpper(cashAccountType) {
+	case "SVGS":
+		return "SAVINGS"
+	case "MOMA":
+		return "MONEYMRKT"
+	case "LOAN":
+		return "CREDITLINE"
+	}
+
+	return "CHECKING"
+}
+
+func bankID(iban, configured string) string {
+	if configured != "" {
+		return configured
+	}
+
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) <= 4 {
+		return "UNKNOWN"
+	}
+
+	code := iban[4:]
+	if len(code) > 8 {
+		code = code[:8]
+	}
+
+	return code
+}
+
+func postedDate(t *nordigen.TransactionResponse) string {
+	if t.BookingDate != "" {
+		return t.BookingDate
+	}
+
+	return t.ValueDate
+}
+
+func counterparty(t *nordigen.TransactionResponse, amount string) string {
+	if strings.HasPrefix(amount, "-") {
+		return t.CreditorName
+	}
+
+	return t.DebtorName
+}
+
+func memo(t *nordigen.TransactionResponse) string {
+	if t.RemittanceInformationUnstructured != "" {
+		return t.RemittanceInformationUnstructured
+	}
+
+	if len(t.RemittanceInformationUnstructuredArray) > 0 {
+		return strings.Join(t.RemittanceInformationUnstructuredArray, " ")
+	}
+
+	return t.AdditionalInformation
+}
+
+// clean drops the control characters, which XML 1.0 doesn't allow, and collapses the whitespace
+func clean(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			return -1
+		}
+		return r
+	}, value)
+
+	return strings.Join(strings.Fields(value), " ")
+}
+
+func truncate(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		return string(runes[:length])
+	}
+
+	return value
+}
@@ -0,0 +1,269 @@
+package ofx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gromson/nordigen"
+)
+
+func testStatement() *Statement {
+	s := &Statement{
+		Details:  &nordigen.AccountDetailsResponse{},
+		Balances: &nordigen.BalanceCollectionResponse{},
+	}
+	s.Details.Account = nordigen.AccountDetailsInfoResponse{
+		ResourceID:      uuid.MustParse("7e944232-bda9-40bc-b784-660c7ab5fe78"),
+		Iban:            "DE89370400440532013000",
+		Currency:        "EUR",
+		CashAccountType: "CACC",
+	}
+	s.Balances.Balances = []nordigen.BalanceResponse{
+		{BalanceAmount: nordigen.Amount{Amount: "1500.00", Currency: "EUR"}, BalanceType: "interimAvailable"},
+		{BalanceAmount: nordigen.Amount{Amount: "1234.56", Currency: "EUR"}, BalanceType: "closingBooked", ReferenceDate: "2022-03-31"},
+	}
+	s.Transactions = []nordigen.TransactionResponse{
+		{
+			Amount:      nordigen.Amount{Amount: "2500.00", Currency: "EUR"},
+			BookingDate: "2022-03-25",
+			ValueDate:   "2022-03-25",
+			DebtorName:  "Employer GmbH",
+		},
+		{
+			ID:                                uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6"),
+			Amount:                            nordigen.Amount{Amount: "-45.10", Currency: "EUR"},
+			BookingDate:                       "2022-03-01",
+			ValueDate:                         "2022-03-02",
+			CreditorName:                      "Fish & Chips <Ltd> with a very long name",
+			RemittanceInformationUnstructured: "Order \"42\"\x01 & more",
+		},
+	}
+
+	return s
+}
+
+func writeTestDocument(t *testing.T, s *Statement, opts *Options) (string, *document) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	if err := Write(buf, s, opts); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	doc := &document{}
+	out := buf.String()
+	if err := xml.Unmarshal([]byte(out[strings.Index(out, "<OFX>"):]), doc); err != nil {
+		t.Fatalf("unexpected error occurred: %s\n%s", err, out)
+	}
+
+	return out, doc
+}
+
+func TestWrite(t *testing.T) {
+	t.Run("statement", testWriteStatement)
+	t.Run("escaping", testWriteEscaping)
+	t.Run("stable FITID", testWriteStableFITID)
+	t.Run("FITID of identical transactions", testWriteIdenticalFITID)
+	t.Run("QFX", testWriteQFX)
+	t.Run("balance types", testWriteBalanceTypes)
+	t.Run("errors", testWriteErrors)
+}
+
+func testWriteStatement(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+	created := time.Date(2022, 4, 1, 8, 30, 0, 0, time.UTC)
+
+	// When/Act
+	out, doc := writeTestDocument(t, s, &Options{Created: created})
+
+	// Then/Assert
+	if !strings.HasPrefix(out, `<?xml version="1.0" encoding="UTF-8"?>`+"\n"+`<?OFX OFXHEADER="200" VERSION="220"`) {
+		t.Errorf("OFX header expected\n%s", out)
+	}
+
+	if doc.SignOn.Server != "20220401083000" || doc.SignOn.FI != nil || doc.SignOn.IntuBID != "" {
+		t.Errorf("unexpected signon %+v", doc.SignOn)
+	}
+
+	stmt := doc.Bank.Statement
+	if stmt.Currency != "EUR" || stmt.Account.BankID != "37040044" ||
+		stmt.Account.AccountID != "DE89370400440532013000" || stmt.Account.Type != "CHECKING" {
+		t.Errorf("unexpected account %+v", stmt.Account)
+	}
+
+	if stmt.Transactions.Start != "20220301" || stmt.Transactions.End != "20220325" {
+		t.Errorf("the period of the transactions expected, got %s-%s", stmt.Transactions.Start, stmt.Transactions.End)
+	}
+
+	if stmt.Ledger != (balance{Amount: "1234.56", Date: "20220331"}) {
+		t.Errorf("closing booked ledger balance expected, got %+v", stmt.Ledger)
+	}
+
+	if stmt.Available == nil || *stmt.Available != (balance{Amount: "1500.00", Date: "20220401083000"}) {
+		t.Errorf("interim available balance expected, got %+v", stmt.Available)
+	}
+
+	transactions := stmt.Transactions.Transactions
+	if len(transactions) != 2 {
+		t.Fatalf("2 transactions expected, got %d", len(transactions))
+	}
+
+	if transactions[0] != (transaction{
+		Type:   "DEBIT",
+		Posted: "20220301",
+		User:   "20220302",
+		Amount: "-45.10",
+		ID:     "3fa85f64-5717-4562-b3fc-2c963f66afa6",
+		Name:   "Fish & Chips <Ltd> with a very l",
+		Memo:   "Order \"42\" & more",
+	}) {
+		t.Errorf("unexpected debit %+v", transactions[0])
+	}
+
+	if transactions[1].Type != "CREDIT" || transactions[1].Name != "Employer GmbH" || transactions[1].User != "" {
+		t.Errorf("unexpected credit %+v", transactions[1])
+	}
+}
+
+func testWriteEscaping(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+
+	// When/Act
+	out, _ := writeTestDocument(t, s, nil)
+
+	// Then/Assert
+	if !strings.Contains(out, "<NAME>Fish &amp; Chips &lt;Ltd&gt; with a very l</NAME>") ||
+		!strings.Contains(out, "<MEMO>Order &#34;42&#34; &amp; more</MEMO>") {
+		t.Errorf("escaped text expected\n%s", out)
+	}
+
+	if strings.ContainsRune(out, '\x01') {
+		t.Errorf("control characters aren't allowed\n%s", out)
+	}
+}
+
+func testWriteStableFITID(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+	s.Transactions[0].ID = uuid.Nil
+
+	// When/Act
+	first, doc := writeTestDocument(t, s, &Options{Created: time.Unix(0, 0)})
+	second, _ := writeTestDocument(t, s, &Options{Created: time.Unix(0, 0)})
+
+	// Then/Assert
+	ID := doc.Bank.Statement.Transactions.Transactions[1].ID
+	if len(ID) != 32 || ID != FITID(&s.Transactions[0]) || first != second {
+		t.Errorf("stable FITID expected for the transaction without ID, got %s", ID)
+	}
+}
+
+func testWriteIdenticalFITID(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+	s.Transactions[0].ID = uuid.Nil
+	s.Transactions = append(s.Transactions, s.Transactions[0])
+
+	// When/Act
+	_, doc := writeTestDocument(t, s, &Options{Created: time.Unix(0, 0)})
+
+	// Then/Assert
+	transactions := doc.Bank.Statement.Transactions.Transactions
+	if transactions[1].ID != FITID(&s.Transactions[0]) || transactions[2].ID == transactions[1].ID || len(transactions[2].ID) != 32 {
+		t.Errorf("distinct FITIDs expected for the identical transactions, got %s and %s", transactions[1].ID, transactions[2].ID)
+	}
+}
+
+func testWriteQFX(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+	s.Details.Account.CashAccountType = "SVGS"
+
+	// When/Act
+	_, doc := writeTestDocument(t, s, &Options{QFX: true, IntuBID: "3000", Org: "N26", FID: "1001", BankID: "NTSBDEB1"})
+
+	// Then/Assert
+	if doc.SignOn.IntuBID != "3000" || doc.SignOn.FI == nil || doc.SignOn.FI.Org != "N26" || doc.SignOn.FI.FID != "1001" {
+		t.Errorf("Intuit elements expected, got %+v", doc.SignOn)
+	}
+
+	if doc.Bank.Statement.Account.BankID != "NTSBDEB1" || doc.Bank.Statement.Account.Type != "SAVINGS" {
+		t.Errorf("unexpected account %+v", doc.Bank.Statement.Account)
+	}
+}
+
+func testWriteBalanceTypes(t *testing.T) {
+	// What/Arrange
+	s := testStatement()
+	s.Transactions = nil
+	changed := time.Date(2022, 3, 30, 18, 0, 0, 0, time.UTC)
+	s.Balances.Balances = []nordigen.BalanceResponse{
+		{BalanceAmount: nordigen.Amount{Amount: "99.00", Currency: "USD"}, BalanceType: "closingBooked"},
+		{BalanceAmount: nordigen.Amount{Amount: "1000.00", Currency: "EUR"}, BalanceType: "expected"},
+		{BalanceAmount: nordigen.Amount{Amount: "1100.00", Currency: "EUR"}, BalanceType: "interimBooked", LastChangeDateTime: changed},
+	}
+	created := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	// When/Act
+	_, doc := writeTestDocument(t, s, &Options{Created: created})
+
+	// Then/Assert
+	stmt := doc.Bank.Statement
+	if stmt.Ledger != (balance{Amount: "1100.00", Date: "20220330180000"}) {
+		t.Errorf("interim booked balance in the currency expected, got %+v", stmt.Ledger)
+	}
+
+	if stmt.Available == nil || stmt.Available.Amount != "1000.00" {
+		t.Errorf("expected balance as the available one expected, got %+v", stmt.Available)
+	}
+
+	if len(stmt.Transactions.Transactions) != 0 || stmt.Transactions.Start != "20220401" || stmt.Transactions.End != "20220401" {
+		t.Errorf("empty transaction list expected, got %+v", stmt.Transactions)
+	}
+}
+
+func testWriteErrors(t *testing.T) {
+	// What/Arrange
+	noLedger := testStatement()
+	noLedger.Balances.Balances = noLedger.Balances.Balances[:1]
+	invalidDate := testStatement()
+	invalidDate.Transactions[0].BookingDate = "25.03.2022"
+	unbooked := testStatement()
+	unbooked.Balances.Balances = []nordigen.BalanceResponse{
+		{BalanceAmount: nordigen.Amount{Amount: "1000.00", Currency: "EUR"}, BalanceType: "expected"},
+		{BalanceAmount: nordigen.Amount{Amount: "900.00", Currency: "EUR"}, BalanceType: "openingBooked"},
+	}
+
+	for name, tc := range map[string]struct {
+		statement *Statement
+		opts      *Options
+		err       error
+	}{
+		"no ledger balance": {statement: noLedger, err: ErrNoLedgerBalance},
+		"no booked balance": {statement: unbooked, err: ErrNoLedgerBalance},
+		"QFX without BID":   {statement: testStatement(), opts: &Options{QFX: true}, err: ErrMissingIntuBID},
+		"too long bank ID":  {statement: testStatement(), opts: &Options{BankID: "N26_NTSBDEB1"}},
+		"invalid date":      {statement: invalidDate},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// When/Act
+			buf := &bytes.Buffer{}
+			err := Write(buf, tc.statement, tc.opts)
+
+			// Then/Assert
+			if err == nil || (tc.err != nil && err != tc.err) {
+				t.Errorf("error %v expected, got %v", tc.err, err)
+			}
+
+			if buf.Len() != 0 {
+				t.Errorf("nothing written expected, got %s", buf.String())
+			}
+		})
+	}
+}
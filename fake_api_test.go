@@ -0,0 +1,258 @@
+package nordigen
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// fakeApi stateful in-memory imitation of the agreements and requisitions API
+type fakeApi struct {
+	mu           sync.Mutex
+	agreements   map[uuid.UUID]*EndUserAgreementResponse
+	requisitions map[uuid.UUID]*RequisitionResponse
+	institutions map[string]*InstitutionResponse
+	// payments the IDs of the institutions with enabled payments
+	payments map[string]bool
+	deleted  []string
+	// failDeletes the IDs of the requisitions which can't be deleted
+	failDeletes map[uuid.UUID]bool
+	// institutionLists the number of institution list requests
+	institutionLists int
+}
+
+func newFakeApi() *fakeApi {
+	return &fakeApi{
+		agreements:   make(map[uuid.UUID]*EndUserAgreementResponse),
+		requisitions: make(map[uuid.UUID]*RequisitionResponse),
+		institutions: make(map[string]*InstitutionResponse),
+		payments:     make(map[string]bool),
+		failDeletes:  make(map[uuid.UUID]bool),
+	}
+}
+
+func (a *fakeApi) start() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(a.serveHTTP))
+}
+
+func (a *fakeApi) addAgreement(agreement *EndUserAgreementResponse) *EndUserAgreementResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if agreement.ID == uuid.Nil {
+		agreement.ID = uuid.New()
+	}
+	a.agreements[agreement.ID] = agreement
+
+	return agreement
+}
+
+func (a *fakeApi) addRequisition(requisition *RequisitionResponse) *RequisitionResponse {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if requisition.ID == uuid.Nil {
+		requisition.ID = uuid.New()
+	}
+	a.requisitions[requisition.ID] = requisition
+
+	return requisition
+}
+
+func (a *fakeApi) setRequisitionStatus(ID uuid.UUID, status string, accounts ...uuid.UUID) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.requisitions[ID].Status = status
+	a.requisitions[ID].Accounts = accounts
+}
+
+func (a *fakeApi) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/token/new/" {
+		authenticate(w)
+		return
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	path := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	switch {
+	case path[0] == "agreements" && len(path) >= 2 && path[1] == "enduser":
+		a.serveAgreements(w, r, path[2:])
+	case path[0] == "requisitions":
+		a.serveRequisitions(w, r, path[1:])
+	case path[0] == "institutions":
+		a.serveInstitutions(w, r, path[1:])
+	default:
+		writeFakeResponse(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+	}
+}
+
+func (a *fakeApi) serveAgreements(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodPost:
+			req := &CreateAgreementRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				writeFakeResponse(w, http.StatusBadRequest, map[string]string{"summary": err.Error()})
+				return
+			}
+
+			agreement := &EndUserAgreementResponse{
+				ID:                 uuid.New(),
+				Created:            time.Now().UTC(),
+				MaxHistoricalDays:  req.MaxHistoricalDays,
+				AccessValidForDays: req.AccessValidForDays,
+				AccessScopes:       req.AccessScope,
+				InstitutionID:      req.InstitutionID,
+			}
+			a.agreements[agreement.ID] = agreement
+			writeFakeResponse(w, http.StatusOK, agreement)
+		case http.MethodGet:
+			list := make([]interface{}, 0, len(a.agreements))
+			for _, agreement := range a.agreements {
+				list = append(list, agreement)
+			}
+			writeFakeList(w, r, list)
+		}
+		return
+	}
+
+	ID, err := uuid.Parse(path[0])
+	agreement, ok := a.agreements[ID]
+	if err != nil || !ok {
+		writeFakeResponse(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeFakeResponse(w, http.StatusOK, agreement)
+	case http.MethodPut:
+		accepted := time.Now().UTC()
+		agreement.Accepted = &accepted
+		writeFakeResponse(w, http.StatusOK, agreement)
+	case http.MethodDelete:
+		delete(a.agreements, ID)
+		a.deleted = append(a.deleted, "agreement:"+ID.String())
+		writeFakeResponse(w, http.StatusOK, map[string]string{"summary": "deleted"})
+	}
+}
+
+func (a *fakeApi) serveRequisitions(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		switch r.Method {
+		case http.MethodPost:
+			req := &CreateRequisitionRequest{}
+			if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+				writeFakeResponse(w, http.StatusBadRequest, map[string]string{"summary": err.Error()})
+				return
+			}
+
+			requisition := &RequisitionResponse{
+				ID:            uuid.New(),
+				Created:       time.Now().UTC(),
+				RedirectUrl:   req.Redirect,
+				Status:        RequisitionStatusCreated,
+				InstitutionID: req.InstitutionID,
+				AgreementID:   req.Agreement,
+				Reference:     req.Reference,
+				Accounts:      []uuid.UUID{},
+				UserLanguage:  req.UserLanguage,
+			}
+			requisition.Link = "https://ob.nordigen.com/psd2/start/" + requisition.ID.String() + "/" + req.InstitutionID
+			a.requisitions[requisition.ID] = requisition
+			writeFakeResponse(w, http.StatusOK, requisition)
+		case http.MethodGet:
+			list := make([]interface{}, 0, len(a.requisitions))
+			for _, requisition := range a.requisitions {
+				list = append(list, requisition)
+			}
+			writeFakeList(w, r, list)
+		}
+		return
+	}
+
+	ID, err := uuid.Parse(path[0])
+	requisition, ok := a.requisitions[ID]
+	if err != nil || !ok {
+		writeFakeResponse(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		writeFakeResponse(w, http.StatusOK, requisition)
+	case http.MethodDelete:
+		if a.failDeletes[ID] {
+			writeFakeResponse(w, http.StatusInternalServerError, map[string]string{"summary": "Internal error"})
+			return
+		}
+		delete(a.requisitions, ID)
+		a.deleted = append(a.deleted, "requisition:"+ID.String())
+		writeFakeResponse(w, http.StatusOK, map[string]string{"summary": "deleted"})
+	}
+}
+
+func (a *fakeApi) serveInstitutions(w http.ResponseWriter, r *http.Request, path []string) {
+	if len(path) == 0 {
+		a.institutionLists++
+		country := r.URL.Query().Get("country")
+		payments := r.URL.Query().Get("payments_enabled")
+		list := make([]*InstitutionResponse, 0, len(a.institutions))
+		for _, institution := range a.institutions {
+			if payments != "" && strconv.FormatBool(a.payments[institution.ID]) != payments {
+				continue
+			}
+
+			for _, c := range institution.Countries {
+				if country == "" || c == country {
+					list = append(list, institution)
+					break
+				}
+			}
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+		writeFakeResponse(w, http.StatusOK, list)
+		return
+	}
+
+	institution, ok := a.institutions[path[0]]
+	if !ok {
+		writeFakeResponse(w, http.StatusNotFound, map[string]string{"summary": "Not found"})
+		return
+	}
+
+	writeFakeResponse(w, http.StatusOK, institution)
+}
+
+func writeFakeList(w http.ResponseWriter, r *http.Request, list []interface{}) {
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	end := offset + limit
+	if end > len(list) || limit == 0 {
+		end = len(list)
+	}
+	if offset > end {
+		offset = end
+	}
+
+	writeFakeResponse(w, http.StatusOK, map[string]interface{}{
+		"count":   len(list),
+		"results": list[offset:end],
+	})
+}
+
+func writeFakeResponse(w http.ResponseWriter, statusCode int, payload interface{}) {
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(payload)
+}
@@ -21,6 +21,14 @@ type InstitutionResponse struct {
 	TransactionTotalDays int      `json:"transaction_total_days,string"`
 	Countries            []string `json:"countries"`
 	LogoUrl              string   `json:"logo"`
+	// MaxAccessValidForDays the maximum AccessValidForDays of the institution's agreements. Zero if unknown
+	MaxAccessValidForDays int `json:"max_access_valid_for_days,string,omitempty"`
+	// SupportedPayments payment schemes per payment type, e.g. "single-payment": ["SCT", "ISCT"]
+	SupportedPayments SupportedPayments `json:"supported_payments,omitempty"`
+	// SupportedFeatures the features of the institution, see the InstitutionFeature constants
+	SupportedFeatures []InstitutionFeature `json:"supported_features,omitempty"`
+	// IdentificationCodes additional codes identifying the institution
+	IdentificationCodes []string `json:"identification_codes,omitempty"`
 }
 
 // Institution provides access to institutions
@@ -0,0 +1,326 @@
+package nordigen
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultCatalogTTL = 24 * time.Hour
+)
+
+// ErrInstitutionNotFound returned by InstitutionCatalog when there is no institution matching the lookup
+var ErrInstitutionNotFound = errors.New("institution not found")
+
+// PaymentsFilter filters the institutions by payments support
+type PaymentsFilter int
+
+const (
+	// PaymentsAny doesn't filter by payments support
+	PaymentsAny PaymentsFilter = iota
+	// PaymentsEnabled keeps the institutions with enabled payments, as ListWithEnabledPayments
+	PaymentsEnabled
+	// PaymentsDisabled keeps the institutions with disabled payments, as ListWithDisabledPayments
+	PaymentsDisabled
+)
+
+// InstitutionQuery the criteria of InstitutionCatalog.Search
+type InstitutionQuery struct {
+	// Country ISO 3166 two-character country code, all countries if empty
+	Country string
+	// Name accent-insensitive fuzzy search on the name, the best matches first. All institutions if empty
+	Name     string
+	Payments PaymentsFilter
+	// Limit the number of results, unlimited if zero
+	Limit int
+}
+
+// InstitutionCatalog caches the institutions per country. Stale countries are served from the cache
+// while they are refreshed in the background. Safe for concurrent use
+type InstitutionCatalog struct {
+	client *Nordigen
+	// TTL after which a country is refreshed. 24 hours by default
+	TTL time.Duration
+	// OnRefreshError is called if a background refresh fails, the stale data is kept
+	OnRefreshError func(country string, err error)
+
+	mu        sync.Mutex
+	countries map[string]*catalogCountry
+	now       func() time.Time
+}
+
+type catalogCountry struct {
+	// ready closed once the first load finishes
+	ready        chan struct{}
+	err          error
+	institutions []InstitutionResponse
+	names        [][]string
+	payments     map[string]bool
+	fetched      time.Time
+	refreshing   bool
+}
+
+// NewInstitutionCatalog creates an empty catalog, the countries are loaded on the first use
+func NewInstitutionCatalog(n *Nordigen) *InstitutionCatalog {
+	return &InstitutionCatalog{
+		client:    n,
+		TTL:       defaultCatalogTTL,
+		countries: make(map[string]*catalogCountry),
+		now:       time.Now,
+	}
+}
+
+// Institutions returns the institutions of the country, all countries if empty
+func (c *InstitutionCatalog) Institutions(ctx context.Context, country string) ([]InstitutionResponse, error) {
+	entry, err := c.country(ctx, country)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]InstitutionResponse(nil), entry.institutions...), nil
+}
+
+// ByID returns the institution with the given ID from the country, all countries if empty,
+// or ErrInstitutionNotFound
+func (c *InstitutionCatalog) ByID(ctx context.Context, country, ID string) (*InstitutionResponse, error) {
+	entry, err := c.country(ctx, country)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entry.institutions {
+		if entry.institutions[i].ID == ID {
+			institution := entry.institutions[i]
+			return &institution, nil
+		}
+	}
+
+	return nil, ErrInstitutionNotFound
+}
+
+// ByBIC returns the institutions with the given BIC from the country, all countries if empty.
+// The BIC is matched case-insensitive, an 8-character BIC matches the 11-character BICs of its branches.
+// ErrInstitutionNotFound is returned if there is no such institution
+func (c *InstitutionCatalog) ByBIC(ctx context.Context, country, BIC string) ([]InstitutionResponse, error) {
+	entry, err := c.country(ctx, country)
+	if err != nil {
+		return nil, err
+	}
+
+	BIC = strings.ToUpper(strings.TrimSpace(BIC))
+	found := make([]InstitutionResponse, 0, 1)
+	for _, institution := range entry.institutions {
+		if bicMatches(BIC, strings.ToUpper(institution.BIC)) {
+			found = append(found, institution)
+		}
+	}
+
+	if len(found) == 0 {
+		return nil, ErrInstitutionNotFound
+	}
+
+	return found, nil
+}
+
+// bicMatches compares BICs treating the primary office code "XXX" and a missing branch code as equal
+func bicMatches(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+
+	trim := func(bic string) string {
+		if len(bic) == 11 && strings.HasSuffix(bic, "XXX") {
+			return bic[:8]
+		}
+		return bic
+	}
+	a, b = trim(a), trim(b)
+
+	return a == b || (len(a) == 8 && strings.HasPrefix(b, a)) || (len(b) == 8 && strings.HasPrefix(a, b))
+}
+
+// Search returns the institutions matching the query
+func (c *InstitutionCatalog) Search(ctx context.Context, query InstitutionQuery) ([]InstitutionResponse, error) {
+	entry, err := c.country(ctx, query.Country)
+	if err != nil {
+		return nil, err
+	}
+
+	institutions := make([]InstitutionResponse, 0, len(entry.institutions))
+	names := make([][]string, 0, len(entry.institutions))
+	for i, institution := range entry.institutions {
+		if query.Payments == PaymentsEnabled && !entry.payments[institution.ID] ||
+			query.Payments == PaymentsDisabled && entry.payments[institution.ID] {
+			continue
+		}
+
+		institutions = append(institutions, institution)
+		names = append(names, entry.names[i])
+	}
+
+	result := searchInstitutions(institutions, names, query.Name)
+	if query.Limit > 0 && len(result) > query.Limit {
+		result = result[:query.Limit]
+	}
+
+	return result, nil
+}
+
+// Refresh reloads the country synchronously, all countries if empty
+func (c *InstitutionCatalog) Refresh(ctx context.Context, country string) error {
+	country = strings.ToUpper(country)
+
+	entry, err := c.load(ctx, country)
+	if err != nil {
+		return err
+	}
+	close(entry.ready)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.countries[country] = entry
+
+	return nil
+}
+
+// Invalidate drops the cached countries, all of them if none is given
+func (c *InstitutionCatalog) Invalidate(countries ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(countries) == 0 {
+		c.countries = make(map[string]*catalogCountry)
+		return
+	}
+
+	for _, country := range countries {
+		delete(c.countries, strings.ToUpper(country))
+	}
+}
+
+// country returns the cached country, waiting for the first load and starting a background refresh if stale
+func (c *InstitutionCatalog) country(ctx context.Context, country string) (*catalogCountry, error) {
+	country = strings.ToUpper(country)
+
+	c.mu.Lock()
+	entry, ok := c.countries[country]
+	if !ok {
+		entry = &catalogCountry{ready: make(chan struct{})}
+		c.countries[country] = entry
+		c.mu.Unlock()
+
+		loaded, err := c.load(ctx, country)
+
+		c.mu.Lock()
+		if err != nil {
+			// not cached, so the next call retries
+			if c.countries[country] == entry {
+				delete(c.countries, country)
+			}
+			entry.err = err
+		} else {
+			entry.institutions, entry.names, entry.payments, entry.fetched =
+				loaded.institutions, loaded.names, loaded.payments, loaded.fetched
+		}
+		close(entry.ready)
+		c.mu.Unlock()
+
+		if err != nil {
+			return nil, err
+		}
+
+		return entry, nil
+	}
+
+	select {
+	case <-entry.ready:
+	default:
+		c.mu.Unlock()
+		select {
+		case <-entry.ready:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		if entry.err != nil {
+			return nil, entry.err
+		}
+
+		return entry, nil
+	}
+
+	if c.now().Sub(entry.fetched) >= c.ttl() && !entry.refreshing {
+		entry.refreshing = true
+		go c.refreshInBackground(country, entry)
+	}
+	c.mu.Unlock()
+
+	return entry, nil
+}
+
+func (c *InstitutionCatalog) refreshInBackground(country string, stale *catalogCountry) {
+	loaded, err := c.load(context.Background(), country)
+
+	c.mu.Lock()
+	stale.refreshing = false
+	if err == nil && c.countries[country] == stale {
+		close(loaded.ready)
+		c.countries[country] = loaded
+	}
+	c.mu.Unlock()
+
+	if err != nil && c.OnRefreshError != nil {
+		c.OnRefreshError(country, err)
+	}
+}
+
+// load fetches the institutions of the country and the ones with enabled payments
+func (c *InstitutionCatalog) load(ctx context.Context, country string) (*catalogCountry, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	institutions, err := c.client.Institution().List(country)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing institutions")
+	}
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	withPayments, err := c.client.Institution().ListWithEnabledPayments(country)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing institutions with enabled payments")
+	}
+
+	entry := &catalogCountry{
+		ready:        make(chan struct{}),
+		institutions: institutions,
+		names:        make([][]string, len(institutions)),
+		payments:     make(map[string]bool, len(withPayments)),
+		fetched:      c.now(),
+	}
+
+	for i, institution := range institutions {
+		entry.names[i] = normalizeName(institution.Name)
+	}
+
+	for _, institution := range withPayments {
+		entry.payments[institution.ID] = true
+	}
+
+	return entry, nil
+}
+
+func (c *InstitutionCatalog) ttl() time.Duration {
+	if c.TTL <= 0 {
+		return defaultCatalogTTL
+	}
+
+	return c.TTL
+}
@@ -0,0 +1,148 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func startCatalogApi() *fakeApi {
+	api := newFakeApi()
+	api.institutions["N26_NTSBDEB1"] = &InstitutionResponse{ID: "N26_NTSBDEB1", Name: "N26 Bank", BIC: "NTSBDEB1XXX", Countries: []string{"DE"}}
+	api.institutions["DEUTSCHE_BANK_DEUTDEFF"] = &InstitutionResponse{ID: "DEUTSCHE_BANK_DEUTDEFF", Name: "Deutsche Bank", BIC: "DEUTDEFF", Countries: []string{"DE"}}
+	api.institutions["SPARKASSE_KOELNBONN"] = &InstitutionResponse{ID: "SPARKASSE_KOELNBONN", Name: "Sparkasse KölnBonn", BIC: "COLSDE33", Countries: []string{"DE"}}
+	api.institutions["REVOLUT_REVOGB21"] = &InstitutionResponse{ID: "REVOLUT_REVOGB21", Name: "Revolut", BIC: "REVOGB21", Countries: []string{"GB", "DE"}}
+	api.payments["N26_NTSBDEB1"] = true
+	api.payments["REVOLUT_REVOGB21"] = true
+
+	return api
+}
+
+func TestInstitutionCatalog(t *testing.T) {
+	t.Parallel()
+	t.Run("catalog lookups", testInstitutionCatalogLookups)
+	t.Run("catalog search", testInstitutionCatalogSearch)
+	t.Run("catalog background refresh", testInstitutionCatalogRefresh)
+}
+
+func testInstitutionCatalogLookups(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := NewInstitutionCatalog(createTestNordigen(srv))
+
+	// When/Act
+	byID, err := underTest.ByID(context.Background(), "de", "N26_NTSBDEB1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	byBIC, err := underTest.ByBIC(context.Background(), "DE", "deutdeffxxx")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	byBranchBIC, err := underTest.ByBIC(context.Background(), "DE", "NTSBDEB1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	_, missingErr := underTest.ByID(context.Background(), "DE", "MISSING")
+
+	// Then/Assert
+	if byID.Name != "N26 Bank" || byBIC[0].ID != "DEUTSCHE_BANK_DEUTDEFF" || byBranchBIC[0].ID != "N26_NTSBDEB1" {
+		t.Fatal("institutions expected to be found by ID and BIC")
+	}
+
+	if !errors.Is(missingErr, ErrInstitutionNotFound) {
+		t.Fatalf("ErrInstitutionNotFound expected, %v returned", missingErr)
+	}
+
+	// institutions and institutions with enabled payments
+	if api.institutionLists != 2 {
+		t.Fatalf("country expected to be downloaded once, %d requests sent", api.institutionLists)
+	}
+}
+
+func testInstitutionCatalogSearch(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := NewInstitutionCatalog(createTestNordigen(srv))
+
+	// When/Act
+	byName, err := underTest.Search(context.Background(), InstitutionQuery{Country: "DE", Name: "koln"})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	enabled, _ := underTest.Search(context.Background(), InstitutionQuery{Country: "DE", Payments: PaymentsEnabled})
+	disabled, _ := underTest.Search(context.Background(), InstitutionQuery{Country: "DE", Payments: PaymentsDisabled, Limit: 1})
+	gb, _ := underTest.Search(context.Background(), InstitutionQuery{Country: "GB"})
+
+	// Then/Assert
+	if len(byName) != 1 || byName[0].ID != "SPARKASSE_KOELNBONN" {
+		t.Fatalf("accent-insensitive match expected, %v found", byName)
+	}
+
+	if len(enabled) != 2 {
+		t.Fatalf("2 institutions with enabled payments expected, %v found", enabled)
+	}
+
+	if len(disabled) != 1 || disabled[0].ID != "DEUTSCHE_BANK_DEUTDEFF" {
+		t.Fatalf("first institution with disabled payments expected, %v found", disabled)
+	}
+
+	if len(gb) != 1 || gb[0].ID != "REVOLUT_REVOGB21" {
+		t.Fatalf("institutions of the other country expected, %v found", gb)
+	}
+}
+
+func testInstitutionCatalogRefresh(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	now := time.Now()
+	underTest := NewInstitutionCatalog(createTestNordigen(srv))
+	underTest.TTL = time.Hour
+	underTest.now = func() time.Time { return now }
+
+	if _, err := underTest.Institutions(context.Background(), "GB"); err != nil {
+		t.Fatal(err)
+	}
+
+	api.mu.Lock()
+	api.institutions["MONZO_MONZGB2L"] = &InstitutionResponse{ID: "MONZO_MONZGB2L", Name: "Monzo", Countries: []string{"GB"}}
+	api.mu.Unlock()
+
+	// When/Act
+	fresh, _ := underTest.Institutions(context.Background(), "GB")
+
+	underTest.mu.Lock()
+	now = now.Add(2 * time.Hour)
+	underTest.mu.Unlock()
+	stale, _ := underTest.Institutions(context.Background(), "GB")
+
+	var refreshed []InstitutionResponse
+	for i := 0; i < 100 && len(refreshed) != 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+		refreshed, _ = underTest.Institutions(context.Background(), "GB")
+	}
+
+	// Then/Assert
+	if len(fresh) != 1 || len(stale) != 1 {
+		t.Fatalf("cached institutions expected within TTL and until refreshed, %d and %d returned", len(fresh), len(stale))
+	}
+
+	if len(refreshed) != 2 {
+		t.Fatalf("catalog expected to be refreshed in the background, %v returned", refreshed)
+	}
+}
@@ -0,0 +1,234 @@
+package nordigen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrEmptyCatalog returned by InstitutionWatcher when the API returns no institutions for a country
+// which had some, to not report all of them as removed because of an API failure
+var ErrEmptyCatalog = errors.New("institution catalog is empty")
+
+// InstitutionChangeKind the kind of change of an institution between two catalog snapshots
+type InstitutionChangeKind string
+
+const (
+	InstitutionAdded   InstitutionChangeKind = "added"
+	InstitutionRemoved InstitutionChangeKind = "removed"
+	InstitutionRenamed InstitutionChangeKind = "renamed"
+	// InstitutionHistoryChanged TransactionTotalDays changed
+	InstitutionHistoryChanged InstitutionChangeKind = "transaction_total_days_changed"
+	InstitutionLogoChanged    InstitutionChangeKind = "logo_changed"
+)
+
+// InstitutionChange a change of an institution between two catalog snapshots
+type InstitutionChange struct {
+	Kind          InstitutionChangeKind `json:"kind"`
+	Country       string                `json:"country"`
+	InstitutionID string                `json:"institution_id"`
+	// Old nil if the institution has been added
+	Old *InstitutionResponse `json:"old,omitempty"`
+	// New nil if the institution has been removed
+	New *InstitutionResponse `json:"new,omitempty"`
+	// OldValue and NewValue of the changed field, empty for added and removed institutions
+	OldValue string `json:"old_value,omitempty"`
+	NewValue string `json:"new_value,omitempty"`
+}
+
+// DiffInstitutions compares two lists of the country's institutions. The changes are ordered by the institution ID
+func DiffInstitutions(country string, old, new []InstitutionResponse) []InstitutionChange {
+	oldByID := make(map[string]*InstitutionResponse, len(old))
+	for i := range old {
+		oldByID[old[i].ID] = &old[i]
+	}
+
+	newByID := make(map[string]*InstitutionResponse, len(new))
+	for i := range new {
+		newByID[new[i].ID] = &new[i]
+	}
+
+	changes := make([]InstitutionChange, 0)
+	for ID, o := range oldByID {
+		if _, ok := newByID[ID]; !ok {
+			changes = append(changes, InstitutionChange{Kind: InstitutionRemoved, Country: country, InstitutionID: ID, Old: o})
+		}
+	}
+
+	for ID, n := range newByID {
+		o, ok := oldByID[ID]
+		if !ok {
+			changes = append(changes, InstitutionChange{Kind: InstitutionAdded, Country: country, InstitutionID: ID, New: n})
+			continue
+		}
+
+		field := func(kind InstitutionChangeKind, oldValue, newValue string) {
+			if oldValue != newValue {
+				changes = append(changes, InstitutionChange{
+					Kind:          kind,
+					Country:       country,
+					InstitutionID: ID,
+					Old:           o,
+					New:           n,
+					OldValue:      oldValue,
+					NewValue:      newValue,
+				})
+			}
+		}
+
+		field(InstitutionRenamed, o.Name, n.Name)
+		field(InstitutionHistoryChanged, strconv.Itoa(o.TransactionTotalDays), strconv.Itoa(n.TransactionTotalDays))
+		field(InstitutionLogoChanged, o.LogoUrl, n.LogoUrl)
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].InstitutionID != changes[j].InstitutionID {
+			return changes[i].InstitutionID < changes[j].InstitutionID
+		}
+
+		return changes[i].Kind < changes[j].Kind
+	})
+
+	return changes
+}
+
+// InstitutionSnapshot the institutions of a country at a point in time
+type InstitutionSnapshot struct {
+	// Country ISO 3166 two-character country code, all countries if empty
+	Country      string                `json:"country"`
+	Taken        time.Time             `json:"taken"`
+	Institutions []InstitutionResponse `json:"institutions"`
+}
+
+// SaveInstitutionSnapshot writes the snapshot as JSON. The file is replaced atomically
+func SaveInstitutionSnapshot(path string, snapshot *InstitutionSnapshot) error {
+	payload, err := json.Marshal(snapshot)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling institution snapshot")
+	}
+
+	if err := writeFileAtomically(path, payload); err != nil {
+		return errors.Wrap(err, "error saving institution snapshot")
+	}
+
+	return nil
+}
+
+// LoadInstitutionSnapshot reads the snapshot written by SaveInstitutionSnapshot.
+// The error satisfies errors.Is(err, os.ErrNotExist) if there is no snapshot
+func LoadInstitutionSnapshot(path string) (*InstitutionSnapshot, error) {
+	payload, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &InstitutionSnapshot{}
+	if err := json.Unmarshal(payload, snapshot); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling institution snapshot")
+	}
+
+	return snapshot, nil
+}
+
+// InstitutionWatcher reports the changes of the institutions since the previous check. The zero value with
+// the Client set is ready to use. Safe for concurrent use
+type InstitutionWatcher struct {
+	Client *Nordigen
+	// Dir the snapshots are persisted to, so the changes are detected across restarts. Kept in memory only if empty
+	Dir string
+
+	mu        sync.Mutex
+	snapshots map[string]*InstitutionSnapshot
+	now       func() time.Time
+}
+
+// NewInstitutionWatcher creates a watcher persisting the snapshots to the directory, in memory only if empty
+func NewInstitutionWatcher(n *Nordigen, dir string) *InstitutionWatcher {
+	return &InstitutionWatcher{
+		Client:    n,
+		Dir:       dir,
+		snapshots: make(map[string]*InstitutionSnapshot),
+		now:       time.Now,
+	}
+}
+
+// Check lists the institutions of the country, all countries if empty, and returns the changes since
+// the previous check. The first check of a country only takes the snapshot and reports no changes
+func (w *InstitutionWatcher) Check(ctx context.Context, country string) ([]InstitutionChange, error) {
+	country = strings.ToUpper(country)
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	institutions, err := w.Client.Institution().List(country)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing institutions")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	previous, err := w.previous(country)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(institutions) == 0 && previous != nil && len(previous.Institutions) > 0 {
+		return nil, ErrEmptyCatalog
+	}
+
+	current := &InstitutionSnapshot{Country: country, Taken: w.clock().UTC(), Institutions: institutions}
+	if w.Dir != "" {
+		if err := SaveInstitutionSnapshot(w.snapshotPath(country), current); err != nil {
+			return nil, err
+		}
+	}
+	if w.snapshots == nil {
+		w.snapshots = make(map[string]*InstitutionSnapshot)
+	}
+	w.snapshots[country] = current
+
+	if previous == nil {
+		return []InstitutionChange{}, nil
+	}
+
+	return DiffInstitutions(country, previous.Institutions, institutions), nil
+}
+
+func (w *InstitutionWatcher) clock() time.Time {
+	if w.now == nil {
+		return time.Now()
+	}
+
+	return w.now()
+}
+
+func (w *InstitutionWatcher) previous(country string) (*InstitutionSnapshot, error) {
+	if snapshot, ok := w.snapshots[country]; ok || w.Dir == "" {
+		return snapshot, nil
+	}
+
+	snapshot, err := LoadInstitutionSnapshot(w.snapshotPath(country))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+
+	return snapshot, err
+}
+
+func (w *InstitutionWatcher) snapshotPath(country string) string {
+	if country == "" {
+		country = "ALL"
+	}
+
+	return filepath.Join(w.Dir, "institutions-"+country+".json")
+}
@@ -0,0 +1,167 @@
+package nordigen
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestDiffInstitutions(t *testing.T) {
+	// What/Arrange
+	old := []InstitutionResponse{
+		{ID: "A", Name: "Alpha Bank", TransactionTotalDays: 730, LogoUrl: "https://cdn/a.png"},
+		{ID: "B", Name: "Beta Bank", TransactionTotalDays: 540, LogoUrl: "https://cdn/b.png"},
+		{ID: "C", Name: "Gamma Bank", TransactionTotalDays: 90, LogoUrl: "https://cdn/c.png"},
+	}
+	updated := []InstitutionResponse{
+		{ID: "A", Name: "Alpha Bank", TransactionTotalDays: 730, LogoUrl: "https://cdn/a.png"},
+		{ID: "B", Name: "Beta Bank AG", TransactionTotalDays: 90, LogoUrl: "https://cdn/b2.png"},
+		{ID: "D", Name: "Delta Bank", TransactionTotalDays: 365, LogoUrl: "https://cdn/d.png"},
+	}
+
+	// When/Act
+	changes := DiffInstitutions("DE", old, updated)
+
+	// Then/Assert
+	expected := []struct {
+		kind     InstitutionChangeKind
+		ID       string
+		oldValue string
+		newValue string
+	}{
+		{InstitutionLogoChanged, "B", "https://cdn/b.png", "https://cdn/b2.png"},
+		{InstitutionRenamed, "B", "Beta Bank", "Beta Bank AG"},
+		{InstitutionHistoryChanged, "B", "540", "90"},
+		{InstitutionRemoved, "C", "", ""},
+		{InstitutionAdded, "D", "", ""},
+	}
+
+	if len(changes) != len(expected) {
+		t.Fatalf("%d changes expected, got %+v", len(expected), changes)
+	}
+
+	for i, e := range expected {
+		c := changes[i]
+		if c.Kind != e.kind || c.InstitutionID != e.ID || c.OldValue != e.oldValue || c.NewValue != e.newValue || c.Country != "DE" {
+			t.Errorf("change %d expected %+v, got %+v", i, e, c)
+		}
+	}
+
+	if changes[3].Old == nil || changes[3].New != nil || changes[4].Old != nil || changes[4].New == nil {
+		t.Error("removed and added changes expected to carry the old and new institution only")
+	}
+
+	if len(DiffInstitutions("DE", old, old)) != 0 {
+		t.Error("no changes expected for the same institutions")
+	}
+}
+
+func TestInstitutionWatcher(t *testing.T) {
+	t.Parallel()
+	t.Run("watcher persists snapshots", testInstitutionWatcherPersists)
+	t.Run("watcher rejects empty catalog", testInstitutionWatcherEmptyCatalog)
+	t.Run("watcher zero value", testInstitutionWatcherZeroValue)
+}
+
+func testInstitutionWatcherPersists(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	first := NewInstitutionWatcher(createTestNordigen(srv), dir)
+
+	baseline, err := first.Check(context.Background(), "de")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	api.mu.Lock()
+	delete(api.institutions, "N26_NTSBDEB1")
+	api.mu.Unlock()
+
+	// When/Act
+	restarted := NewInstitutionWatcher(createTestNordigen(srv), dir)
+	changes, err := restarted.Check(context.Background(), "DE")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	// Then/Assert
+	if len(baseline) != 0 {
+		t.Errorf("first check expected to report no changes, got %+v", baseline)
+	}
+
+	if len(changes) != 1 || changes[0].Kind != InstitutionRemoved || changes[0].InstitutionID != "N26_NTSBDEB1" {
+		t.Fatalf("removal expected to be detected across restarts, got %+v", changes)
+	}
+
+	snapshot, err := LoadInstitutionSnapshot(filepath.Join(dir, "institutions-DE.json"))
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if snapshot.Country != "DE" || len(snapshot.Institutions) != 3 || snapshot.Taken.IsZero() {
+		t.Errorf("latest snapshot expected to be saved, got %+v", snapshot)
+	}
+
+	if _, err := LoadInstitutionSnapshot(filepath.Join(dir, "institutions-GB.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("os.ErrNotExist expected for a missing snapshot, got %v", err)
+	}
+}
+
+func testInstitutionWatcherEmptyCatalog(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := NewInstitutionWatcher(createTestNordigen(srv), "")
+	if _, err := underTest.Check(context.Background(), "DE"); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	api.mu.Lock()
+	api.institutions = make(map[string]*InstitutionResponse)
+	api.mu.Unlock()
+
+	// When/Act
+	_, err := underTest.Check(context.Background(), "DE")
+
+	// Then/Assert
+	if !errors.Is(err, ErrEmptyCatalog) {
+		t.Fatalf("ErrEmptyCatalog expected, got %v", err)
+	}
+}
+
+func testInstitutionWatcherZeroValue(t *testing.T) {
+	// What/Arrange
+	api := startCatalogApi()
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := &InstitutionWatcher{Client: createTestNordigen(srv)}
+	if _, err := underTest.Check(context.Background(), "DE"); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	api.mu.Lock()
+	delete(api.institutions, "N26_NTSBDEB1")
+	api.mu.Unlock()
+
+	// When/Act
+	changes, err := underTest.Check(context.Background(), "DE")
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(changes) != 1 || changes[0].Kind != InstitutionRemoved {
+		t.Fatalf("removal expected to be detected in memory, got %+v", changes)
+	}
+}
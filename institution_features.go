@@ -0,0 +1,96 @@
+package nordigen
+
+// InstitutionFeature a feature supported by an institution
+type InstitutionFeature string
+
+const (
+	// FeatureAccountSelection the end user can choose the accounts to link, see CreateRequisitionRequest.AccountSelection
+	FeatureAccountSelection InstitutionFeature = "account_selection"
+	// FeatureBusinessAccounts business accounts can be linked
+	FeatureBusinessAccounts InstitutionFeature = "business_accounts"
+	// FeatureCardAccounts card accounts can be linked
+	FeatureCardAccounts InstitutionFeature = "card_accounts"
+	// FeatureCorporateAccounts corporate accounts can be linked
+	FeatureCorporateAccounts InstitutionFeature = "corporate_accounts"
+	// FeaturePrivateAccounts private accounts can be linked
+	FeaturePrivateAccounts InstitutionFeature = "private_accounts"
+	// FeaturePendingTransactions pending transactions are reported
+	FeaturePendingTransactions InstitutionFeature = "pending_transactions"
+	// FeatureSeparateContinuousHistoryConsent the access to the transactions older than 90 days
+	// requires a separate consent of the end user
+	FeatureSeparateContinuousHistoryConsent InstitutionFeature = "separate_continuous_history_consent"
+	// FeaturePayments payments can be initiated
+	FeaturePayments InstitutionFeature = "payments"
+)
+
+// Payment types of SupportedPayments
+const (
+	PaymentTypeSingle   = "single-payment"
+	PaymentTypeBulk     = "bulk-payment"
+	PaymentTypePeriodic = "periodic-payment"
+)
+
+// SupportedPayments payment schemes per payment type
+type SupportedPayments map[string][]string
+
+// Supports reports whether the payment type is supported with the scheme, with any scheme if empty
+func (p SupportedPayments) Supports(paymentType, scheme string) bool {
+	schemes, ok := p[paymentType]
+	if !ok {
+		return false
+	}
+
+	if scheme == "" {
+		return true
+	}
+
+	for _, s := range schemes {
+		if s == scheme {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HasFeature reports whether the institution supports the feature
+func (i *InstitutionResponse) HasFeature(feature InstitutionFeature) bool {
+	for _, f := range i.SupportedFeatures {
+		if f == feature {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SupportsAccountSelection reports whether the end user can choose the accounts to link
+func (i *InstitutionResponse) SupportsAccountSelection() bool {
+	return i.HasFeature(FeatureAccountSelection)
+}
+
+// SupportsPendingTransactions reports whether the pending transactions are reported
+func (i *InstitutionResponse) SupportsPendingTransactions() bool {
+	return i.HasFeature(FeaturePendingTransactions)
+}
+
+// SupportsBusinessAccounts reports whether business or corporate accounts can be linked
+func (i *InstitutionResponse) SupportsBusinessAccounts() bool {
+	return i.HasFeature(FeatureBusinessAccounts) || i.HasFeature(FeatureCorporateAccounts)
+}
+
+// SupportsCardAccounts reports whether card accounts can be linked
+func (i *InstitutionResponse) SupportsCardAccounts() bool {
+	return i.HasFeature(FeatureCardAccounts)
+}
+
+// RequiresSeparateHistoryConsent reports whether the transactions older than 90 days
+// require a separate consent of the end user
+func (i *InstitutionResponse) RequiresSeparateHistoryConsent() bool {
+	return i.HasFeature(FeatureSeparateContinuousHistoryConsent)
+}
+
+// SupportsPayments reports whether payments can be initiated
+func (i *InstitutionResponse) SupportsPayments() bool {
+	return len(i.SupportedPayments) > 0 || i.HasFeature(FeaturePayments)
+}
@@ -0,0 +1,57 @@
+package nordigen
+
+import (
+	"testing"
+)
+
+var testFeaturesInstitution = &InstitutionResponse{
+	ID:                "N26_NTSBDEB1",
+	SupportedPayments: SupportedPayments{PaymentTypeSingle: {"SCT", "ISCT"}},
+	SupportedFeatures: []InstitutionFeature{
+		FeatureAccountSelection,
+		FeatureCorporateAccounts,
+		FeatureSeparateContinuousHistoryConsent,
+		"unknown_feature",
+	},
+}
+
+type institutionFeatureTestCase struct {
+	name      string
+	predicate func(i *InstitutionResponse) bool
+	expected  bool
+}
+
+var institutionFeatureTestCases = []*institutionFeatureTestCase{
+	{"account selection", (*InstitutionResponse).SupportsAccountSelection, true},
+	{"pending transactions", (*InstitutionResponse).SupportsPendingTransactions, false},
+	{"business accounts via corporate accounts", (*InstitutionResponse).SupportsBusinessAccounts, true},
+	{"card accounts", (*InstitutionResponse).SupportsCardAccounts, false},
+	{"separate history consent", (*InstitutionResponse).RequiresSeparateHistoryConsent, true},
+	{"payments", (*InstitutionResponse).SupportsPayments, true},
+	{"unknown feature", func(i *InstitutionResponse) bool { return i.HasFeature("unknown_feature") }, true},
+	{"single payment with SCT", func(i *InstitutionResponse) bool {
+		return i.SupportedPayments.Supports(PaymentTypeSingle, "SCT")
+	}, true},
+	{"periodic payment", func(i *InstitutionResponse) bool {
+		return i.SupportedPayments.Supports(PaymentTypePeriodic, "")
+	}, false},
+}
+
+func TestInstitutionResponse_features(t *testing.T) {
+	t.Parallel()
+	for _, tc := range institutionFeatureTestCases {
+		t.Run(tc.name, testInstitutionFeature(tc))
+	}
+}
+
+func testInstitutionFeature(tc *institutionFeatureTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// When/Act
+		actual := tc.predicate(testFeaturesInstitution)
+
+		// Then/Assert
+		if actual != tc.expected {
+			t.Fatalf("%t expected, %t returned", tc.expected, actual)
+		}
+	}
+}
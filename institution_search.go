@@ -0,0 +1,157 @@
+package nordigen
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// accentFolding replacements of the accented Latin letters used in the institution names
+var accentFolding = map[rune]string{
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ė': "e", 'ę': "e", 'ě': "e",
+	'ģ': "g", 'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'į': "i", 'ı': "i",
+	'ķ': "k", 'ĺ': "l", 'ļ': "l", 'ľ': "l", 'ł': "l", 'ñ': "n", 'ń': "n", 'ņ': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ŕ': "r", 'ř': "r", 'ś': "s", 'ş': "s", 'š': "s", 'ș': "s", 'ß': "ss",
+	'ţ': "t", 'ť': "t", 'ț': "t", 'þ': "th", 'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u",
+	'ů': "u", 'ű': "u", 'ų': "u", 'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+}
+
+// normalizeName lowercases the name, folds the accents and splits it into alphanumeric tokens
+func normalizeName(name string) []string {
+	b := strings.Builder{}
+	for _, r := range strings.ToLower(name) {
+		if folded, ok := accentFolding[r]; ok {
+			b.WriteString(folded)
+		} else if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune(' ')
+		}
+	}
+
+	return strings.Fields(b.String())
+}
+
+// nameMatchScore scores how well the query tokens match the name tokens, lower is better.
+// Returns false if any query token doesn't match
+func nameMatchScore(query, name []string) (int, bool) {
+	total := 0
+	for _, q := range query {
+		best := -1
+		for _, n := range name {
+			score, ok := tokenMatchScore(q, n)
+			if ok && (best < 0 || score < best) {
+				best = score
+			}
+		}
+
+		if best < 0 {
+			return 0, false
+		}
+		total += best
+	}
+
+	return total, true
+}
+
+func tokenMatchScore(query, token string) (int, bool) {
+	switch {
+	case query == token:
+		return 0, true
+	case strings.HasPrefix(token, query):
+		return 1, true
+	case strings.Contains(token, query):
+		return 2, true
+	}
+
+	// typos are tolerated in longer tokens only, compared to the token's prefixes of about the query's length
+	queryLen, runes := utf8.RuneCountInString(query), []rune(token)
+	allowed := queryLen / 4
+	if allowed == 0 {
+		return 0, false
+	}
+
+	best := allowed + 1
+	for length := queryLen - allowed; length <= queryLen+allowed && length <= len(runes); length++ {
+		if distance := levenshtein(query, string(runes[:length])); distance < best {
+			best = distance
+		}
+	}
+
+	if best <= allowed {
+		return 3 + best, true
+	}
+
+	return 0, false
+}
+
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+
+			curr[j] = minInt(minInt(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+// searchInstitutions returns the institutions whose name matches the query, the best matches first
+func searchInstitutions(institutions []InstitutionResponse, names [][]string, query string) []InstitutionResponse {
+	queryTokens := normalizeName(query)
+	if len(queryTokens) == 0 {
+		return institutions
+	}
+
+	type match struct {
+		institution InstitutionResponse
+		score       int
+	}
+
+	matches := make([]match, 0)
+	for i, institution := range institutions {
+		if score, ok := nameMatchScore(queryTokens, names[i]); ok {
+			matches = append(matches, match{institution, score})
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score < matches[j].score
+		}
+
+		return matches[i].institution.Name < matches[j].institution.Name
+	})
+
+	result := make([]InstitutionResponse, 0, len(matches))
+	for _, m := range matches {
+		result = append(result, m.institution)
+	}
+
+	return result
+}
@@ -0,0 +1,97 @@
+package nordigen
+
+import (
+	"testing"
+)
+
+var testSearchInstitutions = []InstitutionResponse{
+	{ID: "SPARKASSE_KOELNBONN", Name: "Sparkasse KölnBonn"},
+	{ID: "CAIXA_CGDIPTPL", Name: "Caixa Geral de Depósitos"},
+	{ID: "DEUTSCHE_BANK_DEUTDEFF", Name: "Deutsche Bank"},
+	{ID: "DEUTSCHE_KREDIT_BANK", Name: "Deutsche Kreditbank"},
+	{ID: "SKANDIA_SKIASESS", Name: "Skandiabanken"},
+}
+
+type institutionSearchTestCase struct {
+	name     string
+	query    string
+	expected []string
+}
+
+var institutionSearchTestCases = []*institutionSearchTestCase{
+	{"exact name", "Deutsche Bank", []string{"DEUTSCHE_BANK_DEUTDEFF", "DEUTSCHE_KREDIT_BANK"}},
+	{"prefix", "deutsche kredit", []string{"DEUTSCHE_KREDIT_BANK"}},
+	{"without accents", "sparkasse koln", []string{"SPARKASSE_KOELNBONN"}},
+	{"with accents", "depósitos", []string{"CAIXA_CGDIPTPL"}},
+	{"accents in the query only", "Skandiabankén", []string{"SKANDIA_SKIASESS"}},
+	{"typo", "deutshe", []string{"DEUTSCHE_BANK_DEUTDEFF", "DEUTSCHE_KREDIT_BANK"}},
+	{"no match", "revolut", []string{}},
+}
+
+func TestSearchInstitutions(t *testing.T) {
+	t.Parallel()
+	for _, tc := range institutionSearchTestCases {
+		t.Run(tc.name, testSearchInstitutionsByName(tc))
+	}
+}
+
+func testSearchInstitutionsByName(tc *institutionSearchTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// What/Arrange
+		names := make([][]string, len(testSearchInstitutions))
+		for i, institution := range testSearchInstitutions {
+			names[i] = normalizeName(institution.Name)
+		}
+
+		// When/Act
+		result := searchInstitutions(testSearchInstitutions, names, tc.query)
+
+		// Then/Assert
+		if len(result) != len(tc.expected) {
+			t.Fatalf("%v expected, %v found", tc.expected, result)
+		}
+
+		for i, ID := range tc.expected {
+			if result[i].ID != ID {
+				t.Fatalf("%v expected in the order, %v found", tc.expected, result)
+			}
+		}
+	}
+}
+
+func TestNormalizeName(t *testing.T) {
+	// When/Act
+	tokens := normalizeName("Crédit Agricole – Île-de-France (Straße)")
+
+	// Then/Assert
+	expected := []string{"credit", "agricole", "ile", "de", "france", "strasse"}
+	if len(tokens) != len(expected) {
+		t.Fatalf("%v expected, %v returned", expected, tokens)
+	}
+
+	for i := range expected {
+		if tokens[i] != expected[i] {
+			t.Fatalf("%v expected, %v returned", expected, tokens)
+		}
+	}
+}
+
+func Test_tokenMatchScore(t *testing.T) {
+	for _, tc := range []struct {
+		query, token string
+		score        int
+		ok           bool
+	}{
+		{query: "приватбанк", token: "пріватбанк", score: 4, ok: true},
+		{query: "банк", token: "бнак", ok: false},
+		{query: "deutsche", token: "deutche", score: 4, ok: true},
+	} {
+		// When/Act
+		score, ok := tokenMatchScore(tc.query, tc.token)
+
+		// Then/Assert
+		if score != tc.score || ok != tc.ok {
+			t.Errorf("%s in %s: %d %t expected, %d %t returned", tc.query, tc.token, tc.score, tc.ok, score, ok)
+		}
+	}
+}
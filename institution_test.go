@@ -91,6 +91,7 @@ func testInstitutionListApiError(client *Nordigen) error {
 func TestInstitutionResource_Get(t *testing.T) {
 	t.Parallel()
 	t.Run("getting institutions success", testInstitutionGetOk)
+	t.Run("getting institutions with full metadata", testInstitutionGetFullMetadata)
 	t.Run("getting institutions API error", testApiErrorResponse(testInstitutionGetApiError))
 }
 
@@ -152,6 +153,52 @@ func testInstitutionGetOk(t *testing.T) {
 	}
 }
 
+func testInstitutionGetFullMetadata(t *testing.T) {
+	// What/Arrange
+	responsePayload := `{
+		"id": "N26_NTSBDEB1",
+		"name": "N26 Bank",
+		"bic": "NTSBDEB1",
+		"transaction_total_days": "730",
+		"max_access_valid_for_days": "90",
+		"countries": ["DE"],
+		"logo": "https://cdn.nordigen.com/ais/N26_SANDBOX_NTSBDEB1.png",
+		"supported_payments": {"single-payment": ["SCT", "ISCT"]},
+		"supported_features": ["account_selection", "pending_transactions", "separate_continuous_history_consent"],
+		"identification_codes": ["NTSBDEB1XXX"]
+	}`
+	srv := startServerWithAutoAuth(responsePayload, http.StatusOK)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Institution()
+
+	// When/Act
+	institution, err := underTest.Get("N26_NTSBDEB1")
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error while successful API response: %s", err)
+	}
+
+	if institution.MaxAccessValidForDays != 90 {
+		t.Fatalf("expected max_access_valid_for_days is 90, %d returned", institution.MaxAccessValidForDays)
+	}
+
+	if !institution.SupportedPayments.Supports(PaymentTypeSingle, "ISCT") {
+		t.Fatalf("expected single payments with ISCT scheme, %v returned", institution.SupportedPayments)
+	}
+
+	if len(institution.SupportedFeatures) != 3 || institution.SupportedFeatures[0] != FeatureAccountSelection {
+		t.Fatalf("expected 3 supported features, %v returned", institution.SupportedFeatures)
+	}
+
+	if len(institution.IdentificationCodes) != 1 || institution.IdentificationCodes[0] != "NTSBDEB1XXX" {
+		t.Fatalf("expected identification code \"NTSBDEB1XXX\", %v returned", institution.IdentificationCodes)
+	}
+}
+
 func testInstitutionGetApiError(client *Nordigen) error {
 	underTest := client.Institution()
 	_, err := underTest.Get("DIREKT_HELADEF1822")
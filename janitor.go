@@ -0,0 +1,328 @@
+package nordigen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// JanitorKind the kind of the resource cleaned up by Janitor
+type JanitorKind string
+
+const (
+	JanitorKindRequisition JanitorKind = "requisition"
+	JanitorKindAgreement   JanitorKind = "agreement"
+)
+
+// ErrAgreementInUse reported for the agreement which is not deleted, because the deletion of its requisition failed
+var ErrAgreementInUse = errors.New("agreement is used by a requisition which could not be deleted")
+
+// JanitorRule selects the requisitions and agreements to delete.
+// A nil function doesn't select any resource of the kind
+type JanitorRule struct {
+	// Name reported as the reason of the deletion
+	Name        string
+	Requisition func(requisition *RequisitionResponse, now time.Time) bool
+	Agreement   func(agreement *EndUserAgreementResponse, now time.Time) bool
+}
+
+// OlderThan selects the requisitions and the agreements created more than age ago.
+// The linked requisitions are still in use and are selected only if RequisitionStatusLinked is given
+// explicitly. If statuses are given, only the requisitions in any of them are selected, the agreements
+// have no status and are not selected then
+func OlderThan(age time.Duration, statuses ...string) JanitorRule {
+	rule := JanitorRule{
+		Name: "older than " + age.String(),
+		Requisition: func(requisition *RequisitionResponse, now time.Time) bool {
+			if !requisition.Created.Before(now.Add(-age)) {
+				return false
+			}
+			if len(statuses) == 0 {
+				return requisition.Status != RequisitionStatusLinked
+			}
+
+			return StatusIs(statuses...)(requisition)
+		},
+	}
+
+	if len(statuses) == 0 {
+		rule.Agreement = func(agreement *EndUserAgreementResponse, now time.Time) bool {
+			return agreement.Created.Before(now.Add(-age))
+		}
+	}
+
+	return rule
+}
+
+// InStatus selects the requisitions in any of the statuses created more than minAge ago,
+// e.g. InStatus(time.Hour, RequisitionStatusCreated, RequisitionStatusUndergoingAuthentication)
+func InStatus(minAge time.Duration, statuses ...string) JanitorRule {
+	return JanitorRule{
+		Name: "status " + strings.Join(statuses, ","),
+		Requisition: func(requisition *RequisitionResponse, now time.Time) bool {
+			return requisition.Created.Before(now.Add(-minAge)) && StatusIs(statuses...)(requisition)
+		},
+	}
+}
+
+// NeverAccepted selects the agreements not accepted by the end user within minAge since their creation
+func NeverAccepted(minAge time.Duration) JanitorRule {
+	return JanitorRule{
+		Name: "never accepted",
+		Agreement: func(agreement *EndUserAgreementResponse, now time.Time) bool {
+			return agreement.Accepted == nil && agreement.Created.Before(now.Add(-minAge))
+		},
+	}
+}
+
+// ExpiredAccess selects the expired requisitions and the agreements whose access has expired
+func ExpiredAccess() JanitorRule {
+	return JanitorRule{
+		Name: "expired",
+		Requisition: func(requisition *RequisitionResponse, _ time.Time) bool {
+			return requisition.Status == RequisitionStatusExpired
+		},
+		Agreement: func(agreement *EndUserAgreementResponse, now time.Time) bool {
+			expires, ok := AgreementExpiry(agreement)
+			return ok && !now.Before(expires)
+		},
+	}
+}
+
+// JanitorEntry a resource selected for the deletion
+type JanitorEntry struct {
+	Kind    JanitorKind
+	ID      uuid.UUID
+	Created time.Time
+	// Reason the name of the first matching rule
+	Reason  string
+	Deleted bool
+	Err     error
+}
+
+// JanitorReport the result of Janitor.Run
+type JanitorReport struct {
+	DryRun  bool
+	Entries []*JanitorEntry
+	// Kept agreements selected by the rules, but still used by the requisitions which are not deleted
+	Kept []*JanitorEntry
+}
+
+// Deleted returns the entries deleted successfully
+func (r *JanitorReport) Deleted() []*JanitorEntry {
+	deleted := make([]*JanitorEntry, 0, len(r.Entries))
+	for _, e := range r.Entries {
+		if e.Deleted {
+			deleted = append(deleted, e)
+		}
+	}
+
+	return deleted
+}
+
+// Failed returns the entries which could not be deleted
+func (r *JanitorReport) Failed() []*JanitorEntry {
+	failed := make([]*JanitorEntry, 0)
+	for _, e := range r.Entries {
+		if e.Err != nil {
+			failed = append(failed, e)
+		}
+	}
+
+	return failed
+}
+
+// Write the report as a table of the entries
+func (r *JanitorReport) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	if _, err := fmt.Fprintln(tw, "KIND\tID\tCREATED\tREASON\tRESULT"); err != nil {
+		return err
+	}
+
+	write := func(e *JanitorEntry, result string) error {
+		_, err := fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", e.Kind, e.ID, e.Created.Format(time.RFC3339), e.Reason, result)
+		return err
+	}
+
+	for _, e := range r.Entries {
+		result := "deleted"
+		switch {
+		case e.Err != nil:
+			result = "error: " + e.Err.Error()
+		case r.DryRun:
+			result = "would delete"
+		case !e.Deleted:
+			result = "not deleted"
+		}
+
+		if err := write(e, result); err != nil {
+			return err
+		}
+	}
+
+	for _, e := range r.Kept {
+		if err := write(e, "kept, in use"); err != nil {
+			return err
+		}
+	}
+
+	return tw.Flush()
+}
+
+// Janitor deletes stale requisitions and agreements selected by the rules. Create it with NewJanitor
+// or as a literal
+type Janitor struct {
+	Client *Nordigen
+	Rules  []JanitorRule
+	// DryRun only reports the resources to delete
+	DryRun bool
+	// Limiter throttles the deletions. Deletions are not throttled if nil
+	Limiter RateLimiter
+	now     func() time.Time
+}
+
+// NewJanitor creates a janitor deleting the resources matching any of the rules
+func NewJanitor(n *Nordigen, rules ...JanitorRule) *Janitor {
+	return &Janitor{
+		Client: n,
+		Rules:  rules,
+		now:    time.Now,
+	}
+}
+
+// Run lists all the requisitions and agreements and deletes the ones matching any of the rules,
+// the requisitions first. An agreement is kept while it's used by a requisition which is not deleted,
+// including the requisitions whose deletion failed.
+// The report is returned along with the error if listing failed or the context was canceled,
+// the deletion errors are reported in the entries only
+func (j *Janitor) Run(ctx context.Context) (*JanitorReport, error) {
+	report := &JanitorReport{DryRun: j.DryRun}
+	now := j.clock()
+
+	requisitionList, err := j.Client.Requisition().List()
+	if err != nil {
+		return report, errors.Wrap(err, "error listing requisitions")
+	}
+
+	requisitions, err := requisitionList.All(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "error listing requisitions")
+	}
+
+	agreementList, err := j.Client.EndUserAgreement().List()
+	if err != nil {
+		return report, errors.Wrap(err, "error listing agreements")
+	}
+
+	agreements, err := agreementList.All(ctx)
+	if err != nil {
+		return report, errors.Wrap(err, "error listing agreements")
+	}
+
+	inUse := make(map[uuid.UUID]bool)
+	// deletedWith the requisitions to delete by their agreements
+	deletedWith := make(map[uuid.UUID][]*JanitorEntry)
+	for i := range requisitions {
+		requisition := &requisitions[i]
+		if reason, ok := j.matchRequisition(requisition, now); ok {
+			entry := &JanitorEntry{
+				Kind:    JanitorKindRequisition,
+				ID:      requisition.ID,
+				Created: requisition.Created,
+				Reason:  reason,
+			}
+			report.Entries = append(report.Entries, entry)
+			deletedWith[requisition.AgreementID] = append(deletedWith[requisition.AgreementID], entry)
+			continue
+		}
+
+		inUse[requisition.AgreementID] = true
+	}
+
+	for i := range agreements {
+		agreement := &agreements[i]
+		reason, ok := j.matchAgreement(agreement, now)
+		if !ok {
+			continue
+		}
+
+		entry := &JanitorEntry{
+			Kind:    JanitorKindAgreement,
+			ID:      agreement.ID,
+			Created: agreement.Created,
+			Reason:  reason,
+		}
+
+		if inUse[agreement.ID] {
+			report.Kept = append(report.Kept, entry)
+		} else {
+			report.Entries = append(report.Entries, entry)
+		}
+	}
+
+	if j.DryRun {
+		return report, nil
+	}
+
+	for _, entry := range report.Entries {
+		if err := waitForLimiter(ctx, j.Limiter); err != nil {
+			return report, err
+		}
+
+		// the requisitions precede the agreements, so their deletion outcome is known here
+		if entry.Kind == JanitorKindRequisition {
+			entry.Err = ignoreNotFound(j.Client.Requisition().Delete(entry.ID))
+		} else if allDeleted(deletedWith[entry.ID]) {
+			entry.Err = ignoreNotFound(j.Client.EndUserAgreement().Delete(entry.ID))
+		} else {
+			entry.Err = ErrAgreementInUse
+		}
+		entry.Deleted = entry.Err == nil
+	}
+
+	return report, nil
+}
+
+func allDeleted(entries []*JanitorEntry) bool {
+	for _, e := range entries {
+		if !e.Deleted {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (j *Janitor) clock() time.Time {
+	if j.now == nil {
+		return time.Now()
+	}
+
+	return j.now()
+}
+
+func (j *Janitor) matchRequisition(requisition *RequisitionResponse, now time.Time) (string, bool) {
+	for _, rule := range j.Rules {
+		if rule.Requisition != nil && rule.Requisition(requisition, now) {
+			return rule.Name, true
+		}
+	}
+
+	return "", false
+}
+
+func (j *Janitor) matchAgreement(agreement *EndUserAgreementResponse, now time.Time) (string, bool) {
+	for _, rule := range j.Rules {
+		if rule.Agreement != nil && rule.Agreement(agreement, now) {
+			return rule.Name, true
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,212 @@
+package nordigen
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+type janitorFixture struct {
+	staleCreated     *RequisitionResponse
+	freshCreated     *RequisitionResponse
+	linked           *RequisitionResponse
+	expired          *RequisitionResponse
+	unusedAgreement  *EndUserAgreementResponse
+	linkedAgreement  *EndUserAgreementResponse
+	expiredAgreement *EndUserAgreementResponse
+}
+
+func createJanitorFixture(api *fakeApi) *janitorFixture {
+	now := time.Now()
+	old := now.AddDate(0, 0, -100)
+	f := &janitorFixture{}
+
+	f.unusedAgreement = api.addAgreement(&EndUserAgreementResponse{Created: now.AddDate(0, 0, -2)})
+	f.linkedAgreement = api.addAgreement(&EndUserAgreementResponse{Created: now.AddDate(0, 0, -2)})
+	f.expiredAgreement = api.addAgreement(&EndUserAgreementResponse{Created: old, Accepted: &old, AccessValidForDays: 90})
+
+	f.staleCreated = api.addRequisition(&RequisitionResponse{Created: now.AddDate(0, 0, -2), Status: RequisitionStatusCreated})
+	f.freshCreated = api.addRequisition(&RequisitionResponse{Created: now, Status: RequisitionStatusCreated})
+	f.linked = api.addRequisition(&RequisitionResponse{
+		Created:     now.AddDate(0, 0, -2),
+		Status:      RequisitionStatusLinked,
+		AgreementID: f.linkedAgreement.ID,
+	})
+	f.expired = api.addRequisition(&RequisitionResponse{
+		Created:     old,
+		Status:      RequisitionStatusExpired,
+		AgreementID: f.expiredAgreement.ID,
+	})
+
+	return f
+}
+
+func createTestJanitor(client *Nordigen) *Janitor {
+	return NewJanitor(
+		client,
+		InStatus(24*time.Hour, RequisitionStatusCreated, RequisitionStatusUndergoingAuthentication),
+		NeverAccepted(24*time.Hour),
+		ExpiredAccess(),
+	)
+}
+
+func TestJanitor_Run(t *testing.T) {
+	t.Parallel()
+	t.Run("janitor dry run", testJanitorDryRun)
+	t.Run("janitor run", testJanitorRun)
+	t.Run("janitor failed requisition deletion", testJanitorFailedRequisition)
+}
+
+func testJanitorDryRun(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	f := createJanitorFixture(api)
+
+	underTest := createTestJanitor(createTestNordigen(srv))
+	underTest.DryRun = true
+
+	// When/Act
+	report, err := underTest.Run(context.Background())
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(api.deleted) != 0 || len(report.Deleted()) != 0 {
+		t.Fatalf("nothing expected to be deleted in dry run, %v deleted", api.deleted)
+	}
+
+	expected := map[uuid.UUID]string{
+		f.staleCreated.ID:     "status CR,UA",
+		f.expired.ID:          "expired",
+		f.unusedAgreement.ID:  "never accepted",
+		f.expiredAgreement.ID: "expired",
+	}
+	if len(report.Entries) != len(expected) {
+		t.Fatalf("%d candidates expected, %d reported", len(expected), len(report.Entries))
+	}
+
+	for _, e := range report.Entries {
+		if expected[e.ID] != e.Reason {
+			t.Fatalf(`%s %s expected to be reported with the reason "%s", "%s" reported`, e.Kind, e.ID, expected[e.ID], e.Reason)
+		}
+	}
+
+	if len(report.Kept) != 1 || report.Kept[0].ID != f.linkedAgreement.ID {
+		t.Fatalf("agreement of the linked requisition expected to be kept, %v kept", report.Kept)
+	}
+
+	out := &bytes.Buffer{}
+	if err := report.Write(out); err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Count(out.String(), "would delete") != 4 || !strings.Contains(out.String(), "kept, in use") {
+		t.Fatalf("dry run report expected, %s written", out)
+	}
+}
+
+func testJanitorRun(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	f := createJanitorFixture(api)
+
+	underTest := createTestJanitor(createTestNordigen(srv))
+	underTest.Limiter = NewIntervalLimiter(time.Millisecond)
+
+	// When/Act
+	report, err := underTest.Run(context.Background())
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(report.Deleted()) != 4 || len(report.Failed()) != 0 || len(api.deleted) != 4 {
+		t.Fatalf("4 resources expected to be deleted, %v deleted", api.deleted)
+	}
+
+	if api.deleted[0] != "requisition:"+f.staleCreated.ID.String() && api.deleted[0] != "requisition:"+f.expired.ID.String() {
+		t.Fatalf("requisitions expected to be deleted first, %v deleted", api.deleted)
+	}
+
+	for _, kept := range []uuid.UUID{f.freshCreated.ID, f.linked.ID} {
+		if _, ok := api.requisitions[kept]; !ok {
+			t.Fatalf("requisition %s expected to be kept", kept)
+		}
+	}
+
+	if _, ok := api.agreements[f.linkedAgreement.ID]; !ok {
+		t.Fatal("agreement of the linked requisition expected to be kept")
+	}
+}
+
+func testJanitorFailedRequisition(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	f := createJanitorFixture(api)
+	api.failDeletes[f.expired.ID] = true
+
+	underTest := &Janitor{Client: createTestNordigen(srv), Rules: []JanitorRule{ExpiredAccess()}}
+
+	// When/Act
+	report, err := underTest.Run(context.Background())
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if _, ok := api.agreements[f.expiredAgreement.ID]; !ok {
+		t.Fatal("agreement of the requisition which could not be deleted expected to be kept")
+	}
+
+	failed := report.Failed()
+	if len(failed) != 2 || failed[1].ID != f.expiredAgreement.ID || !errors.Is(failed[1].Err, ErrAgreementInUse) {
+		t.Fatalf("failed requisition and its kept agreement expected to be reported, %v reported", failed)
+	}
+}
+
+func TestOlderThan(t *testing.T) {
+	// What/Arrange
+	old := &EndUserAgreementResponse{Created: time.Now().AddDate(0, 0, -10)}
+	linked := &RequisitionResponse{Created: old.Created, Status: RequisitionStatusLinked}
+	expired := &RequisitionResponse{Created: old.Created, Status: RequisitionStatusExpired}
+
+	// When/Act
+	all := OlderThan(time.Hour)
+	inStatus := OlderThan(time.Hour, RequisitionStatusCreated)
+	withLinked := OlderThan(time.Hour, RequisitionStatusLinked)
+
+	// Then/Assert
+	if all.Requisition(linked, time.Now()) || !all.Requisition(expired, time.Now()) {
+		t.Fatal("old requisitions except the linked ones expected to be selected without statuses")
+	}
+
+	if !withLinked.Requisition(linked, time.Now()) {
+		t.Fatal("old linked requisitions expected to be selected when the status is given")
+	}
+
+	if all.Agreement == nil || !all.Agreement(old, time.Now()) {
+		t.Fatal("old agreements expected to be selected without statuses")
+	}
+
+	if inStatus.Agreement != nil {
+		t.Fatal("agreements expected not to be selected by the status rule")
+	}
+}
@@ -0,0 +1,359 @@
+package nordigen
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// LinkState the state of a bank linking flow
+type LinkState string
+
+// Bank linking flow states
+const (
+	LinkStateInstitutionChosen  LinkState = "institution_chosen"
+	LinkStateAgreementCreated   LinkState = "agreement_created"
+	LinkStateRequisitionCreated LinkState = "requisition_created"
+	LinkStateAwaitingUser       LinkState = "awaiting_user"
+	LinkStateLinked             LinkState = "linked"
+	LinkStateFailed             LinkState = "failed"
+	LinkStateExpired            LinkState = "expired"
+)
+
+// IsFinal reports whether the flow can't advance from the state anymore
+func (s LinkState) IsFinal() bool {
+	return s == LinkStateLinked || s == LinkStateFailed || s == LinkStateExpired
+}
+
+// ErrLinkFlowFinished returned when advancing a flow which is already linked, failed or expired
+var ErrLinkFlowFinished = errors.New("link flow is finished")
+
+// LinkFlowParams parameters of a bank linking flow
+type LinkFlowParams struct {
+	// ID of the flow, e.g. the session ID of the end user. A random UUID is used if empty
+	ID string `json:"id"`
+	// InstitutionID the chosen institution
+	InstitutionID string `json:"institution_id"`
+	// Redirect URL to your application after end-user authorization with ASPSP
+	Redirect string `json:"redirect"`
+	// Reference of the requisition. The flow ID is used if empty
+	Reference string `json:"reference"`
+	// UserLanguage a two-letter country code (ISO 639-1)
+	UserLanguage string `json:"user_language"`
+	// MaxHistoricalDays of the end user agreement. The API default is used if zero
+	MaxHistoricalDays int `json:"max_historical_days"`
+	// AccessValidForDays of the end user agreement. The API default is used if zero
+	AccessValidForDays int `json:"access_valid_for_days"`
+	// AccessScope of the end user agreement. The API default is used if empty
+	AccessScope []string `json:"access_scope"`
+	// AccountSelection option to enable account selection view for the end user
+	AccountSelection bool `json:"account_selection"`
+	// RedirectImmediate enable redirect back to the client after account list received
+	RedirectImmediate bool `json:"redirect_immediate"`
+}
+
+// LinkFlowRecord the persisted progress of a bank linking flow
+type LinkFlowRecord struct {
+	LinkFlowParams
+	State         LinkState   `json:"state"`
+	AgreementID   uuid.UUID   `json:"agreement_id"`
+	RequisitionID uuid.UUID   `json:"requisition_id"`
+	Link          string      `json:"link"`
+	AccountIDs    []uuid.UUID `json:"account_ids"`
+	// RequisitionStatus the last known status of the requisition
+	RequisitionStatus string `json:"requisition_status"`
+	// Error of the last failed step
+	Error   string    `json:"error"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+}
+
+// LinkFlow the flow of linking an end user's bank accounts:
+// institution chosen -> agreement created -> requisition created -> awaiting user -> linked, failed or expired.
+// Every transition is persisted to the store, so the flow can be resumed with ResumeLinkFlow after a restart.
+// LinkFlow is not safe for concurrent use
+type LinkFlow struct {
+	nordigen *Nordigen
+	store    LinkFlowStore
+	record   *LinkFlowRecord
+}
+
+// NewLinkFlow creates a flow for the chosen institution and persists it
+func (n *Nordigen) NewLinkFlow(ctx context.Context, store LinkFlowStore, params *LinkFlowParams) (*LinkFlow, error) {
+	if params.InstitutionID == "" {
+		return nil, errors.New("institution ID can't be empty")
+	}
+
+	now := time.Now()
+	record := &LinkFlowRecord{
+		LinkFlowParams: *params,
+		State:          LinkStateInstitutionChosen,
+		Created:        now,
+		Updated:        now,
+	}
+
+	if record.ID == "" {
+		record.ID = uuid.NewString()
+	}
+
+	if record.Reference == "" {
+		record.Reference = record.ID
+	}
+
+	if err := store.SaveLinkFlow(ctx, record); err != nil {
+		return nil, errors.Wrap(err, "error saving link flow")
+	}
+
+	return &LinkFlow{nordigen: n, store: store, record: record}, nil
+}
+
+// ResumeLinkFlow loads the flow with the given ID from the store
+func (n *Nordigen) ResumeLinkFlow(ctx context.Context, store LinkFlowStore, ID string) (*LinkFlow, error) {
+	record, err := store.LoadLinkFlow(ctx, ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error loading link flow")
+	}
+
+	return &LinkFlow{nordigen: n, store: store, record: record}, nil
+}
+
+// ID of the flow
+func (f *LinkFlow) ID() string {
+	return f.record.ID
+}
+
+// State of the flow
+func (f *LinkFlow) State() LinkState {
+	return f.record.State
+}
+
+// Record returns a copy of the flow's progress
+func (f *LinkFlow) Record() LinkFlowRecord {
+	return *f.record
+}
+
+// Start advances the flow until the end user has to follow the link and returns the link
+func (f *LinkFlow) Start(ctx context.Context) (string, error) {
+	for f.record.State != LinkStateAwaitingUser {
+		if f.record.State.IsFinal() {
+			return "", ErrLinkFlowFinished
+		}
+
+		if err := f.Advance(ctx); err != nil {
+			return "", err
+		}
+	}
+
+	return f.record.Link, nil
+}
+
+// Advance executes the next step of the flow. A failed step doesn't change the state, so it can be retried.
+// If only persisting the step failed, the flow keeps the new state and the created resources in memory,
+// so the next step persists them or Abandon deletes them.
+// In the awaiting user state the requisition is checked once, use Wait to poll it
+func (f *LinkFlow) Advance(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	switch f.record.State {
+	case LinkStateInstitutionChosen:
+		return f.step(ctx, f.createAgreement)
+	case LinkStateAgreementCreated:
+		return f.step(ctx, f.createRequisition)
+	case LinkStateRequisitionCreated:
+		return f.step(ctx, func() error {
+			f.record.State = LinkStateAwaitingUser
+			return nil
+		})
+	case LinkStateAwaitingUser:
+		return f.step(ctx, func() error {
+			requisition, err := f.nordigen.Requisition().Get(f.record.RequisitionID)
+			if err != nil {
+				return errors.Wrap(err, "error getting requisition")
+			}
+
+			f.applyRequisition(requisition)
+
+			return nil
+		})
+	}
+
+	return ErrLinkFlowFinished
+}
+
+// Wait polls the requisition until the end user finishes the authorization and returns the final state.
+// Waiting errors other than the requisition reaching a terminal status are returned as is
+func (f *LinkFlow) Wait(ctx context.Context, opts *WaitOptions) (LinkState, error) {
+	if f.record.State != LinkStateAwaitingUser {
+		if f.record.State.IsFinal() {
+			return f.record.State, nil
+		}
+
+		return f.record.State, errors.New("link flow is not awaiting the end user")
+	}
+
+	requisition, err := f.nordigen.Requisition().WaitForStatus(
+		ctx,
+		f.record.RequisitionID,
+		StatusIs(RequisitionStatusLinked),
+		opts,
+	)
+
+	var statusErr *RequisitionStatusError
+	if err != nil && !(errors.As(err, &statusErr) && !statusErr.Aborted) {
+		return f.record.State, err
+	}
+
+	if err := f.step(ctx, func() error {
+		f.applyRequisition(requisition)
+		return nil
+	}); err != nil {
+		return f.record.State, err
+	}
+
+	return f.record.State, nil
+}
+
+// Fail marks the flow as failed, e.g. when the end user reported an error in the redirect URL
+func (f *LinkFlow) Fail(ctx context.Context, reason error) error {
+	return f.step(ctx, func() error {
+		f.record.State = LinkStateFailed
+		if reason != nil {
+			f.record.Error = reason.Error()
+		}
+
+		return nil
+	})
+}
+
+// Abandon deletes the requisition and the agreement created by the flow if the flow is not linked
+// and removes the flow from the store
+func (f *LinkFlow) Abandon(ctx context.Context) error {
+	if f.record.State == LinkStateLinked {
+		return errors.New("linked flow can't be abandoned")
+	}
+
+	if f.record.RequisitionID != uuid.Nil {
+		if err := ignoreNotFound(f.nordigen.Requisition().Delete(f.record.RequisitionID)); err != nil {
+			return errors.Wrap(err, "error deleting requisition")
+		}
+	}
+
+	if f.record.AgreementID != uuid.Nil {
+		if err := ignoreNotFound(f.nordigen.EndUserAgreement().Delete(f.record.AgreementID)); err != nil {
+			return errors.Wrap(err, "error deleting end user agreement")
+		}
+	}
+
+	return f.store.DeleteLinkFlow(ctx, f.record.ID)
+}
+
+// CleanupLinkFlows abandons the unfinished flows not updated since the given time and the failed
+// and expired ones. The IDs of the abandoned flows are returned along with the first error occurred
+func (n *Nordigen) CleanupLinkFlows(ctx context.Context, store LinkFlowStore, notUpdatedSince time.Time) ([]string, error) {
+	records, err := store.ListLinkFlows(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "error listing link flows")
+	}
+
+	abandoned := make([]string, 0)
+	for _, record := range records {
+		if record.State == LinkStateLinked || (!record.State.IsFinal() && record.Updated.After(notUpdatedSince)) {
+			continue
+		}
+
+		flow := &LinkFlow{nordigen: n, store: store, record: record}
+		if err := flow.Abandon(ctx); err != nil {
+			return abandoned, errors.Wrap(err, "error abandoning link flow "+record.ID)
+		}
+
+		abandoned = append(abandoned, record.ID)
+	}
+
+	return abandoned, nil
+}
+
+// step applies the change to the record and persists it. The flow keeps the previous state if the change fails.
+// If persisting fails, the changed record is kept, the agreement or requisition it refers to already exists
+func (f *LinkFlow) step(ctx context.Context, change func() error) error {
+	previous := *f.record
+
+	if err := change(); err != nil {
+		f.record = &previous
+		f.record.Error = err.Error()
+		f.record.Updated = time.Now()
+		if saveErr := f.store.SaveLinkFlow(ctx, f.record); saveErr != nil {
+			return errors.Wrap(saveErr, "error saving link flow after a failed step: "+err.Error())
+		}
+
+		return err
+	}
+
+	if f.record.State != LinkStateFailed {
+		f.record.Error = ""
+	}
+	f.record.Updated = time.Now()
+
+	if err := f.store.SaveLinkFlow(ctx, f.record); err != nil {
+		return errors.Wrap(err, "error saving link flow")
+	}
+
+	return nil
+}
+
+func (f *LinkFlow) createAgreement() error {
+	agreement, err := f.nordigen.EndUserAgreement().Create(&CreateAgreementRequest{
+		InstitutionID:      f.record.InstitutionID,
+		MaxHistoricalDays:  f.record.MaxHistoricalDays,
+		AccessValidForDays: f.record.AccessValidForDays,
+		AccessScope:        f.record.AccessScope,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating end user agreement")
+	}
+
+	f.record.AgreementID = agreement.ID
+	f.record.State = LinkStateAgreementCreated
+
+	return nil
+}
+
+func (f *LinkFlow) createRequisition() error {
+	requisition, err := f.nordigen.Requisition().Create(&CreateRequisitionRequest{
+		Redirect:          f.record.Redirect,
+		InstitutionID:     f.record.InstitutionID,
+		Agreement:         f.record.AgreementID,
+		Reference:         f.record.Reference,
+		UserLanguage:      f.record.UserLanguage,
+		AccountSelection:  f.record.AccountSelection,
+		RedirectImmediate: f.record.RedirectImmediate,
+	})
+	if err != nil {
+		return errors.Wrap(err, "error creating requisition")
+	}
+
+	f.record.RequisitionID = requisition.ID
+	f.record.Link = requisition.Link
+	f.record.RequisitionStatus = requisition.Status
+	f.record.State = LinkStateRequisitionCreated
+
+	return nil
+}
+
+func (f *LinkFlow) applyRequisition(requisition *RequisitionResponse) {
+	f.record.RequisitionStatus = requisition.Status
+	f.record.AccountIDs = requisition.Accounts
+
+	switch requisition.Status {
+	case RequisitionStatusLinked:
+		f.record.State = LinkStateLinked
+	case RequisitionStatusExpired:
+		f.record.State = LinkStateExpired
+	case RequisitionStatusRejected, RequisitionStatusSuspended:
+		f.record.State = LinkStateFailed
+		f.record.Error = "requisition status " + requisition.Status
+	}
+}
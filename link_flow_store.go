@@ -0,0 +1,83 @@
+package nordigen
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLinkFlowNotFound returned by LinkFlowStore when there is no flow with the given ID
+var ErrLinkFlowNotFound = errors.New("link flow not found")
+
+// LinkFlowStore persists the progress of bank linking flows
+type LinkFlowStore interface {
+	// SaveLinkFlow creates or replaces the record with the same ID
+	SaveLinkFlow(ctx context.Context, record *LinkFlowRecord) error
+	// LoadLinkFlow returns the record with the given ID or ErrLinkFlowNotFound
+	LoadLinkFlow(ctx context.Context, ID string) (*LinkFlowRecord, error)
+	// DeleteLinkFlow deletes the record with the given ID. Deleting a missing record is not an error
+	DeleteLinkFlow(ctx context.Context, ID string) error
+	// ListLinkFlows returns all the records
+	ListLinkFlows(ctx context.Context) ([]*LinkFlowRecord, error)
+}
+
+// MemoryLinkFlowStore keeps link flows in memory. Safe for concurrent use
+type MemoryLinkFlowStore struct {
+	mu      sync.RWMutex
+	records map[string]LinkFlowRecord
+}
+
+// NewMemoryLinkFlowStore creates an empty in-memory store
+func NewMemoryLinkFlowStore() *MemoryLinkFlowStore {
+	return &MemoryLinkFlowStore{
+		records: make(map[string]LinkFlowRecord),
+	}
+}
+
+// SaveLinkFlow stores a copy of the record
+func (s *MemoryLinkFlowStore) SaveLinkFlow(_ context.Context, record *LinkFlowRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records[record.ID] = *record
+
+	return nil
+}
+
+// LoadLinkFlow returns a copy of the record with the given ID
+func (s *MemoryLinkFlowStore) LoadLinkFlow(_ context.Context, ID string) (*LinkFlowRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[ID]
+	if !ok {
+		return nil, ErrLinkFlowNotFound
+	}
+
+	return &record, nil
+}
+
+// DeleteLinkFlow deletes the record with the given ID
+func (s *MemoryLinkFlowStore) DeleteLinkFlow(_ context.Context, ID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.records, ID)
+
+	return nil
+}
+
+// ListLinkFlows returns copies of all the records
+func (s *MemoryLinkFlowStore) ListLinkFlows(_ context.Context) ([]*LinkFlowRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]*LinkFlowRecord, 0, len(s.records))
+	for _, record := range s.records {
+		record := record
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
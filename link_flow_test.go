@@ -0,0 +1,274 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestLinkFlow(t *testing.T) {
+	t.Parallel()
+	t.Run("link flow linked", testLinkFlowLinked)
+	t.Run("link flow resumed", testLinkFlowResumed)
+	t.Run("link flow expired", testLinkFlowExpired)
+	t.Run("link flow failed step", testLinkFlowFailedStep)
+	t.Run("link flow failed saving", testLinkFlowFailedSaving)
+	t.Run("link flow cleanup", testLinkFlowCleanup)
+}
+
+func testLinkFlowLinked(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	store := NewMemoryLinkFlowStore()
+
+	underTest, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{
+		InstitutionID: "TEST_INSTITUTION",
+		Redirect:      "https://example.com/callback",
+		AccessScope:   []string{"balances"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	link, err := underTest.Start(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	accountID := uuid.New()
+	api.setRequisitionStatus(underTest.Record().RequisitionID, RequisitionStatusLinked, accountID)
+
+	state, err := underTest.Wait(context.Background(), &WaitOptions{Interval: time.Millisecond})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if link == "" {
+		t.Fatal("requisition link expected")
+	}
+
+	if state != LinkStateLinked {
+		t.Fatalf("linked state expected, %s returned", state)
+	}
+
+	record, err := store.LoadLinkFlow(context.Background(), underTest.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if record.State != LinkStateLinked || len(record.AccountIDs) != 1 || record.AccountIDs[0] != accountID {
+		t.Fatalf("linked state and accounts expected to be persisted, %v persisted", record)
+	}
+
+	if record.Reference != underTest.ID() {
+		t.Fatal("flow ID expected to be used as the requisition reference")
+	}
+}
+
+func testLinkFlowResumed(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	store := NewMemoryLinkFlowStore()
+
+	flow, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{ID: "session-1", InstitutionID: "TEST_INSTITUTION"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := flow.Advance(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	underTest, err := createTestNordigen(srv).ResumeLinkFlow(context.Background(), store, "session-1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	_, err = underTest.Start(context.Background())
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if underTest.State() != LinkStateAwaitingUser {
+		t.Fatalf("awaiting user state expected, %s returned", underTest.State())
+	}
+
+	if len(api.agreements) != 1 {
+		t.Fatalf("resumed flow expected to reuse the agreement, %d agreements created", len(api.agreements))
+	}
+}
+
+func testLinkFlowExpired(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest, err := client.NewLinkFlow(context.Background(), NewMemoryLinkFlowStore(), &LinkFlowParams{InstitutionID: "TEST_INSTITUTION"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := underTest.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	api.setRequisitionStatus(underTest.Record().RequisitionID, RequisitionStatusExpired)
+
+	// When/Act
+	state, err := underTest.Wait(context.Background(), &WaitOptions{Interval: time.Millisecond})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if state != LinkStateExpired {
+		t.Fatalf("expired state expected, %s returned", state)
+	}
+
+	if err := underTest.Advance(context.Background()); !errors.Is(err, ErrLinkFlowFinished) {
+		t.Fatalf("ErrLinkFlowFinished expected when advancing a finished flow, %v returned", err)
+	}
+}
+
+func testLinkFlowFailedStep(t *testing.T) {
+	// What/Arrange
+	srv := startServerWithAutoAuth(`{"summary": "Service unavailable"}`, 503)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	store := NewMemoryLinkFlowStore()
+
+	underTest, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{InstitutionID: "TEST_INSTITUTION"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	err = underTest.Advance(context.Background())
+
+	// Then/Assert
+	if err == nil {
+		t.Fatal("error expected if API returns an error response")
+	}
+
+	record, _ := store.LoadLinkFlow(context.Background(), underTest.ID())
+	if record.State != LinkStateInstitutionChosen || record.Error == "" {
+		t.Fatalf("state expected to be kept and the error persisted, %v persisted", record)
+	}
+}
+
+// failingLinkFlowStore fails saving the flows after the given number of saves
+type failingLinkFlowStore struct {
+	*MemoryLinkFlowStore
+	saves int
+}
+
+func (s *failingLinkFlowStore) SaveLinkFlow(ctx context.Context, record *LinkFlowRecord) error {
+	if s.saves == 0 {
+		return errors.New("store unavailable")
+	}
+	s.saves--
+
+	return s.MemoryLinkFlowStore.SaveLinkFlow(ctx, record)
+}
+
+func testLinkFlowFailedSaving(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	store := &failingLinkFlowStore{MemoryLinkFlowStore: NewMemoryLinkFlowStore(), saves: 1}
+
+	underTest, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{InstitutionID: "TEST_INSTITUTION"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	advanceErr := underTest.Advance(context.Background())
+	abandonErr := underTest.Abandon(context.Background())
+
+	// Then/Assert
+	if advanceErr == nil {
+		t.Fatal("error expected if the store fails")
+	}
+
+	if underTest.Record().AgreementID == uuid.Nil {
+		t.Fatal("the created agreement expected to be kept by the flow")
+	}
+
+	if abandonErr != nil || len(api.agreements) != 0 {
+		t.Fatalf("the created agreement expected to be deleted by Abandon, %d left: %v", len(api.agreements), abandonErr)
+	}
+}
+
+func testLinkFlowCleanup(t *testing.T) {
+	// What/Arrange
+	api := newFakeApi()
+	srv := api.start()
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	store := NewMemoryLinkFlowStore()
+
+	abandoned, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{ID: "abandoned", InstitutionID: "TEST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := abandoned.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	cutoff := time.Now()
+
+	active, err := client.NewLinkFlow(context.Background(), store, &LinkFlowParams{ID: "active", InstitutionID: "TEST"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := active.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	ids, err := client.CleanupLinkFlows(context.Background(), store, cutoff)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if len(ids) != 1 || ids[0] != "abandoned" {
+		t.Fatalf("only the abandoned flow expected to be cleaned up, %v cleaned up", ids)
+	}
+
+	if len(api.requisitions) != 1 || len(api.agreements) != 1 {
+		t.Fatal("requisition and agreement of the abandoned flow expected to be deleted")
+	}
+
+	if _, err := store.LoadLinkFlow(context.Background(), "abandoned"); !errors.Is(err, ErrLinkFlowNotFound) {
+		t.Fatal("abandoned flow expected to be removed from the store")
+	}
+}
@@ -0,0 +1,289 @@
+package nordigen
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultLogoMaxSize         = 1 << 20
+	defaultLogoCacheControl    = "public, max-age=86400"
+	defaultLogoDownloadTimeout = 30 * time.Second
+)
+
+// ErrNotAnImage returned by LogoCache when the downloaded or seeded content isn't an image
+var ErrNotAnImage = errors.New("logo is not an image")
+
+// Logo an institution logo downloaded once and served locally
+type Logo struct {
+	InstitutionID string `json:"institution_id"`
+	// ContentType detected from the content, not taken from the remote server
+	ContentType string `json:"content_type"`
+	// ETag strong validator derived from the content
+	ETag string `json:"etag"`
+	// SourceUrl the logo has been downloaded from, empty if seeded
+	SourceUrl string `json:"source_url,omitempty"`
+	// Modified the time the logo has been downloaded or seeded, served as Last-Modified
+	Modified time.Time `json:"modified"`
+	Data     []byte    `json:"-"`
+}
+
+// NewLogo creates a logo of the institution detecting the content type, or returns ErrNotAnImage
+func NewLogo(institutionID string, data []byte, modified time.Time) (*Logo, error) {
+	contentType := detectLogoContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, ErrNotAnImage
+	}
+
+	sum := sha256.Sum256(data)
+
+	return &Logo{
+		InstitutionID: institutionID,
+		ContentType:   contentType,
+		ETag:          `"` + hex.EncodeToString(sum[:16]) + `"`,
+		Modified:      modified.UTC().Truncate(time.Second),
+		Data:          data,
+	}, nil
+}
+
+// detectLogoContentType sniffs the content type, recognizing SVG which is sniffed as text
+func detectLogoContentType(data []byte) string {
+	contentType := http.DetectContentType(data)
+	if strings.HasPrefix(contentType, "image/") {
+		return contentType
+	}
+
+	head := data
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+		return "image/svg+xml"
+	}
+
+	return contentType
+}
+
+// LogoCache downloads each institution logo once and serves it from the store, so the end users' browsers
+// don't hot-link the remote logos. Serves the logos as an http.Handler at ".../<institution ID>".
+// Create it with NewLogoCache or as a literal. Safe for concurrent use
+type LogoCache struct {
+	// Catalog resolves the logo URLs. Only seeded logos are served if nil
+	Catalog *InstitutionCatalog
+	Store   LogoStore
+	// HTTPClient downloads the logos, http.DefaultClient if nil
+	HTTPClient *http.Client
+	// MaxSize of a logo in bytes, 1 MiB by default
+	MaxSize int64
+	// CacheControl header of the served logos, "public, max-age=86400" by default
+	CacheControl string
+	// DownloadTimeout limits a download, 30 seconds by default. A download is shared by the concurrent callers,
+	// so it isn't canceled with the context of any of them
+	DownloadTimeout time.Duration
+
+	mu       sync.Mutex
+	inflight map[string]*logoDownload
+	now      func() time.Time
+}
+
+type logoDownload struct {
+	done chan struct{}
+	logo *Logo
+	err  error
+}
+
+// NewLogoCache creates a cache resolving the logo URLs with the catalog and keeping the logos in the store
+func NewLogoCache(catalog *InstitutionCatalog, store LogoStore) *LogoCache {
+	return &LogoCache{
+		Catalog:  catalog,
+		Store:    store,
+		inflight: make(map[string]*logoDownload),
+		now:      time.Now,
+	}
+}
+
+// Seed stores the logo of the institution without downloading it, e.g. for tests and offline use
+func (c *LogoCache) Seed(ctx context.Context, institutionID string, data []byte) (*Logo, error) {
+	logo, err := NewLogo(institutionID, data, c.clock())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := c.Store.SaveLogo(ctx, logo); err != nil {
+		return nil, errors.Wrap(err, "error saving logo")
+	}
+
+	return logo, nil
+}
+
+// Get returns the logo of the institution from the store, downloading it on the first use.
+// Concurrent calls for the same institution share a single download
+func (c *LogoCache) Get(ctx context.Context, institutionID string) (*Logo, error) {
+	logo, err := c.Store.LoadLogo(ctx, institutionID)
+	if err == nil || !errors.Is(err, ErrLogoNotFound) || c.Catalog == nil {
+		return logo, err
+	}
+
+	c.mu.Lock()
+	if c.inflight == nil {
+		c.inflight = make(map[string]*logoDownload)
+	}
+	download, ok := c.inflight[institutionID]
+	if !ok {
+		download = &logoDownload{done: make(chan struct{})}
+		c.inflight[institutionID] = download
+		go c.share(institutionID, download)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-download.done:
+		return download.logo, download.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// share downloads the logo for all the callers waiting for it
+func (c *LogoCache) share(institutionID string, download *logoDownload) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.downloadTimeout())
+	defer cancel()
+
+	download.logo, download.err = c.download(ctx, institutionID)
+
+	c.mu.Lock()
+	delete(c.inflight, institutionID)
+	close(download.done)
+	c.mu.Unlock()
+}
+
+func (c *LogoCache) download(ctx context.Context, institutionID string) (*Logo, error) {
+	institution, err := c.Catalog.ByID(ctx, "", institutionID)
+	if errors.Is(err, ErrInstitutionNotFound) {
+		return nil, ErrLogoNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if institution.LogoUrl == "" {
+		return nil, ErrLogoNotFound
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, institution.LogoUrl, nil)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating logo request")
+	}
+
+	resp, err := c.httpClient().Do(req)
+	if err != nil {
+		return nil, errors.Wrap(err, "error downloading logo")
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("error downloading logo: unexpected status %d", resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, c.maxSize()+1))
+	if err != nil {
+		return nil, errors.Wrap(err, "error downloading logo")
+	}
+	if int64(len(data)) > c.maxSize() {
+		return nil, errors.Errorf("logo exceeds %d bytes", c.maxSize())
+	}
+
+	logo, err := NewLogo(institutionID, data, c.clock())
+	if err != nil {
+		return nil, err
+	}
+	logo.SourceUrl = institution.LogoUrl
+
+	if err := c.Store.SaveLogo(ctx, logo); err != nil {
+		return nil, errors.Wrap(err, "error saving logo")
+	}
+
+	return logo, nil
+}
+
+// ServeHTTP serves the logo of the institution whose ID is the last path element,
+// answering conditional requests with ETag and Last-Modified
+func (c *LogoCache) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", http.MethodGet+", "+http.MethodHead)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	logo, err := c.Get(r.Context(), path.Base(r.URL.Path))
+	switch {
+	case errors.Is(err, ErrLogoNotFound) || errors.Is(err, ErrInvalidInstitutionID):
+		http.NotFound(w, r)
+		return
+	case err != nil:
+		http.Error(w, http.StatusText(http.StatusBadGateway), http.StatusBadGateway)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Content-Type", logo.ContentType)
+	header.Set("ETag", logo.ETag)
+	header.Set("Cache-Control", c.cacheControl())
+	header.Set("X-Content-Type-Options", "nosniff")
+	if logo.ContentType == "image/svg+xml" {
+		// SVG may contain scripts, which must not run if the logo is opened directly
+		header.Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; sandbox")
+	}
+
+	http.ServeContent(w, r, "", logo.Modified, bytes.NewReader(logo.Data))
+}
+
+func (c *LogoCache) httpClient() *http.Client {
+	if c.HTTPClient == nil {
+		return http.DefaultClient
+	}
+
+	return c.HTTPClient
+}
+
+func (c *LogoCache) maxSize() int64 {
+	if c.MaxSize <= 0 {
+		return defaultLogoMaxSize
+	}
+
+	return c.MaxSize
+}
+
+func (c *LogoCache) downloadTimeout() time.Duration {
+	if c.DownloadTimeout <= 0 {
+		return defaultLogoDownloadTimeout
+	}
+
+	return c.DownloadTimeout
+}
+
+func (c *LogoCache) clock() time.Time {
+	if c.now == nil {
+		return time.Now()
+	}
+
+	return c.now()
+}
+
+func (c *LogoCache) cacheControl() string {
+	if c.CacheControl == "" {
+		return defaultLogoCacheControl
+	}
+
+	return c.CacheControl
+}
@@ -0,0 +1,214 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pkg/errors"
+)
+
+func TestLogoCache(t *testing.T) {
+	t.Parallel()
+	t.Run("logo downloaded once", testLogoCacheDownloadsOnce)
+	t.Run("logo handler conditional requests", testLogoCacheHandler)
+	t.Run("logo cache seeded offline", testLogoCacheSeeded)
+	t.Run("logo download outlives canceled caller", testLogoCacheCanceledCaller)
+}
+
+func startLogoServer(downloads *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(downloads, 1)
+		switch r.URL.Path {
+		case "/n26.png":
+			w.Header().Set("Content-Type", "text/html")
+			_, _ = w.Write(testPNG())
+		case "/revolut.svg":
+			_, _ = w.Write([]byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg"></svg>`))
+		default:
+			_, _ = w.Write([]byte("<html><body>not found</body></html>"))
+		}
+	}))
+}
+
+func testLogoCacheDownloadsOnce(t *testing.T) {
+	// What/Arrange
+	downloads := int32(0)
+	logos := startLogoServer(&downloads)
+	defer logos.Close()
+
+	api := startCatalogApi()
+	api.institutions["N26_NTSBDEB1"].LogoUrl = logos.URL + "/n26.png"
+	api.institutions["REVOLUT_REVOGB21"].LogoUrl = logos.URL + "/revolut.svg"
+	api.institutions["DEUTSCHE_BANK_DEUTDEFF"].LogoUrl = logos.URL + "/missing.png"
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := NewLogoCache(NewInstitutionCatalog(createTestNordigen(srv)), NewMemoryLogoStore())
+
+	// When/Act
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = underTest.Get(context.Background(), "N26_NTSBDEB1")
+		}()
+	}
+	wg.Wait()
+
+	png, err := underTest.Get(context.Background(), "N26_NTSBDEB1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	svg, err := underTest.Get(context.Background(), "REVOLUT_REVOGB21")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	_, notImageErr := underTest.Get(context.Background(), "DEUTSCHE_BANK_DEUTDEFF")
+	_, unknownErr := underTest.Get(context.Background(), "UNKNOWN")
+
+	// Then/Assert
+	if downloads := atomic.LoadInt32(&downloads); downloads != 3 {
+		t.Errorf("each logo expected to be downloaded once, got %d downloads", downloads)
+	}
+
+	if png.ContentType != "image/png" || png.SourceUrl != logos.URL+"/n26.png" {
+		t.Errorf("content type expected to be detected from the content, got %+v", png)
+	}
+
+	if svg.ContentType != "image/svg+xml" {
+		t.Errorf("SVG expected to be detected, got %s", svg.ContentType)
+	}
+
+	if !errors.Is(notImageErr, ErrNotAnImage) {
+		t.Errorf("ErrNotAnImage expected, got %v", notImageErr)
+	}
+
+	if !errors.Is(unknownErr, ErrLogoNotFound) {
+		t.Errorf("ErrLogoNotFound expected, got %v", unknownErr)
+	}
+}
+
+func testLogoCacheHandler(t *testing.T) {
+	// What/Arrange
+	underTest := NewLogoCache(nil, NewMemoryLogoStore())
+	logo, err := underTest.Seed(context.Background(), "N26_NTSBDEB1", testPNG())
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	handler := http.StripPrefix("/logos/", underTest)
+
+	// When/Act
+	full := httptest.NewRecorder()
+	handler.ServeHTTP(full, httptest.NewRequest(http.MethodGet, "/logos/N26_NTSBDEB1", nil))
+
+	byETag := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/logos/N26_NTSBDEB1", nil)
+	req.Header.Set("If-None-Match", logo.ETag)
+	handler.ServeHTTP(byETag, req)
+
+	byModified := httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/logos/N26_NTSBDEB1", nil)
+	req.Header.Set("If-Modified-Since", full.Header().Get("Last-Modified"))
+	handler.ServeHTTP(byModified, req)
+
+	missing := httptest.NewRecorder()
+	handler.ServeHTTP(missing, httptest.NewRequest(http.MethodGet, "/logos/UNKNOWN", nil))
+
+	// Then/Assert
+	if full.Code != http.StatusOK || full.Body.String() != string(testPNG()) {
+		t.Errorf("logo expected to be served, got %d", full.Code)
+	}
+
+	if full.Header().Get("Content-Type") != "image/png" || full.Header().Get("ETag") != logo.ETag ||
+		full.Header().Get("Last-Modified") == "" || full.Header().Get("X-Content-Type-Options") != "nosniff" {
+		t.Errorf("logo headers expected, got %v", full.Header())
+	}
+
+	if byETag.Code != http.StatusNotModified || byModified.Code != http.StatusNotModified {
+		t.Errorf("304 expected for conditional requests, got %d and %d", byETag.Code, byModified.Code)
+	}
+
+	if missing.Code != http.StatusNotFound {
+		t.Errorf("404 expected for a missing logo, got %d", missing.Code)
+	}
+}
+
+func testLogoCacheSeeded(t *testing.T) {
+	// What/Arrange
+	store := NewDirLogoStore(t.TempDir())
+	underTest := NewLogoCache(nil, store)
+
+	// When/Act
+	_, seedErr := underTest.Seed(context.Background(), "N26_NTSBDEB1", testPNG())
+	_, notImageErr := underTest.Seed(context.Background(), "REVOLUT_REVOGB21", []byte("plain text"))
+	logo, err := NewLogoCache(nil, store).Get(context.Background(), "N26_NTSBDEB1")
+
+	// Then/Assert
+	if seedErr != nil || err != nil {
+		t.Fatalf("unexpected error occurred: %v, %v", seedErr, err)
+	}
+
+	if logo.ContentType != "image/png" {
+		t.Errorf("seeded logo expected to be served from the store, got %+v", logo)
+	}
+
+	if !errors.Is(notImageErr, ErrNotAnImage) {
+		t.Errorf("ErrNotAnImage expected, got %v", notImageErr)
+	}
+}
+
+func testLogoCacheCanceledCaller(t *testing.T) {
+	// What/Arrange
+	downloads := int32(0)
+	started := make(chan struct{})
+	release := make(chan struct{})
+	logos := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		close(started)
+		<-release
+		_, _ = w.Write(testPNG())
+	}))
+	defer logos.Close()
+
+	api := startCatalogApi()
+	api.institutions["N26_NTSBDEB1"].LogoUrl = logos.URL + "/n26.png"
+	srv := api.start()
+	defer srv.Close()
+
+	underTest := &LogoCache{Catalog: NewInstitutionCatalog(createTestNordigen(srv)), Store: NewMemoryLogoStore()}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := make(chan error)
+	go func() {
+		_, err := underTest.Get(ctx, "N26_NTSBDEB1")
+		canceled <- err
+	}()
+	<-started
+
+	// When/Act
+	cancel()
+	canceledErr := <-canceled
+	close(release)
+	logo, err := underTest.Get(context.Background(), "N26_NTSBDEB1")
+
+	// Then/Assert
+	if !errors.Is(canceledErr, context.Canceled) {
+		t.Errorf("context.Canceled expected for the canceled caller, got %v", canceledErr)
+	}
+
+	if err != nil || logo.ContentType != "image/png" {
+		t.Fatalf("logo expected to be downloaded for the other caller, got %v", err)
+	}
+
+	if downloads := atomic.LoadInt32(&downloads); downloads != 1 {
+		t.Errorf("download expected to be shared, got %d downloads", downloads)
+	}
+}
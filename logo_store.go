@@ -0,0 +1,175 @@
+package nordigen
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// ErrLogoNotFound returned by LogoStore when there is no logo for the institution
+var ErrLogoNotFound = errors.New("logo not found")
+
+// ErrInvalidInstitutionID returned for institution IDs which can't be used as a file name
+var ErrInvalidInstitutionID = errors.New("invalid institution ID")
+
+const logoMetadataSuffix = ".json"
+
+var institutionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+// validLogoFileName reports whether the institution ID is safe as a file name: not a relative path like ".."
+// and not colliding with the metadata or the temporary files of another logo
+func validLogoFileName(institutionID string) bool {
+	return institutionIDPattern.MatchString(institutionID) &&
+		strings.Trim(institutionID, ".") != "" &&
+		!strings.HasSuffix(institutionID, logoMetadataSuffix) &&
+		!strings.HasSuffix(institutionID, ".tmp")
+}
+
+// LogoStore persists the downloaded logos
+type LogoStore interface {
+	// SaveLogo creates or replaces the logo of the same institution
+	SaveLogo(ctx context.Context, logo *Logo) error
+	// LoadLogo returns the logo of the institution or ErrLogoNotFound
+	LoadLogo(ctx context.Context, institutionID string) (*Logo, error)
+}
+
+// MemoryLogoStore keeps the logos in memory. Safe for concurrent use
+type MemoryLogoStore struct {
+	mu    sync.RWMutex
+	logos map[string]Logo
+}
+
+// NewMemoryLogoStore creates an empty in-memory store
+func NewMemoryLogoStore() *MemoryLogoStore {
+	return &MemoryLogoStore{
+		logos: make(map[string]Logo),
+	}
+}
+
+// SaveLogo stores a copy of the logo
+func (s *MemoryLogoStore) SaveLogo(_ context.Context, logo *Logo) error {
+	copied := *logo
+	copied.Data = append([]byte(nil), logo.Data...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.logos[logo.InstitutionID] = copied
+
+	return nil
+}
+
+// LoadLogo returns a copy of the logo of the institution
+func (s *MemoryLogoStore) LoadLogo(_ context.Context, institutionID string) (*Logo, error) {
+	s.mu.RLock()
+	logo, ok := s.logos[institutionID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil, ErrLogoNotFound
+	}
+
+	logo.Data = append([]byte(nil), logo.Data...)
+
+	return &logo, nil
+}
+
+// DirLogoStore keeps the logos in a directory, the image in "<institution ID>" and the metadata
+// in "<institution ID>.json". The directory must exist. The IDs made of dots only or ending in ".json" or ".tmp"
+// are rejected with ErrInvalidInstitutionID
+type DirLogoStore struct {
+	Dir string
+}
+
+// NewDirLogoStore creates a store in the directory
+func NewDirLogoStore(dir string) *DirLogoStore {
+	return &DirLogoStore{Dir: dir}
+}
+
+// SaveLogo writes the image and then the metadata, so a logo is found only once it's complete
+func (s *DirLogoStore) SaveLogo(_ context.Context, logo *Logo) error {
+	if !validLogoFileName(logo.InstitutionID) {
+		return ErrInvalidInstitutionID
+	}
+
+	meta := *logo
+	meta.Data = nil
+	payload, err := json.Marshal(&meta)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling logo metadata")
+	}
+
+	path := filepath.Join(s.Dir, logo.InstitutionID)
+	if err := writeFileAtomically(path, logo.Data); err != nil {
+		return errors.Wrap(err, "error saving logo")
+	}
+
+	if err := writeFileAtomically(path+logoMetadataSuffix, payload); err != nil {
+		return errors.Wrap(err, "error saving logo metadata")
+	}
+
+	return nil
+}
+
+// LoadLogo reads the logo of the institution
+func (s *DirLogoStore) LoadLogo(_ context.Context, institutionID string) (*Logo, error) {
+	if !validLogoFileName(institutionID) {
+		return nil, ErrInvalidInstitutionID
+	}
+
+	path := filepath.Join(s.Dir, institutionID)
+	payload, err := os.ReadFile(path + logoMetadataSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrLogoNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading logo metadata")
+	}
+
+	logo := &Logo{}
+	if err := json.Unmarshal(payload, logo); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling logo metadata")
+	}
+
+	logo.Data, err = os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrLogoNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading logo")
+	}
+
+	return logo, nil
+}
+
+// writeFileAtomically replaces the file with the data via a temporary file in the same directory
+func writeFileAtomically(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+func TestLogoStore(t *testing.T) {
+	t.Parallel()
+	t.Run("memory logo store", func(t *testing.T) { testLogoStore(t, NewMemoryLogoStore()) })
+	t.Run("directory logo store", func(t *testing.T) { testLogoStore(t, NewDirLogoStore(t.TempDir())) })
+	t.Run("directory logo store rejects invalid file names", testDirLogoStoreInvalidID)
+}
+
+func testLogoStore(t *testing.T, underTest LogoStore) {
+	// What/Arrange
+	logo, err := NewLogo("N26_NTSBDEB1", testPNG(), time.Now())
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	logo.SourceUrl = "https://cdn.nordigen.com/ais/N26_SANDBOX_NTSBDEB1.png"
+
+	// When/Act
+	_, missingErr := underTest.LoadLogo(context.Background(), "N26_NTSBDEB1")
+
+	if err := underTest.SaveLogo(context.Background(), logo); err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	loaded, err := underTest.LoadLogo(context.Background(), "N26_NTSBDEB1")
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	// Then/Assert
+	if !errors.Is(missingErr, ErrLogoNotFound) {
+		t.Errorf("ErrLogoNotFound expected, got %v", missingErr)
+	}
+
+	if string(loaded.Data) != string(logo.Data) || loaded.ContentType != "image/png" || loaded.ETag != logo.ETag ||
+		!loaded.Modified.Equal(logo.Modified) || loaded.SourceUrl != logo.SourceUrl {
+		t.Errorf("stored logo expected to be loaded, got %+v", loaded)
+	}
+}
+
+func testDirLogoStoreInvalidID(t *testing.T) {
+	for _, ID := range []string{"../secret", "..", ".", "N26_NTSBDEB1.json", "N26_NTSBDEB1.123.tmp"} {
+		t.Run(ID, func(t *testing.T) {
+			// What/Arrange
+			underTest := NewDirLogoStore(t.TempDir())
+			logo, _ := NewLogo(ID, testPNG(), time.Now())
+
+			// When/Act
+			_, loadErr := underTest.LoadLogo(context.Background(), ID)
+			saveErr := underTest.SaveLogo(context.Background(), logo)
+
+			// Then/Assert
+			if !errors.Is(loadErr, ErrInvalidInstitutionID) || !errors.Is(saveErr, ErrInvalidInstitutionID) {
+				t.Fatalf("ErrInvalidInstitutionID expected, got %v and %v", loadErr, saveErr)
+			}
+		})
+	}
+}
+
+func testPNG() []byte {
+	return []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00")
+}
@@ -2,6 +2,8 @@ package nordigen
 
 import (
 	"net/url"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,8 +31,12 @@ type Nordigen struct {
 	// to compensate the delay between receiving it from the API and setting expiration date to a client.
 	// The value must be negative
 	TokenExpirationBuffer time.Duration
+	// ScopeGuard rejects requests for account data outside the granted access scopes without calling the API.
+	// Accounts are registered with the guard when their requisitions are hydrated. Disabled if nil
+	ScopeGuard            *ScopeGuard
 	accessToken           string
 	accessTokenExpiration time.Time
+	authMu                sync.Mutex
 	// BaseUrl of restClient must be "https://ob.nordigen.com/api".
 	restClient *rest.Client
 }
@@ -71,3 +77,20 @@ func MustNew(secretID uuid.UUID, secretKey []byte) *Nordigen {
 		restClient:             rest.NewClient(apiUrl, nil),
 	}
 }
+
+// SetBaseUrl points the client to another API, e.g. a proxy or a mock. The URL includes the version,
+// "https://ob.nordigen.com/api/v2" by default
+func (n *Nordigen) SetBaseUrl(rawUrl string) error {
+	apiUrl, err := url.Parse(strings.TrimSuffix(rawUrl, "/"))
+	if err != nil {
+		return errors.Wrap(err, "invalid base URL")
+	}
+
+	if apiUrl.Scheme != "http" && apiUrl.Scheme != "https" || apiUrl.Host == "" {
+		return errors.New("base URL must be an absolute http(s) URL")
+	}
+
+	n.restClient.BaseUrl = apiUrl
+
+	return nil
+}
@@ -41,6 +41,28 @@ func TestNewClient(t *testing.T) {
 	}
 }
 
+func TestNordigen_SetBaseUrl(t *testing.T) {
+	// What/Arrange
+	underTest := MustNew(uuid.New(), []byte{12, 23, 42})
+
+	// When/Act
+	err := underTest.SetBaseUrl("https://proxy.example.com/nordigen/v2/")
+	relativeErr := underTest.SetBaseUrl("/v2")
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if underTest.restClient.BaseUrl.String() != "https://proxy.example.com/nordigen/v2" {
+		t.Errorf("base URL expected to be set, got %s", underTest.restClient.BaseUrl)
+	}
+
+	if relativeErr == nil {
+		t.Error("error expected for a relative URL")
+	}
+}
+
 func testNewClientSuccess(t *testing.T) {
 	// What/Arrange
 	secretId := "b6789fd6-95ee-4093-a03b-b003b7d7858a"
@@ -0,0 +1,82 @@
+package nordigen
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter throttles requests to the API
+type RateLimiter interface {
+	// Wait blocks until the next request is allowed or the context is done.
+	// The context error is returned if the context is done before the request is allowed
+	Wait(ctx context.Context) error
+}
+
+// IntervalLimiter allows a single request per the configured interval.
+// It is safe for concurrent use, so the same limiter can be shared between several operations
+type IntervalLimiter struct {
+	interval time.Duration
+	mu       sync.Mutex
+	next     time.Time
+}
+
+// NewIntervalLimiter creates a limiter allowing a single request per the given interval
+func NewIntervalLimiter(interval time.Duration) *IntervalLimiter {
+	return &IntervalLimiter{
+		interval: interval,
+		next:     time.Unix(0, 0),
+	}
+}
+
+// Wait blocks until the next request is allowed or the context is done
+func (l *IntervalLimiter) Wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	slot := l.next
+	if slot.Before(now) {
+		slot = now
+	}
+	l.next = slot.Add(l.interval)
+	l.mu.Unlock()
+
+	delay := time.Until(slot)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func waitForLimiter(ctx context.Context, limiter RateLimiter) error {
+	if limiter == nil {
+		return ctx.Err()
+	}
+
+	return limiter.Wait(ctx)
+}
+
+// execLimited executes the call once a slot of the semaphore is acquired and the limiter allows the request.
+// The semaphore bounds the number of parallel calls and can be shared between several operations
+func execLimited(ctx context.Context, sem chan struct{}, limiter RateLimiter, call func() error) error {
+	select {
+	case sem <- struct{}{}:
+		defer func() { <-sem }()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	if err := waitForLimiter(ctx, limiter); err != nil {
+		return err
+	}
+
+	return call()
+}
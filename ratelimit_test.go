@@ -0,0 +1,47 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestIntervalLimiter_Wait(t *testing.T) {
+	t.Parallel()
+	t.Run("waiting for the interval", testIntervalLimiterWaitOk)
+	t.Run("waiting cancelled", testIntervalLimiterWaitCancelled)
+}
+
+func testIntervalLimiterWaitOk(t *testing.T) {
+	// What/Arrange
+	underTest := NewIntervalLimiter(20 * time.Millisecond)
+	start := time.Now()
+
+	// When/Act
+	for i := 0; i < 3; i++ {
+		if err := underTest.Wait(context.Background()); err != nil {
+			t.Fatalf("unexpected error occurred: %s", err)
+		}
+	}
+
+	// Then/Assert
+	if elapsed := time.Since(start); elapsed < 40*time.Millisecond {
+		t.Fatalf("3 requests expected to take at least 40ms, %s elapsed", elapsed)
+	}
+}
+
+func testIntervalLimiterWaitCancelled(t *testing.T) {
+	// What/Arrange
+	underTest := NewIntervalLimiter(time.Hour)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// When/Act
+	_ = underTest.Wait(context.Background())
+	err := underTest.Wait(ctx)
+
+	// Then/Assert
+	if err == nil {
+		t.Fatal("error expected when the context is cancelled")
+	}
+}
@@ -0,0 +1,357 @@
+package nordigen
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRedirectStateCookie = "nordigen_ref"
+
+	redirectReferenceParam    = "ref"
+	redirectErrorParam        = "error"
+	redirectErrorDetailsParam = "details"
+)
+
+var (
+	// ErrMissingReference returned when the redirect URL has no reference
+	ErrMissingReference = errors.New("reference is missing in the redirect URL")
+	// ErrInvalidRedirectState returned when the reference doesn't match the state stored in the end user's browser
+	ErrInvalidRedirectState = errors.New("reference doesn't match the redirect state")
+	// ErrRequisitionNotFound returned when no requisition has the reference
+	ErrRequisitionNotFound = errors.New("requisition not found")
+)
+
+// RedirectError the error reported by the API in the redirect URL, e.g. when the end user cancelled the session
+type RedirectError struct {
+	Code    string
+	Details string
+}
+
+// Error returns string representation of the error
+func (e *RedirectError) Error() string {
+	if e.Details == "" {
+		return "authorization failed: " + e.Code
+	}
+
+	return "authorization failed: " + e.Code + ": " + e.Details
+}
+
+// RequisitionLookup resolves the reference of a requisition to its ID
+type RequisitionLookup interface {
+	// LookupRequisition returns the ID of the requisition with the given reference.
+	// ErrRequisitionNotFound must be returned if there is no such requisition
+	LookupRequisition(ctx context.Context, reference string) (uuid.UUID, error)
+}
+
+// RequisitionLookupFunc adapts a function to RequisitionLookup
+type RequisitionLookupFunc func(ctx context.Context, reference string) (uuid.UUID, error)
+
+// LookupRequisition calls the function
+func (f RequisitionLookupFunc) LookupRequisition(ctx context.Context, reference string) (uuid.UUID, error) {
+	return f(ctx, reference)
+}
+
+// ListRequisitionLookup looks up the requisition by iterating the list of all requisitions.
+// Suitable for a small number of requisitions only, consider storing the reference to ID mapping otherwise
+type ListRequisitionLookup struct {
+	Client *Nordigen
+}
+
+// LookupRequisition returns the ID of the most recent requisition with the given reference
+func (l *ListRequisitionLookup) LookupRequisition(ctx context.Context, reference string) (uuid.UUID, error) {
+	list, err := l.Client.Requisition().List()
+	if err != nil {
+		return uuid.Nil, errors.Wrap(err, "error listing requisitions")
+	}
+
+	var found *RequisitionResponse
+	for {
+		if err := ctx.Err(); err != nil {
+			return uuid.Nil, err
+		}
+
+		requisition, err := list.Next()
+		if err != nil {
+			return uuid.Nil, errors.Wrap(err, "error listing requisitions")
+		}
+
+		if requisition == nil {
+			break
+		}
+
+		if requisition.Reference == reference && (found == nil || requisition.Created.After(found.Created)) {
+			found = requisition
+		}
+	}
+
+	if found == nil {
+		return uuid.Nil, ErrRequisitionNotFound
+	}
+
+	return found.ID, nil
+}
+
+// ReferenceVerifier verifies the reference from the redirect URL, e.g. ReferenceSigner
+type ReferenceVerifier interface {
+	Verify(ctx context.Context, reference string) (*SignedReference, error)
+}
+
+// ReferenceReleaser is implemented by the verifiers remembering the used references, e.g. ReferenceSigner.
+// RedirectHandler releases the verified reference if the redirect fails temporarily
+type ReferenceReleaser interface {
+	Release(ctx context.Context, reference string) error
+}
+
+// RedirectResult the outcome of the end user returning from the institution
+type RedirectResult struct {
+	// Reference from the redirect URL
+	Reference string
+	// Signed the content of the reference verified by RedirectHandler.Verifier. Nil if no verifier configured
+	Signed *SignedReference
+	// Requisition the requisition with the reference. Nil if it could not be resolved
+	Requisition *HydratedRequisition
+	// AccountIDs linked to the requisition
+	AccountIDs []uuid.UUID
+}
+
+// RedirectHandler handles the end user returning to the Redirect URL of CreateRequisitionRequest.
+// It resolves the requisition by the reference, verifies its status through the API
+// and calls OnSuccess if the requisition is linked or OnFailure otherwise.
+// The callbacks are responsible for writing the response, plain text responses are written if they are nil.
+// Only GET requests are handled, resolving the reference has side effects, e.g. marking it used
+type RedirectHandler struct {
+	Client *Nordigen
+	// Lookup resolves the reference to the requisition. ListRequisitionLookup is used if nil
+	Lookup RequisitionLookup
+	// Verifier rejects forged, expired or replayed references before the requisition is resolved if set
+	Verifier ReferenceVerifier
+	// HydrateOptions for fetching the requisition. By default, the accounts data is not fetched
+	HydrateOptions *HydrateOptions
+	// OnSuccess called when the requisition is linked. Responds with 200 if nil
+	OnSuccess func(w http.ResponseWriter, r *http.Request, result *RedirectResult)
+	// OnFailure called with RedirectError, RequisitionStatusError or any other error preventing linking.
+	// The result contains whatever has been resolved before the failure. Responds with 400 or 502 if nil
+	OnFailure func(w http.ResponseWriter, r *http.Request, result *RedirectResult, err error)
+	// StateCookieName the name of the cookie binding the reference to the end user's browser.
+	// "nordigen_ref" by default
+	StateCookieName string
+	// SkipStateCheck disables the verification of the reference against the state cookie
+	SkipStateCheck bool
+	// InsecureCookie allows sending the state cookie over plain HTTP, e.g. for local development
+	InsecureCookie bool
+}
+
+// NewRedirectHandler creates a handler for the redirect URL
+func NewRedirectHandler(
+	n *Nordigen,
+	onSuccess func(w http.ResponseWriter, r *http.Request, result *RedirectResult),
+	onFailure func(w http.ResponseWriter, r *http.Request, result *RedirectResult, err error),
+) *RedirectHandler {
+	return &RedirectHandler{
+		Client:    n,
+		OnSuccess: onSuccess,
+		OnFailure: onFailure,
+	}
+}
+
+// SetState stores the reference in the end user's browser before redirecting them to RequisitionResponse.Link,
+// so the handler can verify the reference when the end user returns
+func (h *RedirectHandler) SetState(w http.ResponseWriter, reference string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.stateCookieName(),
+		Value:    reference,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   !h.InsecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// ServeHTTP handles the redirect request
+func (h *RedirectHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	result, err := h.resolve(r)
+	if err != nil {
+		// The state and the reference are kept usable after temporary failures, e.g. an API outage,
+		// so the end user can retry
+		if isFinalRedirectError(err) {
+			h.clearState(w)
+		} else if releaser, ok := h.Verifier.(ReferenceReleaser); ok && result.Signed != nil {
+			_ = releaser.Release(r.Context(), result.Reference)
+		}
+		h.onFailure()(w, r, result, err)
+		return
+	}
+
+	h.clearState(w)
+	h.onSuccess()(w, r, result)
+}
+
+func (h *RedirectHandler) onSuccess() func(w http.ResponseWriter, r *http.Request, result *RedirectResult) {
+	if h.OnSuccess == nil {
+		return defaultRedirectSuccess
+	}
+
+	return h.OnSuccess
+}
+
+func (h *RedirectHandler) onFailure() func(w http.ResponseWriter, r *http.Request, result *RedirectResult, err error) {
+	if h.OnFailure == nil {
+		return defaultRedirectFailure
+	}
+
+	return h.OnFailure
+}
+
+func defaultRedirectSuccess(w http.ResponseWriter, _ *http.Request, _ *RedirectResult) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	_, _ = w.Write([]byte("The bank account is linked, you can close this page.\n"))
+}
+
+// defaultRedirectFailure responds without the error details, they may reveal internals to the end user
+func defaultRedirectFailure(w http.ResponseWriter, _ *http.Request, _ *RedirectResult, err error) {
+	if isFinalRedirectError(err) || errors.Is(err, ErrMissingReference) || errors.Is(err, ErrInvalidRedirectState) {
+		http.Error(w, "The bank account could not be linked.", http.StatusBadRequest)
+		return
+	}
+
+	http.Error(w, "The bank account could not be linked, please try again later.", http.StatusBadGateway)
+}
+
+// isFinalRedirectError reports whether the redirect can't succeed on retry: the institution reported an error,
+// the requisition isn't linked, or the reference is unknown or rejected by the verifier
+func isFinalRedirectError(err error) bool {
+	var redirectErr *RedirectError
+	var statusErr *RequisitionStatusError
+
+	return errors.As(err, &redirectErr) ||
+		errors.As(err, &statusErr) ||
+		errors.Is(err, ErrRequisitionNotFound) ||
+		errors.Is(err, ErrInvalidReference) ||
+		errors.Is(err, ErrReferenceExpired) ||
+		errors.Is(err, ErrReferenceReplayed) ||
+		errors.Is(err, ErrUnknownReferenceKey)
+}
+
+func (h *RedirectHandler) resolve(r *http.Request) (*RedirectResult, error) {
+	query := r.URL.Query()
+	result := &RedirectResult{Reference: query.Get(redirectReferenceParam)}
+
+	if result.Reference == "" {
+		return result, ErrMissingReference
+	}
+
+	if err := h.verifyState(r, result.Reference); err != nil {
+		return result, err
+	}
+
+	if h.Verifier != nil {
+		signed, err := h.Verifier.Verify(r.Context(), result.Reference)
+		if err != nil {
+			return result, errors.Wrap(err, "error verifying the reference")
+		}
+
+		result.Signed = signed
+	}
+
+	requisitionID, err := h.lookup().LookupRequisition(r.Context(), result.Reference)
+	if err != nil {
+		return result, errors.Wrap(err, "error resolving the reference")
+	}
+
+	opts := h.HydrateOptions
+	if opts == nil {
+		opts = &HydrateOptions{SkipAccounts: true}
+	}
+
+	var redirectErr error
+	if code := query.Get(redirectErrorParam); code != "" {
+		redirectErr = &RedirectError{Code: code, Details: query.Get(redirectErrorDetailsParam)}
+	}
+
+	// Failures of the agreement or institution don't prevent linking, they are available through the result
+	hydrated, err := h.Client.Requisition().Hydrate(r.Context(), requisitionID, opts)
+	if hydrated == nil {
+		if redirectErr != nil {
+			return result, redirectErr
+		}
+
+		return result, err
+	}
+
+	result.Requisition = hydrated
+	result.AccountIDs = hydrated.AccountIDs()
+
+	if redirectErr != nil {
+		return result, redirectErr
+	}
+
+	if hydrated.Requisition.Status != RequisitionStatusLinked {
+		return result, &RequisitionStatusError{
+			RequisitionID: requisitionID,
+			Status:        hydrated.Requisition.Status,
+			Requisition:   hydrated.Requisition,
+		}
+	}
+
+	return result, nil
+}
+
+func (h *RedirectHandler) verifyState(r *http.Request, reference string) error {
+	if h.SkipStateCheck {
+		return nil
+	}
+
+	cookie, err := r.Cookie(h.stateCookieName())
+	if err != nil {
+		return ErrInvalidRedirectState
+	}
+
+	if subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(reference)) != 1 {
+		return ErrInvalidRedirectState
+	}
+
+	return nil
+}
+
+func (h *RedirectHandler) clearState(w http.ResponseWriter) {
+	if h.SkipStateCheck {
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.stateCookieName(),
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   !h.InsecureCookie,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *RedirectHandler) stateCookieName() string {
+	if h.StateCookieName == "" {
+		return defaultRedirectStateCookie
+	}
+
+	return h.StateCookieName
+}
+
+func (h *RedirectHandler) lookup() RequisitionLookup {
+	if h.Lookup == nil {
+		return &ListRequisitionLookup{Client: h.Client}
+	}
+
+	return h.Lookup
+}
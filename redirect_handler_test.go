@@ -0,0 +1,281 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestRedirectHandler_ServeHTTP(t *testing.T) {
+	t.Parallel()
+	t.Run("redirect linked requisition", testRedirectHandlerLinked)
+	t.Run("redirect with error params", testRedirectHandlerErrorParams)
+	t.Run("redirect with forged reference", testRedirectHandlerInvalidState)
+	t.Run("redirect not linked requisition", testRedirectHandlerNotLinked)
+	t.Run("redirect with default callbacks", testRedirectHandlerDefaultCallbacks)
+	t.Run("redirect HEAD request", testRedirectHandlerHead)
+}
+
+func startRedirectServer(status string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		var payload string
+		switch {
+		case r.URL.Path == "/requisitions":
+			payload = `{"count": 2, "results": [
+				{"id": "0b9b6d9b-4a4f-4a52-9f0d-5b8f8a3c6d11", "reference": "other", "status": "LN"},
+				{"id": "1071addf-e971-4fcf-9cb4-83e10e050eff", "reference": "124151", "status": "` + status + `"}
+			]}`
+		case strings.HasPrefix(r.URL.Path, "/requisitions/"):
+			payload = strings.Replace(testHydrateRequisitionPayload, `"LN"`, `"`+status+`"`, 1)
+		case strings.HasPrefix(r.URL.Path, "/agreements/enduser/"):
+			payload = testHydrateAgreementPayload
+		case strings.HasPrefix(r.URL.Path, "/institutions/"):
+			payload = testHydrateInstitutionPayload
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(payload))
+	}))
+}
+
+type redirectOutcome struct {
+	result *RedirectResult
+	err    error
+	called string
+}
+
+func createTestRedirectHandler(srv *httptest.Server, outcome *redirectOutcome) *RedirectHandler {
+	return NewRedirectHandler(
+		createTestNordigen(srv),
+		func(w http.ResponseWriter, r *http.Request, result *RedirectResult) {
+			outcome.called = "success"
+			outcome.result = result
+		},
+		func(w http.ResponseWriter, r *http.Request, result *RedirectResult, err error) {
+			outcome.called = "failure"
+			outcome.result = result
+			outcome.err = err
+		},
+	)
+}
+
+func newRedirectRequest(target string, stateCookie string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	if stateCookie != "" {
+		req.AddCookie(&http.Cookie{Name: defaultRedirectStateCookie, Value: stateCookie})
+	}
+
+	return req
+}
+
+func testRedirectHandlerLinked(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+	rec := httptest.NewRecorder()
+
+	// When/Act
+	underTest.ServeHTTP(rec, newRedirectRequest("/callback?ref=124151", "124151"))
+
+	// Then/Assert
+	if outcome.called != "success" {
+		t.Fatalf("success callback expected, %s called with %v", outcome.called, outcome.err)
+	}
+
+	if outcome.result.Requisition.Requisition.ID.String() != "1071addf-e971-4fcf-9cb4-83e10e050eff" {
+		t.Fatal("the requisition with the reference expected to be resolved")
+	}
+
+	if len(outcome.result.AccountIDs) != 2 {
+		t.Fatalf("2 account IDs expected, %d returned", len(outcome.result.AccountIDs))
+	}
+
+	if !strings.Contains(rec.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatal("state cookie expected to be cleared")
+	}
+}
+
+func testRedirectHandlerErrorParams(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusUndergoingAuthentication)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+
+	rec := httptest.NewRecorder()
+
+	// When/Act
+	underTest.ServeHTTP(rec, newRedirectRequest("/callback?ref=124151&error=UserCancelledSession&details=User+cancelled", "124151"))
+
+	// Then/Assert
+	var redirectErr *RedirectError
+	if outcome.called != "failure" || !errors.As(outcome.err, &redirectErr) {
+		t.Fatalf("failure callback with RedirectError expected, %v returned", outcome.err)
+	}
+
+	if redirectErr.Code != "UserCancelledSession" || redirectErr.Details != "User cancelled" {
+		t.Fatalf("error params expected to be reported, %v reported", redirectErr)
+	}
+
+	if outcome.result.Requisition == nil {
+		t.Fatal("requisition expected to be passed to the failure callback")
+	}
+
+	if !strings.Contains(rec.Header().Get("Set-Cookie"), "Max-Age=0") {
+		t.Fatal("state cookie expected to be cleared after the failed authorization")
+	}
+}
+
+func testRedirectHandlerInvalidState(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+
+	rec := httptest.NewRecorder()
+
+	// When/Act
+	underTest.ServeHTTP(rec, newRedirectRequest("/callback?ref=124151", "other"))
+
+	// Then/Assert
+	if outcome.called != "failure" || !errors.Is(outcome.err, ErrInvalidRedirectState) {
+		t.Fatalf("failure callback with ErrInvalidRedirectState expected, %v returned", outcome.err)
+	}
+
+	if rec.Header().Get("Set-Cookie") != "" {
+		t.Fatal("state of the end user's flow expected to be kept for a forged reference")
+	}
+}
+
+func testRedirectHandlerNotLinked(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusExpired)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+	underTest.SkipStateCheck = true
+
+	// When/Act
+	underTest.ServeHTTP(httptest.NewRecorder(), newRedirectRequest("/callback?ref=124151", ""))
+
+	// Then/Assert
+	var statusErr *RequisitionStatusError
+	if outcome.called != "failure" || !errors.As(outcome.err, &statusErr) {
+		t.Fatalf("failure callback with RequisitionStatusError expected, %v returned", outcome.err)
+	}
+
+	if statusErr.Status != RequisitionStatusExpired {
+		t.Fatalf("EX status expected to be reported, %s reported", statusErr.Status)
+	}
+}
+
+func testRedirectHandlerDefaultCallbacks(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	underTest := NewRedirectHandler(createTestNordigen(srv), nil, nil)
+	linked := httptest.NewRecorder()
+	forged := httptest.NewRecorder()
+
+	// When/Act
+	underTest.ServeHTTP(linked, newRedirectRequest("/callback?ref=124151", "124151"))
+	underTest.ServeHTTP(forged, newRedirectRequest("/callback?ref=124151", "other"))
+
+	// Then/Assert
+	if linked.Code != http.StatusOK {
+		t.Errorf("200 expected for the linked requisition, %d returned", linked.Code)
+	}
+
+	if forged.Code != http.StatusBadRequest || strings.Contains(forged.Body.String(), "state") {
+		t.Errorf("400 without the error details expected for the forged reference, %d returned: %s", forged.Code, forged.Body)
+	}
+}
+
+func testRedirectHandlerHead(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+	rec := httptest.NewRecorder()
+	req := newRedirectRequest("/callback?ref=124151", "124151")
+	req.Method = http.MethodHead
+
+	// When/Act
+	underTest.ServeHTTP(rec, req)
+
+	// Then/Assert
+	if rec.Code != http.StatusMethodNotAllowed || outcome.called != "" {
+		t.Fatalf("HEAD expected to be rejected without resolving the reference, %d returned", rec.Code)
+	}
+}
+
+func TestListRequisitionLookup_LookupRequisition(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	underTest := &ListRequisitionLookup{Client: createTestNordigen(srv)}
+
+	// When/Act
+	_, err := underTest.LookupRequisition(context.Background(), "unknown")
+
+	// Then/Assert
+	if !errors.Is(err, ErrRequisitionNotFound) {
+		t.Fatalf("ErrRequisitionNotFound expected, %v returned", err)
+	}
+}
+
+func TestRedirectHandler_SetState(t *testing.T) {
+	// What/Arrange
+	underTest := NewRedirectHandler(nil, nil, nil)
+	rec := httptest.NewRecorder()
+
+	// When/Act
+	underTest.SetState(rec, "124151")
+
+	// Then/Assert
+	cookie := rec.Header().Get("Set-Cookie")
+	if !strings.Contains(cookie, "nordigen_ref=124151") || !strings.Contains(cookie, "HttpOnly") ||
+		!strings.Contains(cookie, "Secure") || !strings.Contains(cookie, "SameSite=Lax") {
+		t.Fatalf("secure state cookie expected, %s set", cookie)
+	}
+}
+
+func TestRequisitionLookupFunc_LookupRequisition(t *testing.T) {
+	// What/Arrange
+	id := uuid.New()
+	underTest := RequisitionLookupFunc(func(ctx context.Context, reference string) (uuid.UUID, error) {
+		return id, nil
+	})
+
+	// When/Act
+	res, err := underTest.LookupRequisition(context.Background(), "124151")
+
+	// Then/Assert
+	if err != nil || res != id {
+		t.Fatal("the function result expected to be returned")
+	}
+}
@@ -0,0 +1,279 @@
+package nordigen
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	referenceVersion    = "r1"
+	referenceSeparator  = "."
+	referenceNonceBytes = 9
+
+	defaultReferenceTTL = time.Hour
+
+	// maxReferenceLength the maximum length of a requisition reference accepted by the API
+	maxReferenceLength = 256
+)
+
+var (
+	// ErrInvalidReference returned when the reference is malformed or its signature doesn't match
+	ErrInvalidReference = errors.New("invalid reference")
+	// ErrReferenceExpired returned when the reference is correctly signed but expired
+	ErrReferenceExpired = errors.New("reference expired")
+	// ErrReferenceReplayed returned when the reference has already been used
+	ErrReferenceReplayed = errors.New("reference already used")
+	// ErrUnknownReferenceKey returned when the reference is signed with a key which is not configured
+	ErrUnknownReferenceKey = errors.New("reference signed with an unknown key")
+)
+
+var referenceEncoding = base64.RawURLEncoding
+
+// ReferenceKey a key for signing references
+type ReferenceKey struct {
+	// ID of the key embedded in the reference. Must not contain dots
+	ID     string
+	Secret []byte
+}
+
+// SignedReference the content of a verified reference
+type SignedReference struct {
+	// Subject the internal ID the reference has been generated for, e.g. a user or session ID
+	Subject string
+	Expires time.Time
+	KeyID   string
+	Nonce   string
+}
+
+// ReplayGuard remembers the used references
+type ReplayGuard interface {
+	// MarkUsed records the reference as used until it expires.
+	// ErrReferenceReplayed must be returned if the reference has already been used
+	MarkUsed(ctx context.Context, reference string, expires time.Time) error
+	// Release forgets the used reference, so it can be used again
+	Release(ctx context.Context, reference string) error
+}
+
+// ReferenceSigner generates requisition references embedding an internal ID, an expiry time
+// and an HMAC signature, so a reference edited by the end user in the redirect URL is rejected.
+// Keys can be rotated by putting the new key first and keeping the old ones until their references expire
+type ReferenceSigner struct {
+	// Keys for verifying references. The first key signs new references
+	Keys []ReferenceKey
+	// TTL of new references. 1 hour by default
+	TTL time.Duration
+	// Replay rejects references used more than once if set
+	Replay ReplayGuard
+
+	now func() time.Time
+}
+
+// NewReferenceSigner creates a signer with the given keys, the first key signs new references
+func NewReferenceSigner(keys ...ReferenceKey) *ReferenceSigner {
+	return &ReferenceSigner{
+		Keys: keys,
+		TTL:  defaultReferenceTTL,
+		now:  time.Now,
+	}
+}
+
+// Sign generates a reference for the subject
+func (s *ReferenceSigner) Sign(subject string) (string, error) {
+	if len(s.Keys) == 0 {
+		return "", errors.New("no reference keys configured")
+	}
+
+	key := s.Keys[0]
+	if key.ID == "" || strings.Contains(key.ID, referenceSeparator) {
+		return "", errors.New("reference key ID must be non-empty and must not contain dots")
+	}
+
+	nonce := make([]byte, referenceNonceBytes)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", errors.Wrap(err, "error generating reference nonce")
+	}
+
+	ttl := s.TTL
+	if ttl <= 0 {
+		ttl = defaultReferenceTTL
+	}
+
+	payload := strings.Join([]string{
+		referenceVersion,
+		key.ID,
+		referenceEncoding.EncodeToString([]byte(subject)),
+		strconv.FormatInt(s.clock().Add(ttl).Unix(), 36),
+		referenceEncoding.EncodeToString(nonce),
+	}, referenceSeparator)
+
+	reference := payload + referenceSeparator + referenceEncoding.EncodeToString(signReference(key.Secret, payload))
+	if len(reference) > maxReferenceLength {
+		return "", errors.Errorf("reference exceeds %d characters, the subject is too long", maxReferenceLength)
+	}
+
+	return reference, nil
+}
+
+// Verify checks the signature and the expiry time of the reference and marks it as used
+// if ReplayGuard is configured. Release the reference if using it fails temporarily
+func (s *ReferenceSigner) Verify(ctx context.Context, reference string) (*SignedReference, error) {
+	signed, err := s.parse(reference)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.clock().Before(signed.Expires) {
+		return signed, ErrReferenceExpired
+	}
+
+	if s.Replay != nil {
+		if err := s.Replay.MarkUsed(ctx, reference, signed.Expires); err != nil {
+			return signed, err
+		}
+	}
+
+	return signed, nil
+}
+
+// Release makes the verified reference usable again if ReplayGuard is configured. RedirectHandler releases
+// the reference when the redirect fails temporarily, so the end user can retry
+func (s *ReferenceSigner) Release(ctx context.Context, reference string) error {
+	if s.Replay == nil {
+		return nil
+	}
+
+	return s.Replay.Release(ctx, reference)
+}
+
+// Subject returns the subject of the correctly signed reference regardless of its expiry time and usage,
+// e.g. to find the requisitions of an end user
+func (s *ReferenceSigner) Subject(reference string) (string, error) {
+	signed, err := s.parse(reference)
+	if err != nil {
+		return "", err
+	}
+
+	return signed.Subject, nil
+}
+
+func (s *ReferenceSigner) parse(reference string) (*SignedReference, error) {
+	parts := strings.Split(reference, referenceSeparator)
+	if len(parts) != 6 || parts[0] != referenceVersion {
+		return nil, ErrInvalidReference
+	}
+
+	key, ok := s.key(parts[1])
+	if !ok {
+		return nil, ErrUnknownReferenceKey
+	}
+
+	signature, err := referenceEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, ErrInvalidReference
+	}
+
+	payload := strings.Join(parts[:5], referenceSeparator)
+	if !hmac.Equal(signature, signReference(key.Secret, payload)) {
+		return nil, ErrInvalidReference
+	}
+
+	subject, err := referenceEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrInvalidReference
+	}
+
+	expiresUnix, err := strconv.ParseInt(parts[3], 36, 64)
+	if err != nil {
+		return nil, ErrInvalidReference
+	}
+
+	return &SignedReference{
+		Subject: string(subject),
+		Expires: time.Unix(expiresUnix, 0),
+		KeyID:   key.ID,
+		Nonce:   parts[4],
+	}, nil
+}
+
+func (s *ReferenceSigner) key(ID string) (ReferenceKey, bool) {
+	for _, k := range s.Keys {
+		if k.ID == ID {
+			return k, true
+		}
+	}
+
+	return ReferenceKey{}, false
+}
+
+func (s *ReferenceSigner) clock() time.Time {
+	if s.now == nil {
+		return time.Now()
+	}
+
+	return s.now()
+}
+
+func signReference(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+
+	return mac.Sum(nil)
+}
+
+// MemoryReplayGuard remembers used references in memory until they expire. The zero value is ready to use.
+// Safe for concurrent use
+type MemoryReplayGuard struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+// NewMemoryReplayGuard creates an empty in-memory replay guard
+func NewMemoryReplayGuard() *MemoryReplayGuard {
+	return &MemoryReplayGuard{
+		used: make(map[string]time.Time),
+	}
+}
+
+// MarkUsed records the reference as used or returns ErrReferenceReplayed if it has been used before
+func (g *MemoryReplayGuard) MarkUsed(_ context.Context, reference string, expires time.Time) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	for ref, exp := range g.used {
+		if now.After(exp) {
+			delete(g.used, ref)
+		}
+	}
+
+	if _, ok := g.used[reference]; ok {
+		return ErrReferenceReplayed
+	}
+
+	if g.used == nil {
+		g.used = make(map[string]time.Time)
+	}
+
+	g.used[reference] = expires
+
+	return nil
+}
+
+// Release forgets the reference
+func (g *MemoryReplayGuard) Release(_ context.Context, reference string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	delete(g.used, reference)
+
+	return nil
+}
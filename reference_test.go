@@ -0,0 +1,267 @@
+package nordigen
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var (
+	testReferenceKeyOld = ReferenceKey{ID: "k1", Secret: []byte("old secret")}
+	testReferenceKeyNew = ReferenceKey{ID: "k2", Secret: []byte("new secret")}
+)
+
+func TestReferenceSigner_Verify(t *testing.T) {
+	t.Parallel()
+	t.Run("verifying signed reference", testReferenceVerifyOk)
+	t.Run("verifying tampered reference", testReferenceVerifyTampered)
+	t.Run("verifying expired reference", testReferenceVerifyExpired)
+	t.Run("verifying reference after key rotation", testReferenceVerifyRotated)
+	t.Run("verifying replayed reference", testReferenceVerifyReplayed)
+}
+
+func testReferenceVerifyOk(t *testing.T) {
+	// What/Arrange
+	underTest := NewReferenceSigner(testReferenceKeyNew)
+
+	reference, err := underTest.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	signed, err := underTest.Verify(context.Background(), reference)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if signed.Subject != "user-42" || signed.KeyID != "k2" {
+		t.Fatalf("subject and key ID expected to be extracted, %v returned", signed)
+	}
+
+	if len(reference) > maxReferenceLength {
+		t.Fatalf("reference expected to fit %d characters, %d generated", maxReferenceLength, len(reference))
+	}
+}
+
+func testReferenceVerifyTampered(t *testing.T) {
+	// What/Arrange
+	underTest := NewReferenceSigner(testReferenceKeyNew)
+
+	reference, err := underTest.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(reference, ".")
+	parts[2] = referenceEncoding.EncodeToString([]byte("user-43"))
+	forged := strings.Join(parts, ".")
+
+	// When/Act
+	_, err = underTest.Verify(context.Background(), forged)
+
+	// Then/Assert
+	if !errors.Is(err, ErrInvalidReference) {
+		t.Fatalf("ErrInvalidReference expected for a forged reference, %v returned", err)
+	}
+
+	if _, err := underTest.Verify(context.Background(), "124151"); !errors.Is(err, ErrInvalidReference) {
+		t.Fatalf("ErrInvalidReference expected for a malformed reference, %v returned", err)
+	}
+}
+
+func testReferenceVerifyExpired(t *testing.T) {
+	// What/Arrange
+	underTest := NewReferenceSigner(testReferenceKeyNew)
+	underTest.TTL = time.Minute
+
+	reference, err := underTest.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	underTest.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	// When/Act
+	_, err = underTest.Verify(context.Background(), reference)
+
+	// Then/Assert
+	if !errors.Is(err, ErrReferenceExpired) {
+		t.Fatalf("ErrReferenceExpired expected, %v returned", err)
+	}
+}
+
+func testReferenceVerifyRotated(t *testing.T) {
+	// What/Arrange
+	reference, err := NewReferenceSigner(testReferenceKeyOld).Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	underTest := NewReferenceSigner(testReferenceKeyNew, testReferenceKeyOld)
+
+	// When/Act
+	signed, err := underTest.Verify(context.Background(), reference)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("reference signed with the old key expected to be valid during rotation: %s", err)
+	}
+
+	if signed.KeyID != "k1" {
+		t.Fatalf("old key expected to be used for verification, %s used", signed.KeyID)
+	}
+
+	if _, err := NewReferenceSigner(testReferenceKeyNew).Verify(context.Background(), reference); !errors.Is(err, ErrUnknownReferenceKey) {
+		t.Fatalf("ErrUnknownReferenceKey expected after the old key is removed, %v returned", err)
+	}
+}
+
+func testReferenceVerifyReplayed(t *testing.T) {
+	// What/Arrange
+	underTest := NewReferenceSigner(testReferenceKeyNew)
+	underTest.Replay = NewMemoryReplayGuard()
+
+	reference, err := underTest.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := underTest.Verify(context.Background(), reference); err != nil {
+		t.Fatal(err)
+	}
+
+	// When/Act
+	_, err = underTest.Verify(context.Background(), reference)
+
+	// Then/Assert
+	if !errors.Is(err, ErrReferenceReplayed) {
+		t.Fatalf("ErrReferenceReplayed expected, %v returned", err)
+	}
+
+	if err := underTest.Release(context.Background(), reference); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := underTest.Verify(context.Background(), reference); err != nil {
+		t.Fatalf("released reference expected to be accepted, %v returned", err)
+	}
+}
+
+func TestReferenceSigner_Subject(t *testing.T) {
+	// What/Arrange
+	underTest := NewReferenceSigner(testReferenceKeyNew)
+	underTest.TTL = time.Minute
+	underTest.Replay = NewMemoryReplayGuard()
+
+	reference, err := underTest.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := underTest.Verify(context.Background(), reference); err != nil {
+		t.Fatal(err)
+	}
+
+	underTest.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+
+	// When/Act
+	subject, err := underTest.Subject(reference)
+	_, tamperedErr := underTest.Subject(strings.Replace(reference, "r1.k2.", "r1.k2.x", 1))
+
+	// Then/Assert
+	if err != nil || subject != "user-42" {
+		t.Fatalf("subject of the used and expired reference expected, %s and %v returned", subject, err)
+	}
+
+	if !errors.Is(tamperedErr, ErrInvalidReference) {
+		t.Fatalf("ErrInvalidReference expected for the tampered reference, %v returned", tamperedErr)
+	}
+}
+
+func TestRedirectHandler_ServeHTTP_forgedReference(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+	underTest.SkipStateCheck = true
+	underTest.Verifier = NewReferenceSigner(testReferenceKeyNew)
+
+	// When/Act
+	underTest.ServeHTTP(httptest.NewRecorder(), newRedirectRequest("/callback?ref=124151", ""))
+
+	// Then/Assert
+	if outcome.called != "failure" || !errors.Is(outcome.err, ErrInvalidReference) {
+		t.Fatalf("failure callback with ErrInvalidReference expected, %v returned", outcome.err)
+	}
+
+	if outcome.result.Requisition != nil {
+		t.Fatal("requisition of a forged reference must not be resolved")
+	}
+}
+
+func TestRedirectHandler_ServeHTTP_retriedReference(t *testing.T) {
+	// What/Arrange
+	srv := startRedirectServer(RequisitionStatusLinked)
+	defer srv.Close()
+
+	signer := NewReferenceSigner(testReferenceKeyNew)
+	signer.Replay = NewMemoryReplayGuard()
+	reference, err := signer.Sign("user-42")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outcome := &redirectOutcome{}
+	underTest := createTestRedirectHandler(srv, outcome)
+	underTest.SkipStateCheck = true
+	underTest.Verifier = signer
+
+	lookups := 0
+	underTest.Lookup = RequisitionLookupFunc(func(ctx context.Context, reference string) (uuid.UUID, error) {
+		lookups++
+		if lookups == 1 {
+			return uuid.Nil, errors.New("temporary error")
+		}
+		return uuid.MustParse("1071addf-e971-4fcf-9cb4-83e10e050eff"), nil
+	})
+
+	// When/Act
+	underTest.ServeHTTP(httptest.NewRecorder(), newRedirectRequest("/callback?ref="+reference, ""))
+	failed := outcome.called
+	underTest.ServeHTTP(httptest.NewRecorder(), newRedirectRequest("/callback?ref="+reference, ""))
+
+	// Then/Assert
+	if failed != "failure" || outcome.called != "success" {
+		t.Fatalf("the retry after a temporary failure expected to succeed, %s returned: %v", outcome.called, outcome.err)
+	}
+
+	underTest.ServeHTTP(httptest.NewRecorder(), newRedirectRequest("/callback?ref="+reference, ""))
+	if !errors.Is(outcome.err, ErrReferenceReplayed) {
+		t.Fatalf("ErrReferenceReplayed expected after the successful redirect, %v returned", outcome.err)
+	}
+}
+
+func TestMemoryReplayGuard_zeroValue(t *testing.T) {
+	// What/Arrange
+	underTest := &MemoryReplayGuard{}
+	expires := time.Now().Add(time.Hour)
+
+	// When/Act
+	first := underTest.MarkUsed(context.Background(), "reference", expires)
+	replayed := underTest.MarkUsed(context.Background(), "reference", expires)
+
+	// Then/Assert
+	if first != nil || !errors.Is(replayed, ErrReferenceReplayed) {
+		t.Fatalf("the reference expected to be marked as used once, got %v and %v", first, replayed)
+	}
+}
@@ -0,0 +1,214 @@
+package nordigen
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// supportedUserLanguages the ISO 639-1 codes of the languages of the API's authorization screens
+var supportedUserLanguages = strings.Fields(`
+	BG CS DA DE EL EN ES ET FI FR HR HU IS IT LT LV NL NO PL PT RO RU SK SL SV`)
+
+// IsValidUserLanguage reports whether the authorization screens are available in the language,
+// a two-letter ISO 639-1 code, case-insensitive
+func IsValidUserLanguage(language string) bool {
+	language = strings.ToUpper(language)
+	for _, code := range supportedUserLanguages {
+		if code == language {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MarshalJSON omits the agreement if it's not set, so the API applies the default agreement
+// instead of receiving the zero UUID
+func (r CreateRequisitionRequest) MarshalJSON() ([]byte, error) {
+	type request CreateRequisitionRequest
+
+	payload := struct {
+		request
+		Agreement *uuid.UUID `json:"agreement,omitempty"`
+	}{
+		request: request(r),
+	}
+
+	if r.Agreement != uuid.Nil {
+		payload.Agreement = &r.Agreement
+	}
+
+	return json.Marshal(payload)
+}
+
+// Validate checks the request without sending it to the API. The agreement is optional,
+// if given its institution must match the requisition's one.
+// ValidationError is returned if the request would be rejected
+func (r *CreateRequisitionRequest) Validate(agreement *EndUserAgreementResponse) error {
+	verr := &ValidationError{}
+
+	if r.InstitutionID == "" {
+		verr.add("institution_id", "must not be empty")
+	}
+
+	if msg := validateRedirect(r.Redirect); msg != "" {
+		verr.add("redirect", msg)
+	}
+
+	if r.UserLanguage != "" && !IsValidUserLanguage(r.UserLanguage) {
+		verr.add("user_language", `"`+r.UserLanguage+`" is not supported, expected one of `+strings.Join(supportedUserLanguages, ", "))
+	}
+
+	if len(r.Reference) > maxReferenceLength {
+		verr.add("reference", "must not exceed "+strconv.Itoa(maxReferenceLength)+" characters")
+	}
+
+	if agreement != nil {
+		if r.Agreement != uuid.Nil && r.Agreement != agreement.ID {
+			verr.add("agreement", "doesn't match the agreement "+agreement.ID.String())
+		}
+
+		if r.InstitutionID != "" && agreement.InstitutionID != "" && r.InstitutionID != agreement.InstitutionID {
+			verr.add("institution_id", "doesn't match the institution of the agreement "+agreement.InstitutionID)
+		}
+	}
+
+	return verr.errorOrNil()
+}
+
+func validateRedirect(redirect string) string {
+	if redirect == "" {
+		return "must not be empty"
+	}
+
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return "must be a valid URL"
+	}
+
+	if !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "must be an absolute http(s) URL"
+	}
+
+	return ""
+}
+
+// RequisitionRequestBuilder builds a validated CreateRequisitionRequest
+type RequisitionRequestBuilder struct {
+	request     CreateRequisitionRequest
+	agreement   *EndUserAgreementResponse
+	institution *InstitutionResponse
+	// nilAgreement WithAgreement has been called with nil, reported on Build
+	nilAgreement bool
+}
+
+// NewRequisitionRequest starts building a requisition request for the institution
+// redirecting the end user to the given URL after the authorization
+func NewRequisitionRequest(institutionID, redirect string) *RequisitionRequestBuilder {
+	return &RequisitionRequestBuilder{
+		request: CreateRequisitionRequest{
+			InstitutionID: institutionID,
+			Redirect:      redirect,
+		},
+	}
+}
+
+// WithAgreement links the requisition to the agreement and checks the agreement's institution on Build.
+// A nil agreement fails Build
+func (b *RequisitionRequestBuilder) WithAgreement(agreement *EndUserAgreementResponse) *RequisitionRequestBuilder {
+	b.agreement = agreement
+	b.nilAgreement = agreement == nil
+	b.request.Agreement = uuid.Nil
+	if agreement != nil {
+		b.request.Agreement = agreement.ID
+	}
+
+	return b
+}
+
+// WithAgreementID links the requisition to the agreement with the given ID
+func (b *RequisitionRequestBuilder) WithAgreementID(ID uuid.UUID) *RequisitionRequestBuilder {
+	b.agreement = nil
+	b.nilAgreement = false
+	b.request.Agreement = ID
+
+	return b
+}
+
+// WithInstitution checks the request against the institution's ID and features on Build,
+// e.g. the account selection is rejected if the institution doesn't support it
+func (b *RequisitionRequestBuilder) WithInstitution(institution *InstitutionResponse) *RequisitionRequestBuilder {
+	b.institution = institution
+
+	return b
+}
+
+// WithReference sets the reference identifying the end user
+func (b *RequisitionRequestBuilder) WithReference(reference string) *RequisitionRequestBuilder {
+	b.request.Reference = reference
+
+	return b
+}
+
+// WithUserLanguage sets the language of the authorization screens (ISO 639-1), case-insensitive
+func (b *RequisitionRequestBuilder) WithUserLanguage(language string) *RequisitionRequestBuilder {
+	b.request.UserLanguage = strings.ToUpper(language)
+
+	return b
+}
+
+// WithSsn sets the SSN to verify the ownership of the account
+func (b *RequisitionRequestBuilder) WithSsn(ssn string) *RequisitionRequestBuilder {
+	b.request.Ssn = ssn
+
+	return b
+}
+
+// WithAccountSelection enables the account selection view for the end user
+func (b *RequisitionRequestBuilder) WithAccountSelection(enabled bool) *RequisitionRequestBuilder {
+	b.request.AccountSelection = enabled
+
+	return b
+}
+
+// WithRedirectImmediate enables redirecting back right after the account list is received
+func (b *RequisitionRequestBuilder) WithRedirectImmediate(enabled bool) *RequisitionRequestBuilder {
+	b.request.RedirectImmediate = enabled
+
+	return b
+}
+
+// Build validates and returns the request. ValidationError is returned if the request is invalid
+func (b *RequisitionRequestBuilder) Build() (*CreateRequisitionRequest, error) {
+	if b.nilAgreement {
+		verr := &ValidationError{}
+		verr.add("agreement", "must not be nil")
+		return nil, verr
+	}
+
+	request := b.request
+	if err := request.Validate(b.agreement); err != nil {
+		return nil, err
+	}
+
+	if b.institution != nil {
+		verr := &ValidationError{}
+		if request.InstitutionID != b.institution.ID {
+			verr.add("institution_id", "doesn't match the institution "+b.institution.ID)
+		}
+
+		if request.AccountSelection && !b.institution.SupportsAccountSelection() {
+			verr.add("account_selection", "not supported by the institution "+b.institution.ID)
+		}
+
+		if err := verr.errorOrNil(); err != nil {
+			return nil, err
+		}
+	}
+
+	return &request, nil
+}
@@ -0,0 +1,146 @@
+package nordigen
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var testBuilderAgreement = &EndUserAgreementResponse{
+	ID:            uuid.MustParse("3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb"),
+	InstitutionID: "N26_NTSBDEB1",
+}
+
+type requisitionBuilderTestCase struct {
+	name         string
+	builder      func() *RequisitionRequestBuilder
+	invalidField string
+}
+
+var requisitionBuilderTestCases = []*requisitionBuilderTestCase{
+	{"valid request", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com/callback").
+			WithAgreement(testBuilderAgreement).
+			WithUserLanguage("de").
+			WithReference("124151")
+	}, ""},
+	{"relative redirect", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "/callback")
+	}, "redirect"},
+	{"non http redirect", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "ftp://example.com/callback")
+	}, "redirect"},
+	{"unknown language", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com").WithUserLanguage("XX")
+	}, "user_language"},
+	{"unsupported language", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com").WithUserLanguage("zu")
+	}, "user_language"},
+	{"nil agreement", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com").WithAgreement(nil)
+	}, "agreement"},
+	{"too long reference", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com").WithReference(strings.Repeat("r", 257))
+	}, "reference"},
+	{"mismatching institution", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("REVOLUT_REVOGB21", "https://example.com").WithAgreement(testBuilderAgreement)
+	}, "institution_id"},
+	{"unsupported account selection", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com").
+			WithInstitution(&InstitutionResponse{ID: "N26_NTSBDEB1"}).
+			WithAccountSelection(true)
+	}, "account_selection"},
+	{"supported account selection", func() *RequisitionRequestBuilder {
+		return NewRequisitionRequest("N26_NTSBDEB1", "https://example.com/callback").
+			WithAgreement(testBuilderAgreement).
+			WithUserLanguage("de").
+			WithInstitution(&InstitutionResponse{ID: "N26_NTSBDEB1", SupportedFeatures: []InstitutionFeature{FeatureAccountSelection}}).
+			WithAccountSelection(true)
+	}, ""},
+}
+
+func TestRequisitionRequestBuilder_Build(t *testing.T) {
+	t.Parallel()
+	for _, tc := range requisitionBuilderTestCases {
+		t.Run(tc.name, testRequisitionRequestBuilderBuild(tc))
+	}
+}
+
+func testRequisitionRequestBuilderBuild(tc *requisitionBuilderTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// What/Arrange
+		underTest := tc.builder()
+
+		// When/Act
+		request, err := underTest.Build()
+
+		// Then/Assert
+		if tc.invalidField == "" {
+			if err != nil {
+				t.Fatalf("unexpected error occurred: %s", err)
+			}
+
+			if request.UserLanguage != "DE" || request.Agreement != testBuilderAgreement.ID {
+				t.Fatalf("request expected to be built with the given values, %v built", request)
+			}
+			return
+		}
+
+		var verr *ValidationError
+		if !errors.As(err, &verr) || !verr.Has(tc.invalidField) {
+			t.Fatalf(`ValidationError for the field "%s" expected, %v returned`, tc.invalidField, err)
+		}
+	}
+}
+
+func TestCreateRequisitionRequest_MarshalJSON(t *testing.T) {
+	t.Parallel()
+	t.Run("without agreement", testCreateRequisitionRequestMarshalWithoutAgreement)
+	t.Run("with agreement", testCreateRequisitionRequestMarshalWithAgreement)
+}
+
+func testCreateRequisitionRequestMarshalWithoutAgreement(t *testing.T) {
+	// What/Arrange
+	underTest := &CreateRequisitionRequest{InstitutionID: "N26_NTSBDEB1", Redirect: "https://example.com"}
+
+	// When/Act
+	payload, err := json.Marshal(underTest)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(string(payload), "agreement") {
+		t.Fatalf("unset agreement expected to be omitted, %s marshaled", payload)
+	}
+
+	if !strings.Contains(string(payload), `"institution_id":"N26_NTSBDEB1"`) {
+		t.Fatalf("other fields expected to be marshaled, %s marshaled", payload)
+	}
+}
+
+func testCreateRequisitionRequestMarshalWithAgreement(t *testing.T) {
+	// What/Arrange
+	underTest := CreateRequisitionRequest{InstitutionID: "N26_NTSBDEB1", Agreement: testBuilderAgreement.ID}
+
+	// When/Act
+	payload, err := json.Marshal(underTest)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(string(payload), `"agreement":"3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb"`) {
+		t.Fatalf("agreement expected to be marshaled, %s marshaled", payload)
+	}
+
+	unmarshaled := &CreateRequisitionRequest{}
+	if err := json.Unmarshal(payload, unmarshaled); err != nil || unmarshaled.Agreement != testBuilderAgreement.ID {
+		t.Fatalf("marshaled request expected to be unmarshaled back, %v, %v", unmarshaled, err)
+	}
+}
@@ -0,0 +1,175 @@
+package nordigen
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultHydrateConcurrency = 4
+)
+
+// HydrateOptions options for fetching all the data of a requisition
+type HydrateOptions struct {
+	// Concurrency the maximum number of parallel requests shared by all the accounts. 4 by default
+	Concurrency int
+	// Limiter throttles all the requests of the operation. Requests are not throttled if nil
+	Limiter RateLimiter
+	// DateFrom of the transactions to fetch
+	DateFrom *time.Time
+	// DateTo of the transactions to fetch
+	DateTo *time.Time
+	// SkipAccounts fetches only the requisition, its agreement and institution
+	SkipAccounts bool
+}
+
+// HydratedRequisition a requisition along with its end user agreement, institution and the linked accounts
+type HydratedRequisition struct {
+	Requisition *RequisitionResponse
+	Agreement   *EndUserAgreementResponse
+	Institution *InstitutionResponse
+	// Accounts snapshots in the order of RequisitionResponse.Accounts
+	Accounts []*AccountSnapshot
+
+	AgreementErr   error
+	InstitutionErr error
+}
+
+// RequisitionHydrateError returned when some data of a requisition could not be fetched
+type RequisitionHydrateError struct {
+	RequisitionID  uuid.UUID
+	AgreementErr   error
+	InstitutionErr error
+	// Accounts errors of account snapshots
+	Accounts []*AccountSnapshotError
+}
+
+// Error returns string representation of the error
+func (e *RequisitionHydrateError) Error() string {
+	msg := make([]string, 0, 2+len(e.Accounts))
+	if e.AgreementErr != nil {
+		msg = append(msg, "agreement: "+e.AgreementErr.Error())
+	}
+	if e.InstitutionErr != nil {
+		msg = append(msg, "institution: "+e.InstitutionErr.Error())
+	}
+	for _, accErr := range e.Accounts {
+		msg = append(msg, accErr.Error())
+	}
+
+	return "error hydrating requisition " + e.RequisitionID.String() + ": " + strings.Join(msg, "; ")
+}
+
+// AccountIDs returns the IDs of the accounts linked to the requisition
+func (h *HydratedRequisition) AccountIDs() []uuid.UUID {
+	if h.Requisition == nil {
+		return nil
+	}
+
+	return h.Requisition.Accounts
+}
+
+// Err returns RequisitionHydrateError if any part of the requisition failed
+func (h *HydratedRequisition) Err() error {
+	hydrateErr := &RequisitionHydrateError{
+		RequisitionID:  h.Requisition.ID,
+		AgreementErr:   h.AgreementErr,
+		InstitutionErr: h.InstitutionErr,
+	}
+
+	for _, s := range h.Accounts {
+		var snapshotErr *AccountSnapshotError
+		if errors.As(s.Err(), &snapshotErr) {
+			hydrateErr.Accounts = append(hydrateErr.Accounts, snapshotErr)
+		}
+	}
+
+	if hydrateErr.AgreementErr == nil && hydrateErr.InstitutionErr == nil && len(hydrateErr.Accounts) == 0 {
+		return nil
+	}
+
+	return hydrateErr
+}
+
+// Hydrate fetches the requisition with the given ID, its end user agreement, institution
+// and a snapshot of every linked account.
+// An error is returned without any data if the requisition itself could not be fetched.
+// Otherwise, the data is always returned, along with RequisitionHydrateError if some of its parts failed.
+// The accounts are registered with the client's ScopeGuard if the agreement has been fetched
+func (r *RequisitionResource) Hydrate(ctx context.Context, ID uuid.UUID, opts *HydrateOptions) (*HydratedRequisition, error) {
+	if opts == nil {
+		opts = &HydrateOptions{}
+	}
+
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = defaultHydrateConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	if err := waitForLimiter(ctx, opts.Limiter); err != nil {
+		return nil, err
+	}
+
+	requisition, err := r.Get(ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "error getting requisition")
+	}
+
+	h := &HydratedRequisition{Requisition: requisition}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		h.AgreementErr = execLimited(ctx, sem, opts.Limiter, func() (err error) {
+			if requisition.AgreementID == uuid.Nil {
+				return nil
+			}
+
+			h.Agreement, err = r.nordigen.EndUserAgreement().Get(requisition.AgreementID)
+			return
+		})
+	}()
+	go func() {
+		defer wg.Done()
+		h.InstitutionErr = execLimited(ctx, sem, opts.Limiter, func() (err error) {
+			h.Institution, err = r.nordigen.Institution().Get(requisition.InstitutionID)
+			return
+		})
+	}()
+	wg.Wait()
+
+	if h.Agreement != nil && r.nordigen.ScopeGuard != nil {
+		r.nordigen.ScopeGuard.Remember(h.Agreement, requisition.Accounts...)
+	}
+
+	if opts.SkipAccounts {
+		return h, h.Err()
+	}
+
+	// Without the agreement the access scopes are unknown, so all the parts are requested
+	snapshotOpts := &AccountSnapshotOptions{
+		Agreement: h.Agreement,
+		Limiter:   opts.Limiter,
+		DateFrom:  opts.DateFrom,
+		DateTo:    opts.DateTo,
+	}
+
+	h.Accounts = make([]*AccountSnapshot, len(requisition.Accounts))
+	for i, accountID := range requisition.Accounts {
+		wg.Add(1)
+		go func(i int, accountID uuid.UUID) {
+			defer wg.Done()
+			h.Accounts[i] = r.nordigen.Account().snapshot(ctx, accountID, snapshotOpts, sem)
+		}(i, accountID)
+	}
+	wg.Wait()
+
+	return h, h.Err()
+}
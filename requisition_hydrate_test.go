@@ -0,0 +1,162 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	testHydrateRequisitionPayload = `{
+		"id": "1071addf-e971-4fcf-9cb4-83e10e050eff",
+		"status": "LN",
+		"institution_id": "TEST_INSTITUTION",
+		"agreement": "3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb",
+		"reference": "124151",
+		"accounts": [
+			"9febb941-0886-4d03-991f-98111c68bf26",
+			"713e989a-0ba3-4015-a64b-863942711f14"
+		]
+	}`
+	testHydrateAgreementPayload = `{
+		"id": "3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb",
+		"max_historical_days": 90,
+		"access_valid_for_days": 90,
+		"access_scope": ["balances", "details"],
+		"institution_id": "TEST_INSTITUTION"
+	}`
+	testHydrateInstitutionPayload = `{
+		"id": "TEST_INSTITUTION",
+		"name": "Test Bank",
+		"bic": "TESTBIC",
+		"transaction_total_days": "540",
+		"countries": ["DE"]
+	}`
+)
+
+func TestRequisitionResource_Hydrate(t *testing.T) {
+	t.Parallel()
+	t.Run("hydrating requisition", testRequisitionHydrateOk)
+	t.Run("hydrating requisition partial failure", testRequisitionHydratePartialFailure)
+	t.Run("hydrating requisition API error", testApiErrorResponse(testRequisitionHydrateApiError))
+}
+
+func startHydrateServer(failingAccount string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		atomic.AddInt32(requests, 1)
+
+		var payload string
+		switch {
+		case strings.HasPrefix(r.URL.Path, "/requisitions/"):
+			payload = testHydrateRequisitionPayload
+		case strings.HasPrefix(r.URL.Path, "/agreements/enduser/"):
+			payload = testHydrateAgreementPayload
+		case strings.HasPrefix(r.URL.Path, "/institutions/"):
+			payload = testHydrateInstitutionPayload
+		case failingAccount != "" && strings.HasPrefix(r.URL.Path, "/accounts/"+failingAccount+"/"):
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"summary": "Rate limit exceeded"}`))
+			return
+		case strings.HasSuffix(r.URL.Path, "/details"):
+			payload = `{"account": {"iban": "DE75512108001245126199", "currency": "EUR"}}`
+		case strings.HasSuffix(r.URL.Path, "/balances"):
+			payload = `{"balances": [{"balanceAmount": {"amount": "657.49", "currency": "EUR"}, "balanceType": "expected"}]}`
+		default:
+			payload = `{"id": "` + strings.TrimPrefix(r.URL.Path, "/accounts/") + `", "status": "READY"}`
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(payload))
+	}))
+}
+
+func testRequisitionHydrateOk(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startHydrateServer("", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	// When/Act
+	h, err := underTest.Hydrate(context.Background(), uuid.New(), &HydrateOptions{Concurrency: 2})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	// requisition + agreement + institution + 2 accounts * (account + details + balances)
+	if requests != 9 {
+		t.Fatalf("9 requests expected, %d sent", requests)
+	}
+
+	if h.Agreement == nil || h.Agreement.ID.String() != "3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb" {
+		t.Fatal("agreement of the requisition expected to be fetched")
+	}
+
+	if h.Institution == nil || h.Institution.Name != "Test Bank" {
+		t.Fatal("institution of the requisition expected to be fetched")
+	}
+
+	if len(h.Accounts) != 2 {
+		t.Fatalf("2 account snapshots expected, %d returned", len(h.Accounts))
+	}
+
+	for i, s := range h.Accounts {
+		if s.AccountID != h.Requisition.Accounts[i] {
+			t.Fatal("account snapshots expected in the order of the requisition's accounts")
+		}
+
+		if s.Balances == nil || s.Details == nil || s.Transactions != nil {
+			t.Fatal("balances and details expected to be fetched, transactions expected to be skipped")
+		}
+	}
+}
+
+func testRequisitionHydratePartialFailure(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startHydrateServer("713e989a-0ba3-4015-a64b-863942711f14", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	// When/Act
+	h, err := underTest.Hydrate(context.Background(), uuid.New(), nil)
+
+	// Then/Assert
+	var hydrateErr *RequisitionHydrateError
+	if !errors.As(err, &hydrateErr) {
+		t.Fatalf("RequisitionHydrateError expected, %v returned", err)
+	}
+
+	if len(hydrateErr.Accounts) != 1 || hydrateErr.Accounts[0].AccountID.String() != "713e989a-0ba3-4015-a64b-863942711f14" {
+		t.Fatalf("the failure of the second account expected to be reported, %v returned", hydrateErr.Accounts)
+	}
+
+	if h.Accounts[0].Err() != nil {
+		t.Fatalf("the first account expected to be fetched, %v returned", h.Accounts[0].Err())
+	}
+}
+
+func testRequisitionHydrateApiError(c *Nordigen) error {
+	_, err := c.Requisition().Hydrate(context.Background(), uuid.New(), nil)
+
+	return err
+}
@@ -0,0 +1,191 @@
+package nordigen
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Requisition statuses
+const (
+	// RequisitionStatusCreated requisition has been successfully created
+	RequisitionStatusCreated = "CR"
+	// RequisitionStatusGivingConsent end-user is giving consent at Nordigen's consent screen
+	RequisitionStatusGivingConsent = "GC"
+	// RequisitionStatusUndergoingAuthentication end-user is redirected to the financial institution for authentication
+	RequisitionStatusUndergoingAuthentication = "UA"
+	// RequisitionStatusRejected either SSN verification has failed or end-user has entered incorrect credentials
+	RequisitionStatusRejected = "RJ"
+	// RequisitionStatusSelectingAccounts end-user is selecting accounts
+	RequisitionStatusSelectingAccounts = "SA"
+	// RequisitionStatusGrantingAccess end-user is granting access to their account information
+	RequisitionStatusGrantingAccess = "GA"
+	// RequisitionStatusLinked account has been successfully linked to requisition
+	RequisitionStatusLinked = "LN"
+	// RequisitionStatusSuspended requisition is suspended due to numerous consecutive errors that happened
+	// while accessing its accounts
+	RequisitionStatusSuspended = "SU"
+	// RequisitionStatusExpired access to accounts has expired as set in End User Agreement
+	RequisitionStatusExpired = "EX"
+)
+
+const (
+	defaultWaitInterval    = 2 * time.Second
+	defaultWaitMaxInterval = 30 * time.Second
+	defaultWaitBackoff     = 1.5
+)
+
+// ErrWaitTimeout returned when the requisition doesn't reach the expected status within the timeout
+var ErrWaitTimeout = errors.New("timeout waiting for the requisition status")
+
+// RequisitionPredicate reports whether the requisition reached the expected state
+type RequisitionPredicate func(r *RequisitionResponse) bool
+
+// StatusIs returns a predicate satisfied when the requisition has one of the given statuses
+func StatusIs(statuses ...string) RequisitionPredicate {
+	return func(r *RequisitionResponse) bool {
+		for _, s := range statuses {
+			if r.Status == s {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// IsTerminalRequisitionStatus reports whether the requisition can't change its status anymore
+// without the end user going through the authorization again
+func IsTerminalRequisitionStatus(status string) bool {
+	switch status {
+	case RequisitionStatusRejected, RequisitionStatusSuspended, RequisitionStatusExpired:
+		return true
+	}
+
+	return false
+}
+
+// WaitOptions options for polling a requisition
+type WaitOptions struct {
+	// Interval between the first polls. 2 seconds by default
+	Interval time.Duration
+	// MaxInterval the upper limit of the interval between polls. 30 seconds by default
+	MaxInterval time.Duration
+	// Backoff multiplier applied to the interval after every poll. 1.5 by default, 1 disables backoff
+	Backoff float64
+	// Timeout for waiting. Only the context limits waiting if zero
+	Timeout time.Duration
+	// OnTransition called every time the status of the requisition changes, including the first poll
+	// when the previous status is an empty string
+	OnTransition func(previous string, current *RequisitionResponse)
+	// Abort stops waiting when closed, e.g. when the end user has cancelled the authorization.
+	// RequisitionStatusError with Aborted set is returned in this case
+	Abort <-chan struct{}
+}
+
+// RequisitionStatusError returned when the requisition reached a terminal status other than expected
+// or waiting has been aborted
+type RequisitionStatusError struct {
+	RequisitionID uuid.UUID
+	// Status of the requisition when waiting finished
+	Status string
+	// Requisition the last polled state of the requisition. Nil if waiting was aborted before the first poll
+	Requisition *RequisitionResponse
+	// Aborted is true if waiting was aborted through WaitOptions.Abort
+	Aborted bool
+}
+
+// Error returns string representation of the error
+func (e *RequisitionStatusError) Error() string {
+	if e.Aborted {
+		return "waiting for requisition " + e.RequisitionID.String() + " aborted in status " + e.Status
+	}
+
+	return "requisition " + e.RequisitionID.String() + " reached terminal status " + e.Status
+}
+
+// WaitForStatus polls the requisition until the predicate is satisfied and returns the requisition.
+// RequisitionStatusError is returned if the requisition reaches a terminal status (RJ, SU, EX) not satisfying
+// the predicate or waiting is aborted. ErrWaitTimeout is returned when the timeout elapses.
+// Rate limit, server and network errors are retried, other errors, e.g. malformed responses, are returned immediately
+func (r *RequisitionResource) WaitForStatus(
+	ctx context.Context,
+	ID uuid.UUID,
+	predicate RequisitionPredicate,
+	opts *WaitOptions,
+) (*RequisitionResponse, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = defaultWaitInterval
+	}
+
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = defaultWaitMaxInterval
+	}
+
+	backoff := opts.Backoff
+	if backoff < 1 {
+		backoff = defaultWaitBackoff
+	}
+
+	var timeout <-chan time.Time
+	if opts.Timeout > 0 {
+		timer := time.NewTimer(opts.Timeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var last *RequisitionResponse
+	status := ""
+	for {
+		requisition, err := r.Get(ID)
+		if err != nil && !isRetryableError(err) {
+			return last, err
+		}
+
+		if err == nil {
+			last = requisition
+
+			if requisition.Status != status {
+				if opts.OnTransition != nil {
+					opts.OnTransition(status, requisition)
+				}
+				status = requisition.Status
+			}
+
+			if predicate(requisition) {
+				return requisition, nil
+			}
+
+			if IsTerminalRequisitionStatus(requisition.Status) {
+				return requisition, &RequisitionStatusError{RequisitionID: ID, Status: status, Requisition: requisition}
+			}
+		}
+
+		poll := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			poll.Stop()
+			return last, ctx.Err()
+		case <-timeout:
+			poll.Stop()
+			return last, ErrWaitTimeout
+		case <-opts.Abort:
+			poll.Stop()
+			return last, &RequisitionStatusError{RequisitionID: ID, Status: status, Requisition: last, Aborted: true}
+		case <-poll.C:
+		}
+
+		interval = time.Duration(float64(interval) * backoff)
+		if interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestRequisitionResource_WaitForStatus(t *testing.T) {
+	t.Parallel()
+	t.Run("waiting for linked status", testRequisitionWaitLinked)
+	t.Run("waiting terminal status", testRequisitionWaitTerminal)
+	t.Run("waiting timeout", testRequisitionWaitTimeout)
+	t.Run("waiting aborted", testRequisitionWaitAborted)
+	t.Run("waiting API error", testApiErrorResponse(testRequisitionWaitApiError))
+	t.Run("waiting malformed response", testRequisitionWaitMalformedResponse)
+}
+
+// startStatusServer responds with the given statuses one by one repeating the last one
+func startStatusServer(statuses ...string) *httptest.Server {
+	var polls int32
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		i := int(atomic.AddInt32(&polls, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+
+		if statuses[i] == "" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(`{"summary": "Service unavailable"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id": "1071addf-e971-4fcf-9cb4-83e10e050eff", "status": "` + statuses[i] + `"}`))
+	}))
+}
+
+func testRequisitionWaitLinked(t *testing.T) {
+	// What/Arrange
+	srv := startStatusServer(RequisitionStatusCreated, "", RequisitionStatusUndergoingAuthentication, RequisitionStatusLinked)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	transitions := make([]string, 0, 3)
+	opts := &WaitOptions{
+		Interval: time.Millisecond,
+		OnTransition: func(previous string, current *RequisitionResponse) {
+			transitions = append(transitions, previous+">"+current.Status)
+		},
+	}
+
+	// When/Act
+	res, err := underTest.WaitForStatus(context.Background(), uuid.New(), StatusIs(RequisitionStatusLinked), opts)
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if res.Status != RequisitionStatusLinked {
+		t.Fatalf("LN status expected, %s returned", res.Status)
+	}
+
+	if len(transitions) != 3 || transitions[0] != ">CR" || transitions[1] != "CR>UA" || transitions[2] != "UA>LN" {
+		t.Fatalf("every status transition expected to be reported, %v reported", transitions)
+	}
+}
+
+func testRequisitionWaitTerminal(t *testing.T) {
+	// What/Arrange
+	srv := startStatusServer(RequisitionStatusCreated, RequisitionStatusRejected)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	// When/Act
+	_, err := underTest.WaitForStatus(
+		context.Background(),
+		uuid.New(),
+		StatusIs(RequisitionStatusLinked),
+		&WaitOptions{Interval: time.Millisecond},
+	)
+
+	// Then/Assert
+	var statusErr *RequisitionStatusError
+	if !errors.As(err, &statusErr) {
+		t.Fatalf("RequisitionStatusError expected, %v returned", err)
+	}
+
+	if statusErr.Status != RequisitionStatusRejected || statusErr.Aborted {
+		t.Fatalf("RJ terminal status expected to be reported, %s reported", statusErr.Status)
+	}
+}
+
+func testRequisitionWaitTimeout(t *testing.T) {
+	// What/Arrange
+	srv := startStatusServer(RequisitionStatusCreated)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	// When/Act
+	_, err := underTest.WaitForStatus(
+		context.Background(),
+		uuid.New(),
+		StatusIs(RequisitionStatusLinked),
+		&WaitOptions{Interval: time.Millisecond, Timeout: 20 * time.Millisecond},
+	)
+
+	// Then/Assert
+	if !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("ErrWaitTimeout expected, %v returned", err)
+	}
+}
+
+func testRequisitionWaitAborted(t *testing.T) {
+	// What/Arrange
+	srv := startStatusServer(RequisitionStatusUndergoingAuthentication)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	abort := make(chan struct{})
+	close(abort)
+
+	// When/Act
+	_, err := underTest.WaitForStatus(
+		context.Background(),
+		uuid.New(),
+		StatusIs(RequisitionStatusLinked),
+		&WaitOptions{Interval: time.Hour, Abort: abort},
+	)
+
+	// Then/Assert
+	var statusErr *RequisitionStatusError
+	if !errors.As(err, &statusErr) || !statusErr.Aborted {
+		t.Fatalf("aborted RequisitionStatusError expected, %v returned", err)
+	}
+
+	if statusErr.Status != RequisitionStatusUndergoingAuthentication {
+		t.Fatalf("the last polled status expected to be reported, %s reported", statusErr.Status)
+	}
+}
+
+func testRequisitionWaitMalformedResponse(t *testing.T) {
+	// What/Arrange
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"id": `))
+	}))
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Requisition()
+
+	// When/Act
+	_, err := underTest.WaitForStatus(context.Background(), uuid.New(), StatusIs(RequisitionStatusLinked), &WaitOptions{Interval: time.Millisecond})
+
+	// Then/Assert
+	if err == nil || errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("the decoding error expected to be returned without retrying, %v returned", err)
+	}
+}
+
+func testRequisitionWaitApiError(c *Nordigen) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Requisition().WaitForStatus(ctx, uuid.New(), StatusIs(RequisitionStatusLinked), &WaitOptions{Interval: time.Millisecond})
+
+	return err
+}
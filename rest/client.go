@@ -6,6 +6,7 @@ import (
 	"log"
 	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -20,6 +21,8 @@ type Client struct {
 	Header   http.Header
 	LogError func(err error, message string)
 
+	headerMu   sync.RWMutex
+	httpOnce   sync.Once
 	httpClient *http.Client
 }
 
@@ -50,11 +53,37 @@ func (c *Client) NewRequest(method, resourceID string, body io.Reader) (*http.Re
 		return nil, err
 	}
 
+	c.headerMu.RLock()
 	req.Header = utils.MergeMapsOfArrays(req.Header, c.Header)
+	c.headerMu.RUnlock()
 
 	return req, nil
 }
 
+// GetHeader returns the value of the header sent with every request. Safe for concurrent use
+func (c *Client) GetHeader(key string) string {
+	c.headerMu.RLock()
+	defer c.headerMu.RUnlock()
+
+	return c.Header.Get(key)
+}
+
+// SetHeader sets the header sent with every request. Safe for concurrent use
+func (c *Client) SetHeader(key, value string) {
+	c.headerMu.Lock()
+	defer c.headerMu.Unlock()
+
+	c.Header.Set(key, value)
+}
+
+// DelHeader deletes the header sent with every request. Safe for concurrent use
+func (c *Client) DelHeader(key string) {
+	c.headerMu.Lock()
+	defer c.headerMu.Unlock()
+
+	c.Header.Del(key)
+}
+
 // ExecuteRequest executes the HTTP request and populates the result in case of a successful response or returns
 // ApiError in case of API HTTP error response. In case of the error unrelated to API other error type will be returned
 func (c *Client) ExecuteRequest(req *http.Request, target interface{}) error {
@@ -93,11 +122,13 @@ func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
 }
 
 func (c *Client) http() *http.Client {
-	if c.httpClient == nil {
-		c.httpClient = &http.Client{
-			Timeout: 5 * time.Second,
+	c.httpOnce.Do(func() {
+		if c.httpClient == nil {
+			c.httpClient = &http.Client{
+				Timeout: 5 * time.Second,
+			}
 		}
-	}
+	})
 
 	return c.httpClient
 }
@@ -0,0 +1,182 @@
+package nordigen
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// ErrAccessExpired reported when the access granted by the end user agreement has expired
+var ErrAccessExpired = errors.New("access granted by the end user agreement has expired")
+
+// ScopeError returned when the account data is requested outside the access granted by the end user agreement.
+// It matches ErrScopeNotGranted or ErrAccessExpired with errors.Is
+type ScopeError struct {
+	AccountID   uuid.UUID
+	AgreementID uuid.UUID
+	Scope       string
+	// Expired is true if the scope is granted but the access has expired
+	Expired bool
+	// Expires the time the access expires or expired at. Zero if the agreement has not been accepted
+	Expires time.Time
+}
+
+// Error returns string representation of the error
+func (e *ScopeError) Error() string {
+	if e.Expired {
+		return "account " + e.AccountID.String() + ": access granted by agreement " + e.AgreementID.String() +
+			" expired at " + e.Expires.Format(time.RFC3339)
+	}
+
+	return "account " + e.AccountID.String() + ": scope " + e.Scope + " not granted by agreement " +
+		e.AgreementID.String()
+}
+
+// Is reports whether the error matches ErrScopeNotGranted or ErrAccessExpired
+func (e *ScopeError) Is(target error) bool {
+	if e.Expired {
+		return target == ErrAccessExpired
+	}
+
+	return target == ErrScopeNotGranted
+}
+
+// AccountGrant the access to an account granted by an end user agreement
+type AccountGrant struct {
+	AgreementID uuid.UUID
+	// Scopes granted by the agreement. All the scopes are granted if empty
+	Scopes []string
+	// Expires the time the access expires at. Zero if the agreement has not been accepted
+	Expires time.Time
+}
+
+// Allows reports whether the scope is granted
+func (g *AccountGrant) Allows(scope string) bool {
+	if len(g.Scopes) == 0 {
+		return true
+	}
+
+	for _, s := range g.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+
+	return false
+}
+
+// AgreementExpiry returns the time the access granted by the agreement expires at
+// and false if the agreement has not been accepted
+func AgreementExpiry(agreement *EndUserAgreementResponse) (time.Time, bool) {
+	if agreement.Accepted == nil {
+		return time.Time{}, false
+	}
+
+	return agreement.Accepted.AddDate(0, 0, agreement.AccessValidForDays), true
+}
+
+// ScopeGuard remembers the end user agreement of every account and rejects requests for account data
+// outside the agreement's access scopes or after the access has expired, without calling the API.
+// Requests for accounts the guard doesn't know about are not checked. The zero value is ready to use.
+// Safe for concurrent use
+type ScopeGuard struct {
+	mu       sync.RWMutex
+	accounts map[uuid.UUID]*AccountGrant
+	now      func() time.Time
+}
+
+// NewScopeGuard creates a guard without any known accounts
+func NewScopeGuard() *ScopeGuard {
+	return &ScopeGuard{
+		accounts: make(map[uuid.UUID]*AccountGrant),
+		now:      time.Now,
+	}
+}
+
+// Remember the agreement the accounts have been linked with
+func (g *ScopeGuard) Remember(agreement *EndUserAgreementResponse, accountIDs ...uuid.UUID) {
+	grant := &AccountGrant{
+		AgreementID: agreement.ID,
+		Scopes:      agreement.AccessScopes,
+	}
+	if expires, ok := AgreementExpiry(agreement); ok {
+		grant.Expires = expires
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.accounts == nil {
+		g.accounts = make(map[uuid.UUID]*AccountGrant)
+	}
+
+	for _, ID := range accountIDs {
+		g.accounts[ID] = grant
+	}
+}
+
+// Forget the accounts, e.g. after the requisition has been deleted
+func (g *ScopeGuard) Forget(accountIDs ...uuid.UUID) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, ID := range accountIDs {
+		delete(g.accounts, ID)
+	}
+}
+
+// Grant returns the access granted to the account and false if the account is unknown
+func (g *ScopeGuard) Grant(accountID uuid.UUID) (AccountGrant, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	grant, ok := g.accounts[accountID]
+	if !ok {
+		return AccountGrant{}, false
+	}
+
+	return *grant, true
+}
+
+// Check returns ScopeError if the scope of the account is not granted or the access has expired
+func (g *ScopeGuard) Check(accountID uuid.UUID, scope string) error {
+	grant, ok := g.Grant(accountID)
+	if !ok {
+		return nil
+	}
+
+	if !grant.Allows(scope) {
+		return &ScopeError{AccountID: accountID, AgreementID: grant.AgreementID, Scope: scope, Expires: grant.Expires}
+	}
+
+	if !grant.Expires.IsZero() && !g.clock().Before(grant.Expires) {
+		return &ScopeError{
+			AccountID:   accountID,
+			AgreementID: grant.AgreementID,
+			Scope:       scope,
+			Expired:     true,
+			Expires:     grant.Expires,
+		}
+	}
+
+	return nil
+}
+
+func (g *ScopeGuard) clock() time.Time {
+	if g.now == nil {
+		return time.Now()
+	}
+
+	return g.now()
+}
+
+// checkScope verifies the scope of the account if the client has a ScopeGuard
+func (n *Nordigen) checkScope(accountID uuid.UUID, scope string) error {
+	if n.ScopeGuard == nil {
+		return nil
+	}
+
+	return n.ScopeGuard.Check(accountID, scope)
+}
@@ -0,0 +1,150 @@
+package nordigen
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+var testGuardAccountID = uuid.MustParse("9febb941-0886-4d03-991f-98111c68bf26")
+
+type scopeGuardTestCase struct {
+	name      string
+	agreement *EndUserAgreementResponse
+	scope     string
+	expected  error
+}
+
+func createTestGuardAgreement(accepted *time.Time, scopes ...string) *EndUserAgreementResponse {
+	return &EndUserAgreementResponse{
+		ID:                 uuid.MustParse("3d0e2267-40a4-47ed-a7d6-8b4a830c1cfb"),
+		AccessValidForDays: 90,
+		AccessScopes:       scopes,
+		Accepted:           accepted,
+	}
+}
+
+func createScopeGuardTestCases() []*scopeGuardTestCase {
+	recent := time.Now().AddDate(0, 0, -10)
+	old := time.Now().AddDate(0, 0, -91)
+
+	return []*scopeGuardTestCase{
+		{"unknown account", nil, AccessScopeTransactions, nil},
+		{"granted scope", createTestGuardAgreement(&recent, AccessScopeBalances), AccessScopeBalances, nil},
+		{"all scopes granted", createTestGuardAgreement(&recent), AccessScopeTransactions, nil},
+		{"not accepted agreement", createTestGuardAgreement(nil, AccessScopeBalances), AccessScopeBalances, nil},
+		{"scope not granted", createTestGuardAgreement(&recent, AccessScopeBalances), AccessScopeTransactions, ErrScopeNotGranted},
+		{"access expired", createTestGuardAgreement(&old, AccessScopeBalances), AccessScopeBalances, ErrAccessExpired},
+	}
+}
+
+func TestScopeGuard_Check(t *testing.T) {
+	t.Parallel()
+	for _, tc := range createScopeGuardTestCases() {
+		t.Run(tc.name, testScopeGuardCheck(tc))
+	}
+}
+
+func testScopeGuardCheck(tc *scopeGuardTestCase) func(t *testing.T) {
+	return func(t *testing.T) {
+		// What/Arrange
+		underTest := NewScopeGuard()
+		if tc.agreement != nil {
+			underTest.Remember(tc.agreement, testGuardAccountID)
+		}
+
+		// When/Act
+		err := underTest.Check(testGuardAccountID, tc.scope)
+
+		// Then/Assert
+		if tc.expected == nil {
+			if err != nil {
+				t.Fatalf("unexpected error occurred: %s", err)
+			}
+			return
+		}
+
+		var scopeErr *ScopeError
+		if !errors.Is(err, tc.expected) || !errors.As(err, &scopeErr) {
+			t.Fatalf("ScopeError matching %v expected, %v returned", tc.expected, err)
+		}
+
+		if scopeErr.AccountID != testGuardAccountID || scopeErr.AgreementID != tc.agreement.ID {
+			t.Fatalf("ScopeError expected to identify the account and the agreement, %v returned", scopeErr)
+		}
+	}
+}
+
+func TestScopeGuard_Forget(t *testing.T) {
+	// What/Arrange
+	underTest := NewScopeGuard()
+	underTest.Remember(createTestGuardAgreement(nil, AccessScopeBalances), testGuardAccountID)
+
+	// When/Act
+	underTest.Forget(testGuardAccountID)
+
+	// Then/Assert
+	if _, ok := underTest.Grant(testGuardAccountID); ok {
+		t.Fatal("forgotten account expected to be unknown")
+	}
+
+	if err := underTest.Check(testGuardAccountID, AccessScopeTransactions); err != nil {
+		t.Fatalf("forgotten account expected not to be checked, %v returned", err)
+	}
+}
+
+func TestScopeGuard_zeroValue(t *testing.T) {
+	// What/Arrange
+	old := time.Now().AddDate(0, 0, -100)
+	underTest := &ScopeGuard{}
+
+	// When/Act
+	unknownErr := underTest.Check(testGuardAccountID, AccessScopeBalances)
+	underTest.Remember(createTestGuardAgreement(&old, AccessScopeBalances), testGuardAccountID)
+	err := underTest.Check(testGuardAccountID, AccessScopeBalances)
+
+	// Then/Assert
+	if unknownErr != nil {
+		t.Fatalf("unknown account expected not to be checked, %v returned", unknownErr)
+	}
+
+	if !errors.Is(err, ErrAccessExpired) {
+		t.Fatalf("ErrAccessExpired expected, %v returned", err)
+	}
+}
+
+func TestNordigen_ScopeGuard(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startHydrateServer("", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+	client.ScopeGuard = NewScopeGuard()
+
+	h, err := client.Requisition().Hydrate(context.Background(), uuid.New(), &HydrateOptions{SkipAccounts: true})
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+	sent := requests
+
+	// When/Act
+	_, transactionsErr := client.Account().Transaction(h.AccountIDs()[0]).Get(nil, nil)
+	balances, balancesErr := client.Account().Balance(h.AccountIDs()[0]).Get()
+
+	// Then/Assert
+	if !errors.Is(transactionsErr, ErrScopeNotGranted) {
+		t.Fatalf("ErrScopeNotGranted expected for transactions, %v returned", transactionsErr)
+	}
+
+	if balancesErr != nil || balances == nil {
+		t.Fatalf("balances expected to be fetched, %v returned", balancesErr)
+	}
+
+	if requests != sent+1 {
+		t.Fatalf("only the balances request expected to be sent, %d sent", requests-sent)
+	}
+}
@@ -19,6 +19,7 @@ const (
 // TransactionResource access to account's balances
 type TransactionResource struct {
 	nordigenResource[TransactionCollectionResponse]
+	accountID uuid.UUID
 }
 
 // TransactionCollectionResponse API response structure
@@ -114,12 +115,18 @@ func (r *AccountResource) Transaction(accountID uuid.UUID) *TransactionResource
 				Client: r.nordigen.restClient,
 			},
 		},
+		accountID,
 	}
 }
 
 // Get transactions for the underlying account resource
 // In case API HTTP error response rest.ApiError will be returned,
+// ScopeError is returned without calling the API if the client's ScopeGuard rejects the request
 func (tr *TransactionResource) Get(dateFrom *time.Time, dateTo *time.Time) (*TransactionCollectionResponse, error) {
+	if err := tr.nordigen.checkScope(tr.accountID, AccessScopeTransactions); err != nil {
+		return nil, err
+	}
+
 	return tr.wrap(
 		func() (*TransactionCollectionResponse, error) {
 			params := url.Values{}
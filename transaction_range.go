@@ -0,0 +1,229 @@
+package nordigen
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultTransactionChunkDays = 90
+)
+
+// ErrChunkSkipped reported for the chunks that were not fetched because of a previous failure
+var ErrChunkSkipped = errors.New("chunk skipped after a previous failure")
+
+// GetRangeOptions options for fetching transactions of a long period of time
+type GetRangeOptions struct {
+	// ChunkDays the maximum number of days covered by a single API request. 90 days by default
+	ChunkDays int
+	// Institution of the account. If set, the range is clamped to the institution's TransactionTotalDays
+	// and chunks never exceed the available history
+	Institution *InstitutionResponse
+	// Limiter throttles the requests. Requests are not throttled if nil
+	Limiter RateLimiter
+	// StopOnError stops fetching the remaining chunks after the first failed one
+	StopOnError bool
+}
+
+// TransactionChunkError the error of fetching a single chunk of the range
+type TransactionChunkError struct {
+	From time.Time
+	To   time.Time
+	Err  error
+}
+
+// Error returns string representation of the error
+func (e *TransactionChunkError) Error() string {
+	return e.From.Format(dateFormat) + " - " + e.To.Format(dateFormat) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *TransactionChunkError) Unwrap() error {
+	return e.Err
+}
+
+// TransactionRangeError returned by TransactionResource.GetRange when some of the chunks failed
+type TransactionRangeError struct {
+	// Chunks that could not be fetched
+	Chunks []*TransactionChunkError
+}
+
+// Error returns string representation of the error
+func (e *TransactionRangeError) Error() string {
+	msg := make([]string, 0, len(e.Chunks))
+	for _, c := range e.Chunks {
+		msg = append(msg, c.Error())
+	}
+
+	return "error fetching transaction chunks: " + strings.Join(msg, "; ")
+}
+
+// GetRange fetches transactions between the given dates (both inclusive) splitting the range into chunks.
+// Chunks are fetched starting from the most recent one. Booked, pending and information transactions
+// of all the chunks are merged, the transactions returned by several chunks are deduplicated by their ID.
+// The transactions without an ID are all kept, identical ones are distinct purchases
+// If some chunks fail the transactions of the successful ones are returned along with TransactionRangeError
+func (tr *TransactionResource) GetRange(
+	ctx context.Context,
+	from time.Time,
+	to time.Time,
+	opts *GetRangeOptions,
+) (*TransactionCollectionResponse, error) {
+	if opts == nil {
+		opts = &GetRangeOptions{}
+	}
+
+	result := &TransactionCollectionResponse{
+		Transactions: TransactionTypesResponse{
+			Booked:      make([]TransactionResponse, 0),
+			Pending:     make([]TransactionResponse, 0),
+			Information: make([]TransactionResponse, 0),
+		},
+	}
+	merger := newTransactionMerger(result)
+
+	rangeErr := &TransactionRangeError{}
+	for _, c := range transactionChunks(from, to, opts) {
+		if len(rangeErr.Chunks) > 0 && opts.StopOnError {
+			rangeErr.Chunks = append(rangeErr.Chunks, &TransactionChunkError{From: c[0], To: c[1], Err: ErrChunkSkipped})
+			continue
+		}
+
+		if err := waitForLimiter(ctx, opts.Limiter); err != nil {
+			rangeErr.Chunks = append(rangeErr.Chunks, &TransactionChunkError{From: c[0], To: c[1], Err: err})
+			continue
+		}
+
+		res, err := tr.Get(&c[0], &c[1])
+		if err != nil {
+			rangeErr.Chunks = append(rangeErr.Chunks, &TransactionChunkError{From: c[0], To: c[1], Err: err})
+			continue
+		}
+
+		merger.merge(res)
+	}
+
+	if len(rangeErr.Chunks) > 0 {
+		return result, rangeErr
+	}
+
+	return result, nil
+}
+
+// transactionChunks returns [from, to] pairs of the chunks starting from the most recent one
+func transactionChunks(from time.Time, to time.Time, opts *GetRangeOptions) [][2]time.Time {
+	chunkDays := opts.ChunkDays
+	if chunkDays <= 0 {
+		chunkDays = defaultTransactionChunkDays
+	}
+
+	from = truncateToDate(from)
+	to = truncateToDate(to)
+
+	if opts.Institution != nil && opts.Institution.TransactionTotalDays > 0 {
+		totalDays := opts.Institution.TransactionTotalDays
+		if totalDays < chunkDays {
+			chunkDays = totalDays
+		}
+
+		earliest := truncateToDate(time.Now()).AddDate(0, 0, 1-totalDays)
+		if from.Before(earliest) {
+			from = earliest
+		}
+	}
+
+	chunks := make([][2]time.Time, 0, 1)
+	for end := to; !end.Before(from); end = end.AddDate(0, 0, -chunkDays) {
+		start := end.AddDate(0, 0, 1-chunkDays)
+		if start.Before(from) {
+			start = from
+		}
+
+		chunks = append(chunks, [2]time.Time{start, end})
+	}
+
+	return chunks
+}
+
+func truncateToDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+type transactionMerger struct {
+	target *TransactionCollectionResponse
+	seen   map[string]struct{}
+	// pending the number of the added pending transactions without ID by their key
+	pending map[string]int
+}
+
+func newTransactionMerger(target *TransactionCollectionResponse) *transactionMerger {
+	return &transactionMerger{
+		target:  target,
+		seen:    make(map[string]struct{}),
+		pending: make(map[string]int),
+	}
+}
+
+func (m *transactionMerger) merge(res *TransactionCollectionResponse) {
+	m.target.Transactions.Booked = m.append(m.target.Transactions.Booked, "booked", res.Transactions.Booked)
+	m.target.Transactions.Pending = m.append(m.target.Transactions.Pending, "pending", res.Transactions.Pending)
+	m.target.Transactions.Information = m.append(
+		m.target.Transactions.Information,
+		"information",
+		res.Transactions.Information,
+	)
+}
+
+// append adds the transactions skipping the ones with an already added ID. The booked chunks don't overlap,
+// so the booked and information transactions without ID are kept. The pending ones may be returned
+// with every chunk, those without ID are matched by the number of occurrences of their key in the chunk
+func (m *transactionMerger) append(dst []TransactionResponse, kind string, src []TransactionResponse) []TransactionResponse {
+	chunk := make(map[string]int)
+	for _, t := range src {
+		switch {
+		case t.ID != uuid.Nil:
+			key := kind + "|" + t.ID.String()
+			if _, ok := m.seen[key]; ok {
+				continue
+			}
+			m.seen[key] = struct{}{}
+		case kind == "pending":
+			key := t.Key()
+			chunk[key]++
+			if chunk[key] <= m.pending[key] {
+				continue
+			}
+			m.pending[key] = chunk[key]
+		}
+
+		dst = append(dst, t)
+	}
+
+	return dst
+}
+
+// Key identifies the transaction by its ID or by its content if the ASPSP doesn't provide an ID.
+// Stable across requests, but identical transactions without ID, e.g. two equal purchases on the same day,
+// share the key, so the transactions must be matched by the number of occurrences of the key, not by the key alone
+func (t *TransactionResponse) Key() string {
+	if t.ID != uuid.Nil {
+		return t.ID.String()
+	}
+
+	return strings.Join([]string{
+		t.BookingDate,
+		t.ValueDate,
+		t.Amount.Amount,
+		t.Amount.Currency,
+		t.CreditorName,
+		t.DebtorName,
+		t.RemittanceInformationUnstructured,
+		strings.Join(t.RemittanceInformationUnstructuredArray, ","),
+		t.AdditionalInformation,
+		t.BankTransactionCode,
+	}, "|")
+}
@@ -0,0 +1,170 @@
+package nordigen
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+func TestTransactionResource_GetRange(t *testing.T) {
+	t.Parallel()
+	t.Run("getting transactions range", testTransactionsGetRangeOk)
+	t.Run("getting transactions range partial failure", testTransactionsGetRangePartialFailure)
+}
+
+func startTransactionRangeServer(failFrom string, requests *int32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token/new/" {
+			authenticate(w)
+			return
+		}
+
+		atomic.AddInt32(requests, 1)
+
+		dateFrom := r.URL.Query().Get("date_from")
+		if dateFrom == failFrom {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"summary": "Rate limit exceeded"}`))
+			return
+		}
+
+		chunkID := uuid.NewSHA1(uuid.NameSpaceOID, []byte(dateFrom))
+		payload := `{
+			"transactions": {
+				"booked": [
+					{"transactionId": "` + chunkID.String() + `", "bookingDate": "` + dateFrom + `"},
+					{"transactionId": "06de5c3d-aecd-4e58-9d5f-797c7c8a16e8", "bookingDate": "2022-09-18"}
+				],
+				"pending": [
+					{"bookingDate": "` + dateFrom + `", "transactionAmount": {"amount": "-1.99", "currency": "EUR"}},
+					{"bookingDate": "` + dateFrom + `", "transactionAmount": {"amount": "-1.99", "currency": "EUR"}},
+					{"valueDate": "2022-12-30", "transactionAmount": {"amount": "-4.50", "currency": "EUR"}},
+					{"valueDate": "2022-12-30", "transactionAmount": {"amount": "-4.50", "currency": "EUR"}}
+				]
+			}
+		}`
+
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(payload))
+	}))
+}
+
+func testTransactionsGetRangeOk(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startTransactionRangeServer("", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Account().Transaction(uuid.New())
+
+	from := time.Date(2022, 1, 1, 12, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 12, 31, 12, 0, 0, 0, time.UTC)
+
+	// When/Act
+	res, err := underTest.GetRange(context.Background(), from, to, &GetRangeOptions{ChunkDays: 100})
+
+	// Then/Assert
+	if err != nil {
+		t.Fatalf("unexpected error occurred: %s", err)
+	}
+
+	if requests != 4 {
+		t.Fatalf("4 requests expected for 365 days in 100 days chunks, %d sent", requests)
+	}
+
+	if len(res.Transactions.Booked) != 5 {
+		t.Fatalf("5 deduplicated booked transactions expected, %d received", len(res.Transactions.Booked))
+	}
+
+	if len(res.Transactions.Pending) != 10 {
+		t.Fatalf("8 identical pending transactions of the chunks and 2 repeated by every chunk expected, %d received",
+			len(res.Transactions.Pending))
+	}
+
+	if res.Transactions.Booked[0].BookingDate != "2022-09-23" {
+		t.Fatalf(
+			`the most recent chunk expected to be fetched first starting "2022-09-23", "%s" received`,
+			res.Transactions.Booked[0].BookingDate)
+	}
+}
+
+func testTransactionsGetRangePartialFailure(t *testing.T) {
+	// What/Arrange
+	var requests int32
+	srv := startTransactionRangeServer("2022-06-15", &requests)
+	defer srv.Close()
+
+	client := createTestNordigen(srv)
+
+	underTest := client.Account().Transaction(uuid.New())
+
+	from := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2022, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	// When/Act
+	res, err := underTest.GetRange(context.Background(), from, to, &GetRangeOptions{ChunkDays: 100})
+
+	// Then/Assert
+	var rangeErr *TransactionRangeError
+	if !errors.As(err, &rangeErr) {
+		t.Fatalf("TransactionRangeError expected, %v returned", err)
+	}
+
+	if len(rangeErr.Chunks) != 1 {
+		t.Fatalf("1 failed chunk expected, %d reported", len(rangeErr.Chunks))
+	}
+
+	if rangeErr.Chunks[0].From.Format(dateFormat) != "2022-06-15" {
+		t.Fatalf(`failed chunk expected to start "2022-06-15", "%s" reported`, rangeErr.Chunks[0].From.Format(dateFormat))
+	}
+
+	if res == nil || len(res.Transactions.Booked) != 4 {
+		t.Fatal("transactions of the successful chunks expected to be returned")
+	}
+}
+
+func Test_transactionChunks(t *testing.T) {
+	// What/Arrange
+	today := truncateToDate(time.Now())
+	opts := &GetRangeOptions{
+		Institution: &InstitutionResponse{TransactionTotalDays: 30},
+	}
+
+	// When/Act
+	chunks := transactionChunks(today.AddDate(-1, 0, 0), today, opts)
+
+	// Then/Assert
+	if len(chunks) != 1 {
+		t.Fatalf("1 chunk expected for the institution with 30 days of history, %d returned", len(chunks))
+	}
+
+	if !chunks[0][0].Equal(today.AddDate(0, 0, -29)) {
+		t.Fatalf("the range expected to be clamped to the institution's history, starts at %s", chunks[0][0])
+	}
+}
+
+func TestTransactionResponse_Key(t *testing.T) {
+	// What/Arrange
+	withID := &TransactionResponse{ID: uuid.MustParse("3fa85f64-5717-4562-b3fc-2c963f66afa6"), BookingDate: "2022-03-01"}
+	withoutID := TransactionResponse{BookingDate: "2022-03-01", Amount: Amount{Amount: "-45.10", Currency: "EUR"}, CreditorName: "Grocery Store"}
+	same := withoutID
+	other := withoutID
+	other.Amount.Amount = "-45.11"
+
+	// When/Act & Then/Assert
+	if withID.Key() != "3fa85f64-5717-4562-b3fc-2c963f66afa6" {
+		t.Errorf("ID expected as the key, got %s", withID.Key())
+	}
+
+	if withoutID.Key() != same.Key() || withoutID.Key() == other.Key() {
+		t.Error("content-based key expected to identify the transaction")
+	}
+}
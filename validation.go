@@ -0,0 +1,51 @@
+package nordigen
+
+import (
+	"strings"
+)
+
+// FieldError a problem with a single field of a request
+type FieldError struct {
+	// Field JSON name of the field as expected by the API
+	Field   string
+	Message string
+}
+
+// ValidationError returned when a request is rejected before sending it to the API
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// Error returns string representation of the error
+func (e *ValidationError) Error() string {
+	msg := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msg = append(msg, f.Field+": "+f.Message)
+	}
+
+	return "invalid request: " + strings.Join(msg, "; ")
+}
+
+// Has reports whether the field has an error
+func (e *ValidationError) Has(field string) bool {
+	for _, f := range e.Fields {
+		if f.Field == field {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (e *ValidationError) add(field, message string) {
+	e.Fields = append(e.Fields, FieldError{Field: field, Message: message})
+}
+
+// errorOrNil returns the error only if it has any field errors
+func (e *ValidationError) errorOrNil() error {
+	if len(e.Fields) == 0 {
+		return nil
+	}
+
+	return e
+}